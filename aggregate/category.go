@@ -0,0 +1,67 @@
+package aggregate
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidCategory is returned when a category is created without a name
+	ErrInvalidCategory = errors.New("a category has to have a name")
+)
+
+// Category is a section of the menu, such as starters, mains or drinks
+type Category struct {
+	// id is the root identifier of the category
+	id   uuid.UUID
+	name string
+	// position orders the categories on the menu, lower comes first
+	position int
+}
+
+// NewCategory is a factory to create a new Category at a position on the menu
+func NewCategory(name string, position int) (Category, error) {
+	if name == "" {
+		return Category{}, ErrInvalidCategory
+	}
+	return Category{
+		id:       uuid.New(),
+		name:     name,
+		position: position,
+	}, nil
+}
+
+// GetID returns the categories root ID
+func (c Category) GetID() uuid.UUID {
+	return c.id
+}
+
+// SetID sets the root ID
+func (c *Category) SetID(id uuid.UUID) {
+	c.id = id
+}
+
+// GetName returns the name of the category
+func (c Category) GetName() string {
+	return c.name
+}
+
+// SetName changes the name of the category
+func (c *Category) SetName(name string) error {
+	if name == "" {
+		return ErrInvalidCategory
+	}
+	c.name = name
+	return nil
+}
+
+// GetPosition returns the position of the category on the menu
+func (c Category) GetPosition() int {
+	return c.position
+}
+
+// SetPosition moves the category on the menu
+func (c *Category) SetPosition(position int) {
+	c.position = position
+}
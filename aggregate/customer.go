@@ -2,8 +2,12 @@ package aggregate
 
 import (
 	"errors"
+	"fmt"
+	"regexp"
+	"strings"
 	"taverne/entity"
 	"taverne/valueobject"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -11,6 +15,38 @@ import (
 var (
 	// ErrInvalidPerson is returned when the person is not valid in the NewCustomer factory
 	ErrInvalidPerson = errors.New("a customer has to have an valid person")
+	// ErrInvalidPoints is returned when points are added or redeemed by a number below one
+	ErrInvalidPoints = errors.New("points can only be changed by a positive number")
+	// ErrInsufficientPoints is returned when a customer redeems more points than collected
+	ErrInsufficientPoints = errors.New("not enough loyalty points")
+	// ErrInvalidTopUp is returned when a wallet is fed with anything but a positive top-up to the customer
+	ErrInvalidTopUp = errors.New("a wallet can only be topped up by a positive top-up transaction to the customer")
+	// ErrInvalidCharge is returned when a wallet is charged with an amount below one cent
+	ErrInvalidCharge = errors.New("a wallet can only be charged a positive amount")
+	// ErrInsufficientFunds is returned when a charge exceeds the wallet balance and the credit limit
+	ErrInsufficientFunds = errors.New("insufficient funds in the wallet")
+	// ErrInvalidCreditLimit is returned when a credit limit is negative
+	ErrInvalidCreditLimit = errors.New("a credit limit can not be negative")
+	// ErrInvalidBirthDate is returned when a birth date is not a date in the past
+	ErrInvalidBirthDate = errors.New("a birth date has to be in the past")
+	// ErrAgeUnverified is returned when the age of a customer is needed but no birth date was recorded
+	ErrAgeUnverified = errors.New("the age of the customer is not verified")
+	// ErrInvalidEmail is returned when an email address is malformed
+	ErrInvalidEmail = errors.New("the email address is not valid")
+	// ErrInvalidPhone is returned when a phone number is malformed
+	ErrInvalidPhone = errors.New("the phone number is not valid")
+	// ErrInvalidAllergen is returned when an allergy or a product allergen is not one of the declared allergens
+	ErrInvalidAllergen = errors.New("unknown allergen")
+	// ErrInvalidDiet is returned when a dietary preference is unknown
+	ErrInvalidDiet = errors.New("unknown diet")
+)
+
+var (
+	// emailPattern is deliberately loose, the only way to really validate an address is to send a mail
+	emailPattern = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+	// phonePattern matches international and national numbers once the separators are removed
+	phonePattern    = regexp.MustCompile(`^\+?[0-9]{6,15}$`)
+	phoneSeparators = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "", "/", "")
 )
 
 type Customer struct {
@@ -21,26 +57,135 @@ type Customer struct {
 	products []*entity.Item
 	// a customer can perform many transactions
 	transactions []valueobject.Transaction
+	// points is the balance of loyalty points
+	points int
+	// balance is the prepaid wallet in cents, it drops below zero only within the credit limit
+	balance int
+	// creditLimit is how many cents the wallet may be overdrawn
+	creditLimit int
+	// allergies are the allergens the customer has to avoid
+	allergies []valueobject.Allergen
+	// diets are the dietary preferences of the customer
+	diets []valueobject.Diet
+	// marketingConsent is true once the customer agreed to receive marketing
+	marketingConsent bool
 }
 
+// CustomerOption is an option which changes the profile of a customer
+// Each option validates its field and returns a *FieldError if it is invalid
+type CustomerOption func(c *Customer) error
+
 // NewCustomer is a factory to create a new Customer aggregate
-// It will validate that the name is not empty
-func NewCustomer(name string) (Customer, error) {
+// It will validate that the name is not empty and every option is valid
+// will return ErrInvalidPerson for an empty name and ValidationErrors holding the error of every invalid option
+func NewCustomer(name string, opts ...CustomerOption) (Customer, error) {
 	if name == "" {
 		return Customer{}, ErrInvalidPerson
 	}
 
-	person := &entity.Person{
-		Name: name,
-		ID:   uuid.New(),
-	}
-
 	// Create a customer object and initialize all the values to avoid nil pointer exceptions
-	return Customer{
-		person:       person,
+	c := Customer{
+		person: &entity.Person{
+			ID: uuid.New(),
+		},
 		products:     make([]*entity.Item, 0),
 		transactions: make([]valueobject.Transaction, 0),
-	}, nil
+	}
+	if err := c.Apply(append([]CustomerOption{WithName(name)}, opts...)...); err != nil {
+		return Customer{}, err
+	}
+	return c, nil
+}
+
+// Apply changes the profile of the customer with all options
+// The customer is only changed if every option is valid, otherwise ValidationErrors is returned
+func (c *Customer) Apply(opts ...CustomerOption) error {
+	changed := *c
+	if c.person != nil {
+		// copy the person, so a failed or stored customer does not see the changes
+		p := *c.person
+		changed.person = &p
+	}
+
+	var errs ValidationErrors
+	for _, opt := range opts {
+		err := opt(&changed)
+		if err == nil {
+			continue
+		}
+		var fe *FieldError
+		if !errors.As(err, &fe) {
+			fe = &FieldError{Field: "customer", Err: err}
+		}
+		errs = append(errs, fe)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	*c = changed
+	return nil
+}
+
+// fieldError attaches the field to the error of a setter
+func fieldError(field string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &FieldError{Field: field, Err: err}
+}
+
+// WithName changes the name of the customer, which can not be empty
+func WithName(name string) CustomerOption {
+	return func(c *Customer) error {
+		if name == "" {
+			return fieldError("name", ErrInvalidPerson)
+		}
+		c.SetName(name)
+		return nil
+	}
+}
+
+// WithBirthDate records the verified birth date of the customer
+func WithBirthDate(birthDate time.Time) CustomerOption {
+	return func(c *Customer) error {
+		return fieldError("birth_date", c.SetBirthDate(birthDate))
+	}
+}
+
+// WithEmail changes the email address of the customer, an empty address removes it
+func WithEmail(email string) CustomerOption {
+	return func(c *Customer) error {
+		return fieldError("email", c.SetEmail(email))
+	}
+}
+
+// WithPhone changes the phone number of the customer, an empty number removes it
+func WithPhone(phone string) CustomerOption {
+	return func(c *Customer) error {
+		return fieldError("phone", c.SetPhone(phone))
+	}
+}
+
+// WithAllergies replaces the allergies of the customer
+func WithAllergies(allergies ...valueobject.Allergen) CustomerOption {
+	return func(c *Customer) error {
+		return fieldError("allergies", c.SetAllergies(allergies...))
+	}
+}
+
+// WithDiets replaces the dietary preferences of the customer
+func WithDiets(diets ...valueobject.Diet) CustomerOption {
+	return func(c *Customer) error {
+		return fieldError("diets", c.SetDiets(diets...))
+	}
+}
+
+// WithMarketingConsent records if the customer agreed to receive marketing
+func WithMarketingConsent(consent bool) CustomerOption {
+	return func(c *Customer) error {
+		c.SetMarketingConsent(consent)
+		return nil
+	}
 }
 
 // GetID returns the customers root entity ID
@@ -50,21 +195,246 @@ func (c *Customer) GetID() uuid.UUID {
 
 // SetID setzs the root ID
 func (c *Customer) SetID(id uuid.UUID) {
-	if c.person == nil {
-		c.person = &entity.Person{}
-	}
-	c.person.ID = id
+	c.changePerson(func(p *entity.Person) {
+		p.ID = id
+	})
 }
 
 // SetName changes the name of the customer
 func (c *Customer) SetName(name string) {
-	if c.person == nil {
-		c.person = &entity.Person{}
-	}
-	c.person.Name = name
+	c.changePerson(func(p *entity.Person) {
+		p.Name = name
+	})
 }
 
 // SetName changes the name of the customer
 func (c *Customer) GetName() string {
 	return c.person.Name
 }
+
+// changePerson changes a copy of the person, so copies of the customer keep the old values
+func (c *Customer) changePerson(change func(p *entity.Person)) {
+	p := entity.Person{}
+	if c.person != nil {
+		p = *c.person
+	}
+	change(&p)
+	c.person = &p
+}
+
+// GetBirthDate returns the verified birth date, the zero time if it was never verified
+func (c *Customer) GetBirthDate() time.Time {
+	return c.person.BirthDate
+}
+
+// SetBirthDate records the birth date after the ID of the customer was checked
+// will return error if the birth date lies in the future
+func (c *Customer) SetBirthDate(birthDate time.Time) error {
+	if birthDate.IsZero() || birthDate.After(time.Now()) {
+		return ErrInvalidBirthDate
+	}
+	c.changePerson(func(p *entity.Person) {
+		p.BirthDate = birthDate
+	})
+	return nil
+}
+
+// AgeAt returns the age of the customer in full years at the given time
+// will return error if the birth date was never verified
+func (c *Customer) AgeAt(at time.Time) (int, error) {
+	birth := c.person.BirthDate
+	if birth.IsZero() {
+		return 0, ErrAgeUnverified
+	}
+	age := at.Year() - birth.Year()
+	// the birthday of this year is still ahead
+	if at.Month() < birth.Month() || (at.Month() == birth.Month() && at.Day() < birth.Day()) {
+		age--
+	}
+	return age, nil
+}
+
+// GetEmail returns the email address of the customer
+func (c *Customer) GetEmail() string {
+	return c.person.Email
+}
+
+// SetEmail changes the email address of the customer, an empty address removes it
+// will return error if the address is malformed
+func (c *Customer) SetEmail(email string) error {
+	email = strings.TrimSpace(email)
+	if email != "" && !emailPattern.MatchString(email) {
+		return ErrInvalidEmail
+	}
+	c.changePerson(func(p *entity.Person) {
+		p.Email = email
+	})
+	return nil
+}
+
+// GetPhone returns the phone number of the customer
+func (c *Customer) GetPhone() string {
+	return c.person.Phone
+}
+
+// SetPhone changes the phone number of the customer, an empty number removes it
+// Spaces, dashes, dots and parentheses are allowed between the digits
+// will return error if the number is malformed
+func (c *Customer) SetPhone(phone string) error {
+	phone = strings.TrimSpace(phone)
+	if phone != "" && !phonePattern.MatchString(phoneSeparators.Replace(phone)) {
+		return ErrInvalidPhone
+	}
+	c.changePerson(func(p *entity.Person) {
+		p.Phone = phone
+	})
+	return nil
+}
+
+// GetAllergies returns the allergies of the customer
+func (c *Customer) GetAllergies() []valueobject.Allergen {
+	return append([]valueobject.Allergen(nil), c.allergies...)
+}
+
+// SetAllergies replaces the allergies of the customer
+// will return error if any allergen is not one of the declared allergens
+func (c *Customer) SetAllergies(allergies ...valueobject.Allergen) error {
+	for _, a := range allergies {
+		if !a.Valid() {
+			return fmt.Errorf("%q: %w", a, ErrInvalidAllergen)
+		}
+	}
+	c.allergies = append([]valueobject.Allergen(nil), allergies...)
+	return nil
+}
+
+// GetDiets returns the dietary preferences of the customer
+func (c *Customer) GetDiets() []valueobject.Diet {
+	return append([]valueobject.Diet(nil), c.diets...)
+}
+
+// SetDiets replaces the dietary preferences of the customer
+// will return error if any diet is unknown
+func (c *Customer) SetDiets(diets ...valueobject.Diet) error {
+	for _, d := range diets {
+		if !d.Valid() {
+			return fmt.Errorf("%q: %w", d, ErrInvalidDiet)
+		}
+	}
+	c.diets = append([]valueobject.Diet(nil), diets...)
+	return nil
+}
+
+// HasMarketingConsent reports if the customer agreed to receive marketing
+func (c *Customer) HasMarketingConsent() bool {
+	return c.marketingConsent
+}
+
+// SetMarketingConsent records if the customer agreed to receive marketing
+func (c *Customer) SetMarketingConsent(consent bool) {
+	c.marketingConsent = consent
+}
+
+// GetPoints returns the balance of loyalty points
+func (c *Customer) GetPoints() int {
+	return c.points
+}
+
+// SetPoints sets the balance of loyalty points, such as when reading a customer back from a database
+func (c *Customer) SetPoints(points int) {
+	c.points = points
+}
+
+// AddPoints adds loyalty points to the balance
+func (c *Customer) AddPoints(points int) error {
+	if points < 1 {
+		return ErrInvalidPoints
+	}
+	c.points += points
+	return nil
+}
+
+// RedeemPoints takes loyalty points off the balance
+// will return error if the customer has less points
+func (c *Customer) RedeemPoints(points int) error {
+	if points < 1 {
+		return ErrInvalidPoints
+	}
+	if c.points < points {
+		return ErrInsufficientPoints
+	}
+	c.points -= points
+	return nil
+}
+
+// GetTransactions returns a copy of all transactions of the customer
+func (c *Customer) GetTransactions() []valueobject.Transaction {
+	return append([]valueobject.Transaction(nil), c.transactions...)
+}
+
+// AddTransaction records a transaction of the customer
+func (c *Customer) AddTransaction(t valueobject.Transaction) {
+	// append to a copy, so copies of the customer do not share the new transaction
+	c.transactions = append(c.GetTransactions(), t)
+}
+
+// GetBalance returns the wallet balance in cents
+func (c *Customer) GetBalance() int {
+	return c.balance
+}
+
+// SetBalance sets the wallet balance in cents, such as when reading a customer back from a database
+func (c *Customer) SetBalance(cents int) {
+	c.balance = cents
+}
+
+// GetCreditLimit returns how many cents the wallet may be overdrawn
+func (c *Customer) GetCreditLimit() int {
+	return c.creditLimit
+}
+
+// SetCreditLimit sets how many cents the wallet may be overdrawn, zero allows no credit
+func (c *Customer) SetCreditLimit(cents int) error {
+	if cents < 0 {
+		return ErrInvalidCreditLimit
+	}
+	c.creditLimit = cents
+	return nil
+}
+
+// TopUp feeds the wallet with a top-up transaction to the customer and records it
+func (c *Customer) TopUp(t valueobject.Transaction) error {
+	if t.GetKind() != valueobject.TransactionTopUp || t.GetAmount() < 1 || t.GetTo() != c.GetID() {
+		return ErrInvalidTopUp
+	}
+	c.balance += t.GetAmount()
+	c.AddTransaction(t)
+	return nil
+}
+
+// Charge draws cents from the wallet for a reference, such as an order, and records the charge
+// will return error if the balance and the credit limit do not cover the charge
+func (c *Customer) Charge(cents int, reference uuid.UUID) (valueobject.Transaction, error) {
+	if cents < 1 {
+		return valueobject.Transaction{}, ErrInvalidCharge
+	}
+	if c.balance-cents < -c.creditLimit {
+		return valueobject.Transaction{}, ErrInsufficientFunds
+	}
+	c.balance -= cents
+	t := valueobject.NewTransaction(valueobject.TransactionWalletCharge, cents, c.GetID(), reference)
+	c.AddTransaction(t)
+	return t, nil
+}
+
+// Refund pays cents back into the wallet for a reference, such as a returned order, and records
+// the refund as a negative transaction
+func (c *Customer) Refund(cents int, reference uuid.UUID) (valueobject.Transaction, error) {
+	if cents < 1 {
+		return valueobject.Transaction{}, ErrInvalidRefund
+	}
+	c.balance += cents
+	t := valueobject.NewTransaction(valueobject.TransactionRefund, -cents, c.GetID(), reference)
+	c.AddTransaction(t)
+	return t, nil
+}
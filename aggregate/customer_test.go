@@ -1,9 +1,14 @@
 package aggregate_test
 
 import (
+	"errors"
 	"testing"
+	"time"
 
 	"taverne/aggregate"
+	"taverne/valueobject"
+
+	"github.com/google/uuid"
 )
 
 func TestCustomer_NewCustomer(t *testing.T) {
@@ -35,3 +40,232 @@ func TestCustomer_NewCustomer(t *testing.T) {
 		})
 	}
 }
+
+func TestCustomer_NewCustomerOptions(t *testing.T) {
+	type testCase struct {
+		test        string
+		opts        []aggregate.CustomerOption
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			test: "Valid profile",
+			opts: []aggregate.CustomerOption{
+				aggregate.WithEmail("donald@duckburg.org"),
+				aggregate.WithPhone("+49 (30) 123-4567"),
+				aggregate.WithAllergies(valueobject.AllergenPeanuts, valueobject.AllergenMilk),
+				aggregate.WithDiets(valueobject.DietVegetarian),
+				aggregate.WithMarketingConsent(true),
+			},
+			expectedErr: nil,
+		},
+		{
+			test:        "Email validation",
+			opts:        []aggregate.CustomerOption{aggregate.WithEmail("donald.duckburg.org")},
+			expectedErr: aggregate.ErrInvalidEmail,
+		},
+		{
+			test:        "Phone validation",
+			opts:        []aggregate.CustomerOption{aggregate.WithPhone("call me")},
+			expectedErr: aggregate.ErrInvalidPhone,
+		},
+		{
+			test:        "Allergen validation",
+			opts:        []aggregate.CustomerOption{aggregate.WithAllergies("strawberries")},
+			expectedErr: aggregate.ErrInvalidAllergen,
+		},
+		{
+			test:        "Diet validation",
+			opts:        []aggregate.CustomerOption{aggregate.WithDiets("carnivore")},
+			expectedErr: aggregate.ErrInvalidDiet,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			_, err := aggregate.NewCustomer("Donald Duck", tc.opts...)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestCustomer_Apply(t *testing.T) {
+	cust, err := aggregate.NewCustomer("Donald Duck", aggregate.WithEmail("donald@duckburg.org"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := cust
+
+	err = cust.Apply(
+		aggregate.WithName(""),
+		aggregate.WithEmail("donald"),
+		aggregate.WithPhone("0171 2345678"),
+	)
+	var verrs aggregate.ValidationErrors
+	if !errors.As(err, &verrs) {
+		t.Fatalf("Expected validation errors, got %v", err)
+	}
+	if len(verrs) != 2 || verrs.Field("name") != aggregate.ErrInvalidPerson || verrs.Field("email") != aggregate.ErrInvalidEmail {
+		t.Errorf("Expected errors of name and email, got %v", err)
+	}
+	// nothing is changed as long as one field is invalid
+	if cust.GetPhone() != "" || cust.GetEmail() != "donald@duckburg.org" {
+		t.Errorf("Expected the customer to be unchanged, got %s %s", cust.GetEmail(), cust.GetPhone())
+	}
+
+	if err := cust.Apply(aggregate.WithEmail("duck@duckburg.org")); err != nil {
+		t.Fatal(err)
+	}
+	if cust.GetEmail() != "duck@duckburg.org" {
+		t.Errorf("Expected email duck@duckburg.org, got %s", cust.GetEmail())
+	}
+	// copies of the customer, such as the one in a memory repository, keep the old values
+	if stored.GetEmail() != "donald@duckburg.org" {
+		t.Errorf("Expected the copy to keep donald@duckburg.org, got %s", stored.GetEmail())
+	}
+}
+
+func TestCustomer_Points(t *testing.T) {
+	cust, err := aggregate.NewCustomer("Donald Duck")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cust.AddPoints(0); err != aggregate.ErrInvalidPoints {
+		t.Errorf("Expected error %v, got %v", aggregate.ErrInvalidPoints, err)
+	}
+	if err := cust.AddPoints(10); err != nil {
+		t.Fatal(err)
+	}
+	if err := cust.RedeemPoints(11); err != aggregate.ErrInsufficientPoints {
+		t.Errorf("Expected error %v, got %v", aggregate.ErrInsufficientPoints, err)
+	}
+	if err := cust.RedeemPoints(4); err != nil {
+		t.Fatal(err)
+	}
+	if cust.GetPoints() != 6 {
+		t.Errorf("Expected 6 points, got %d", cust.GetPoints())
+	}
+}
+
+func TestCustomer_Wallet(t *testing.T) {
+	cust, err := aggregate.NewCustomer("Donald Duck")
+	if err != nil {
+		t.Fatal(err)
+	}
+	order := uuid.New()
+
+	type testCase struct {
+		test            string
+		change          func(c *aggregate.Customer) error
+		expectedBalance int
+		expectedErr     error
+	}
+
+	testCases := []testCase{
+		{
+			test: "Top-up to another customer",
+			change: func(c *aggregate.Customer) error {
+				return c.TopUp(valueobject.NewTransaction(valueobject.TransactionTopUp, 1000, uuid.New(), uuid.New()))
+			},
+			expectedErr: aggregate.ErrInvalidTopUp,
+		},
+		{
+			test: "Top-up",
+			change: func(c *aggregate.Customer) error {
+				return c.TopUp(valueobject.NewTransaction(valueobject.TransactionTopUp, 1000, c.GetID(), c.GetID()))
+			},
+			expectedBalance: 1000,
+		},
+		{
+			test: "Charge within balance",
+			change: func(c *aggregate.Customer) error {
+				_, err := c.Charge(800, order)
+				return err
+			},
+			expectedBalance: 200,
+		},
+		{
+			test: "Charge beyond balance",
+			change: func(c *aggregate.Customer) error {
+				_, err := c.Charge(300, order)
+				return err
+			},
+			expectedBalance: 200,
+			expectedErr:     aggregate.ErrInsufficientFunds,
+		},
+		{
+			test: "Charge within credit limit",
+			change: func(c *aggregate.Customer) error {
+				if err := c.SetCreditLimit(100); err != nil {
+					return err
+				}
+				_, err := c.Charge(300, order)
+				return err
+			},
+			expectedBalance: -100,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			err := tc.change(&cust)
+			if err != tc.expectedErr {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+			if cust.GetBalance() != tc.expectedBalance {
+				t.Errorf("Expected balance %d, got %d", tc.expectedBalance, cust.GetBalance())
+			}
+		})
+	}
+
+	// one top-up and two charges
+	if got := len(cust.GetTransactions()); got != 3 {
+		t.Errorf("Expected 3 transactions, got %d", got)
+	}
+}
+
+func TestCustomer_AgeAt(t *testing.T) {
+	cust, err := aggregate.NewCustomer("Donald Duck")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2023, time.March, 1, 12, 0, 0, 0, time.UTC)
+	if _, err := cust.AgeAt(at); err != aggregate.ErrAgeUnverified {
+		t.Errorf("Expected error %v, got %v", aggregate.ErrAgeUnverified, err)
+	}
+	if err := cust.SetBirthDate(time.Now().Add(time.Hour)); err != aggregate.ErrInvalidBirthDate {
+		t.Errorf("Expected error %v, got %v", aggregate.ErrInvalidBirthDate, err)
+	}
+
+	// born on a leap day, in other years the birthday is only reached on the 1st of March
+	if err := cust.SetBirthDate(time.Date(2000, time.February, 29, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		test        string
+		at          time.Time
+		expectedAge int
+	}
+
+	testCases := []testCase{
+		{test: "Day before", at: at.AddDate(0, 0, -1), expectedAge: 22},
+		{test: "Birthday", at: at, expectedAge: 23},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			age, err := cust.AgeAt(tc.at)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if age != tc.expectedAge {
+				t.Errorf("Expected age %d, got %d", tc.expectedAge, age)
+			}
+		})
+	}
+}
@@ -0,0 +1,488 @@
+package aggregate
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"taverne/valueobject"
+
+	"github.com/google/uuid"
+)
+
+// OrderStatus is the step of the lifecycle an order is in
+type OrderStatus string
+
+const (
+	OrderPlaced    OrderStatus = "placed"
+	OrderPreparing OrderStatus = "preparing"
+	OrderServed    OrderStatus = "served"
+	OrderPaid      OrderStatus = "paid"
+	OrderCancelled OrderStatus = "cancelled"
+	OrderRefunded  OrderStatus = "refunded"
+)
+
+var (
+	// ErrOrderWithoutItems is returned when an order is created without any items
+	ErrOrderWithoutItems = errors.New("an order has to have at least one item")
+	// ErrInvalidDiscount is returned when a discount is negative or exceeds the order total
+	ErrInvalidDiscount = errors.New("a discount has to be positive and can not exceed the order total")
+	// ErrOrderNotPlaced is returned when an order is changed after it left the placed status
+	ErrOrderNotPlaced = errors.New("the order can only be changed while it is placed")
+	// ErrIllegalTransition is returned when an order can not move into the requested status
+	ErrIllegalTransition = errors.New("illegal order status transition")
+	// ErrInvoiceNumberAssigned is returned when an order which has an invoice number gets another one
+	ErrInvoiceNumberAssigned = errors.New("the order has an invoice number already")
+	// ErrOrderNotBilled is returned when an order which was never billed, or was cancelled, is refunded
+	ErrOrderNotBilled = errors.New("only a billed order can be refunded")
+	// ErrInvalidRefund is returned when a refund is not a positive amount
+	ErrInvalidRefund = errors.New("a refund has to be a positive amount")
+	// ErrInvalidReturn is returned when a returned item is not in the order or more are returned than were ordered
+	ErrInvalidReturn = errors.New("the returned items do not match the order")
+	// ErrRefundExceedsPayment is returned when more would be refunded than the customer paid
+	ErrRefundExceedsPayment = errors.New("a refund can not exceed what was paid")
+	// ErrInvalidInvoiceNumber is returned when an empty invoice number is assigned
+	ErrInvalidInvoiceNumber = errors.New("an invoice number can not be empty")
+	// ErrRefundNotFound is returned when a refund is revoked which the order does not have
+	ErrRefundNotFound = errors.New("the order has no refund with the given ID")
+	// ErrOrderInvoiced is returned when an order which has an invoice number is cancelled
+	ErrOrderInvoiced = errors.New("an invoiced order can not be cancelled")
+)
+
+// orderTransitions lists for every status the statuses an order may move into
+//
+//	placed → preparing → served → paid → refunded
+//	placed, preparing → paid
+//	placed, preparing → cancelled
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderPlaced:    {OrderPreparing, OrderPaid, OrderCancelled},
+	OrderPreparing: {OrderServed, OrderPaid, OrderCancelled},
+	OrderServed:    {OrderPaid},
+	OrderPaid:      {OrderRefunded},
+}
+
+// TransitionError is returned when an order is moved along an edge the lifecycle does not allow
+// It matches ErrIllegalTransition with errors.Is
+type TransitionError struct {
+	From OrderStatus
+	To   OrderStatus
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("%v: %s → %s", ErrIllegalTransition, e.From, e.To)
+}
+
+func (e *TransitionError) Unwrap() error {
+	return ErrIllegalTransition
+}
+
+// Order is an aggregate of everything a customer ordered at once
+type Order struct {
+	// id is the root identifier of the order
+	id        uuid.UUID
+	customer  uuid.UUID
+	items     []valueobject.OrderItem
+	discounts []valueobject.Discount
+	tax       valueobject.TaxBreakdown
+	status    OrderStatus
+	placedAt  time.Time
+	// table is the table the order is served at, uuid.Nil for orders at the bar or to go
+	table uuid.UUID
+	// invoiceNumber is assigned once the order is billed
+	invoiceNumber string
+	refunds       []valueobject.Refund
+	// coupons are the coupon codes handed in for the discounts of the order
+	coupons []string
+	// redeemedPoints are the loyalty points the customer paid part of the order with
+	redeemedPoints int
+	// tab is the tab the order was put on, uuid.Nil for orders billed on their own
+	tab uuid.UUID
+}
+
+// NewOrder is a factory to create a new placed Order for a customer
+func NewOrder(customer uuid.UUID, items []valueobject.OrderItem) (Order, error) {
+	if len(items) == 0 {
+		return Order{}, ErrOrderWithoutItems
+	}
+
+	return Order{
+		id:       uuid.New(),
+		customer: customer,
+		items:    append([]valueobject.OrderItem(nil), items...),
+		status:   OrderPlaced,
+		placedAt: time.Now(),
+	}, nil
+}
+
+// GetID returns the orders root ID
+func (o Order) GetID() uuid.UUID {
+	return o.id
+}
+
+// GetCustomerID returns the ID of the customer who placed the order
+func (o Order) GetCustomerID() uuid.UUID {
+	return o.customer
+}
+
+// GetItems returns a copy of the ordered items
+func (o Order) GetItems() []valueobject.OrderItem {
+	return append([]valueobject.OrderItem(nil), o.items...)
+}
+
+// GetStatus returns the current lifecycle status
+func (o Order) GetStatus() OrderStatus {
+	return o.status
+}
+
+// GetPlacedAt returns when the order was placed
+func (o Order) GetPlacedAt() time.Time {
+	return o.placedAt
+}
+
+// GetTableID returns the table the order is served at, uuid.Nil if it is not served at a table
+func (o Order) GetTableID() uuid.UUID {
+	return o.table
+}
+
+// SetTableID changes the table the order is served at, such as when the guests move
+func (o *Order) SetTableID(table uuid.UUID) {
+	o.table = table
+}
+
+// GetTabID returns the tab the order was put on, uuid.Nil if it was billed on its own
+func (o Order) GetTabID() uuid.UUID {
+	return o.tab
+}
+
+// SetTabID records the tab the order was put on
+func (o *Order) SetTabID(tab uuid.UUID) {
+	o.tab = tab
+}
+
+// GetInvoiceNumber returns the invoice number, empty as long as the order is not billed
+func (o Order) GetInvoiceNumber() string {
+	return o.invoiceNumber
+}
+
+// SetInvoiceNumber assigns the invoice number of the billed order, an order only ever gets one number
+func (o *Order) SetInvoiceNumber(number string) error {
+	if number == "" {
+		return ErrInvalidInvoiceNumber
+	}
+	if o.invoiceNumber != "" {
+		return ErrInvoiceNumberAssigned
+	}
+	o.invoiceNumber = number
+	return nil
+}
+
+// GetCoupons returns the coupon codes handed in with the order
+func (o Order) GetCoupons() []string {
+	return append([]string(nil), o.coupons...)
+}
+
+// SetCoupons records the coupon codes handed in with the order
+func (o *Order) SetCoupons(codes []string) {
+	o.coupons = append([]string(nil), codes...)
+}
+
+// GetRedeemedPoints returns the loyalty points the customer paid part of the order with
+func (o Order) GetRedeemedPoints() int {
+	return o.redeemedPoints
+}
+
+// SetRedeemedPoints records the loyalty points the customer paid part of the order with
+func (o *Order) SetRedeemedPoints(points int) {
+	o.redeemedPoints = points
+}
+
+// GetDiscounts returns a copy of all discounts applied to the order
+func (o Order) GetDiscounts() []valueobject.Discount {
+	return append([]valueobject.Discount(nil), o.discounts...)
+}
+
+// Subtotal returns the sum of all items before discounts
+func (o Order) Subtotal() float64 {
+	var subtotal float64
+	for _, i := range o.items {
+		subtotal += i.Total()
+	}
+	return subtotal
+}
+
+// Total returns the sum of all items minus all discounts
+func (o Order) Total() float64 {
+	total := o.Subtotal()
+	for _, d := range o.discounts {
+		total -= d.Amount
+	}
+	return total
+}
+
+// DiscountedItemTotals returns what is left to pay of every item after discounts.
+// A discount on a product reduces the items of that product, a discount on the whole
+// order is spread over all items in proportion to what is left of them
+func (o Order) DiscountedItemTotals() []float64 {
+	cents := make([]int, len(o.items))
+	for i, item := range o.items {
+		cents[i] = toCents(item.Total())
+	}
+
+	var orderWide int
+	for _, d := range o.discounts {
+		if d.ProductID == uuid.Nil {
+			orderWide += toCents(d.Amount)
+			continue
+		}
+		spread(cents, toCents(d.Amount), func(i int) bool {
+			return o.items[i].ProductID == d.ProductID
+		})
+	}
+	spread(cents, orderWide, func(int) bool { return true })
+
+	totals := make([]float64, len(cents))
+	for i, c := range cents {
+		totals[i] = float64(c) / 100
+	}
+	return totals
+}
+
+// GetTaxBreakdown returns the tax included in the total
+func (o Order) GetTaxBreakdown() valueobject.TaxBreakdown {
+	return o.tax
+}
+
+// SetTaxBreakdown stores the tax included in the total
+func (o *Order) SetTaxBreakdown(b valueobject.TaxBreakdown) {
+	o.tax = b
+}
+
+// ApplyDiscounts reduces the total of a placed order
+func (o *Order) ApplyDiscounts(discounts ...valueobject.Discount) error {
+	if o.status != OrderPlaced {
+		return ErrOrderNotPlaced
+	}
+	total := o.Total()
+	for _, d := range discounts {
+		if d.Amount < 0 || d.Amount > total+0.000001 {
+			return ErrInvalidDiscount
+		}
+		total -= d.Amount
+	}
+	o.discounts = append(o.discounts, discounts...)
+	return nil
+}
+
+// Prepare moves a placed order into the kitchen
+func (o *Order) Prepare() error {
+	return o.transition(OrderPreparing)
+}
+
+// Serve marks a prepared order as served to the customer
+func (o *Order) Serve() error {
+	return o.transition(OrderServed)
+}
+
+// Pay marks an order as paid, an order billed at the counter is paid before it is served
+func (o *Order) Pay() error {
+	return o.transition(OrderPaid)
+}
+
+// Cancel cancels an order which has not been served, paid or invoiced yet
+func (o *Order) Cancel() error {
+	if o.invoiceNumber != "" {
+		return ErrOrderInvoiced
+	}
+	return o.transition(OrderCancelled)
+}
+
+// Refund marks a paid order as refunded
+func (o *Order) Refund() error {
+	return o.transition(OrderRefunded)
+}
+
+// GetRefunds returns a copy of all refunds of the order
+func (o Order) GetRefunds() []valueobject.Refund {
+	return append([]valueobject.Refund(nil), o.refunds...)
+}
+
+// Refunded returns the sum of all refunds
+func (o Order) Refunded() float64 {
+	var cents int
+	for _, r := range o.refunds {
+		cents += toCents(r.Amount)
+	}
+	return float64(cents) / 100
+}
+
+// Returned returns how many of the item at index i were handed back
+func (o Order) Returned(i int) int {
+	var n int
+	for _, r := range o.refunds {
+		for _, ri := range r.Returned {
+			if ri.Item == i {
+				n += ri.Quantity
+			}
+		}
+	}
+	return n
+}
+
+// IssueRefund pays back the value of the returned items plus extra on top, such as for a complaint.
+// Returned items are valued at what was paid for them after discounts.
+// will return error if the order was not billed or more would be refunded than was paid
+func (o *Order) IssueRefund(returned []valueobject.ReturnedItem, extra float64, reason string) (valueobject.Refund, error) {
+	if o.invoiceNumber == "" || o.status == OrderCancelled {
+		return valueobject.Refund{}, ErrOrderNotBilled
+	}
+	if extra < 0 {
+		return valueobject.Refund{}, ErrInvalidRefund
+	}
+
+	// remaining is what is left to refund of every item
+	paid := o.DiscountedItemTotals()
+	remaining := make([]int, len(o.items))
+	for i := range o.items {
+		remaining[i] = toCents(paid[i])
+		for _, r := range o.refunds {
+			remaining[i] -= toCents(r.Amounts[i])
+		}
+	}
+
+	amounts := make([]int, len(o.items))
+	counted := make(map[int]int)
+	for _, ri := range returned {
+		if ri.Item < 0 || ri.Item >= len(o.items) || ri.Quantity < 1 {
+			return valueobject.Refund{}, ErrInvalidReturn
+		}
+		before := o.Returned(ri.Item) + counted[ri.Item]
+		quantity := o.items[ri.Item].Quantity
+		if before+ri.Quantity > quantity {
+			return valueobject.Refund{}, ErrInvalidReturn
+		}
+		counted[ri.Item] += ri.Quantity
+
+		// valued as the difference of the shares, so returning all items one by one adds up to the item total
+		item := toCents(paid[ri.Item])
+		value := shareOf(item, before+ri.Quantity, quantity) - shareOf(item, before, quantity)
+		amounts[ri.Item] += min(value, remaining[ri.Item]-amounts[ri.Item])
+	}
+
+	var total, rest int
+	left := make([]int, len(o.items))
+	for i := range amounts {
+		total += amounts[i]
+		left[i] = remaining[i] - amounts[i]
+		rest += left[i]
+	}
+	if toCents(extra) > rest {
+		return valueobject.Refund{}, ErrRefundExceedsPayment
+	}
+	if extra > 0 {
+		// the extra amount is taken from the items in proportion to what is left of them
+		shares := append([]int(nil), left...)
+		spread(shares, toCents(extra), func(int) bool { return true })
+		for i := range amounts {
+			amounts[i] += left[i] - shares[i]
+			total += left[i] - shares[i]
+		}
+	}
+	if total < 1 {
+		return valueobject.Refund{}, ErrInvalidRefund
+	}
+	if toCents(o.Refunded())+total > toCents(o.Total()) {
+		return valueobject.Refund{}, ErrRefundExceedsPayment
+	}
+
+	r := valueobject.Refund{
+		ID:        uuid.New(),
+		Amount:    float64(total) / 100,
+		Amounts:   make([]float64, len(amounts)),
+		Returned:  append([]valueobject.ReturnedItem(nil), returned...),
+		Reason:    reason,
+		CreatedAt: time.Now(),
+	}
+	for i, c := range amounts {
+		r.Amounts[i] = float64(c) / 100
+	}
+	// append to a copy, so copies of the order do not share the new refund
+	o.refunds = append(o.GetRefunds(), r)
+	if toCents(o.Refunded()) == toCents(o.Total()) && o.status == OrderPaid {
+		o.status = OrderRefunded
+	}
+	return r, nil
+}
+
+// RevokeRefund takes back a refund which could not be paid out, a refunded order is paid again
+func (o *Order) RevokeRefund(id uuid.UUID) error {
+	for i, r := range o.refunds {
+		if r.ID != id {
+			continue
+		}
+		// remove from a copy, so copies of the order keep their refunds
+		refunds := o.GetRefunds()
+		o.refunds = append(refunds[:i], refunds[i+1:]...)
+		if o.status == OrderRefunded {
+			o.status = OrderPaid
+		}
+		return nil
+	}
+	return ErrRefundNotFound
+}
+
+// shareOf returns the cents of n of quantity items which cost cents together
+func shareOf(cents, n, quantity int) int {
+	return int(math.Round(float64(cents) * float64(n) / float64(quantity)))
+}
+
+// transition moves the order into status to if the lifecycle allows it
+func (o *Order) transition(to OrderStatus) error {
+	for _, allowed := range orderTransitions[o.status] {
+		if allowed == to {
+			o.status = to
+			return nil
+		}
+	}
+	return &TransitionError{From: o.status, To: to}
+}
+
+// spread subtracts amount cents from the matching totals in proportion to their size
+func spread(cents []int, amount int, match func(i int) bool) {
+	var base int
+	for i, c := range cents {
+		if match(i) {
+			base += c
+		}
+	}
+	if base <= 0 || amount <= 0 {
+		return
+	}
+
+	rest := amount
+	for i, c := range cents {
+		if match(i) {
+			part := amount * c / base
+			cents[i] -= part
+			rest -= part
+		}
+	}
+	// the cents lost by rounding down are taken one by one from the items which have some left
+	for rest > 0 {
+		taken := false
+		for i := range cents {
+			if rest > 0 && match(i) && cents[i] > 0 {
+				cents[i]--
+				rest--
+				taken = true
+			}
+		}
+		if !taken {
+			return
+		}
+	}
+}
+
+func toCents(amount float64) int {
+	return int(math.Round(amount * 100))
+}
@@ -0,0 +1,316 @@
+package aggregate_test
+
+import (
+	"errors"
+	"math"
+	"taverne/aggregate"
+	"taverne/valueobject"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestOrder_NewOrder(t *testing.T) {
+	type testCase struct {
+		test        string
+		items       []valueobject.OrderItem
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			test:        "Order without items",
+			items:       nil,
+			expectedErr: aggregate.ErrOrderWithoutItems,
+		},
+		{
+			test: "Valid order",
+			items: []valueobject.OrderItem{
+				{ProductID: uuid.New(), Name: "Beer", Quantity: 2, UnitPrice: 1.5},
+			},
+			expectedErr: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			o, err := aggregate.NewOrder(uuid.New(), tc.items)
+			if err != tc.expectedErr {
+				t.Fatalf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+			if err == nil && o.GetStatus() != aggregate.OrderPlaced {
+				t.Errorf("Expected status %s, got %s", aggregate.OrderPlaced, o.GetStatus())
+			}
+		})
+	}
+}
+
+func TestOrder_Transitions(t *testing.T) {
+	type testCase struct {
+		test        string
+		steps       []func(*aggregate.Order) error
+		expected    aggregate.OrderStatus
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			test:     "Placed to paid",
+			steps:    []func(*aggregate.Order) error{(*aggregate.Order).Prepare, (*aggregate.Order).Serve, (*aggregate.Order).Pay},
+			expected: aggregate.OrderPaid,
+		},
+		{
+			test:     "Cancel placed order",
+			steps:    []func(*aggregate.Order) error{(*aggregate.Order).Cancel},
+			expected: aggregate.OrderCancelled,
+		},
+		{
+			test:     "Refund paid order",
+			steps:    []func(*aggregate.Order) error{(*aggregate.Order).Prepare, (*aggregate.Order).Serve, (*aggregate.Order).Pay, (*aggregate.Order).Refund},
+			expected: aggregate.OrderRefunded,
+		},
+		{
+			test:     "Pay before serving",
+			steps:    []func(*aggregate.Order) error{(*aggregate.Order).Pay},
+			expected: aggregate.OrderPaid,
+		},
+		{
+			test:        "Cancel paid order",
+			steps:       []func(*aggregate.Order) error{(*aggregate.Order).Pay, (*aggregate.Order).Cancel},
+			expected:    aggregate.OrderPaid,
+			expectedErr: aggregate.ErrIllegalTransition,
+		},
+		{
+			test:        "Serve paid order",
+			steps:       []func(*aggregate.Order) error{(*aggregate.Order).Pay, (*aggregate.Order).Serve},
+			expected:    aggregate.OrderPaid,
+			expectedErr: aggregate.ErrIllegalTransition,
+		},
+		{
+			test:        "Cancel served order",
+			steps:       []func(*aggregate.Order) error{(*aggregate.Order).Prepare, (*aggregate.Order).Serve, (*aggregate.Order).Cancel},
+			expected:    aggregate.OrderServed,
+			expectedErr: aggregate.ErrIllegalTransition,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			o, err := aggregate.NewOrder(uuid.New(), []valueobject.OrderItem{
+				{ProductID: uuid.New(), Name: "Beer", Quantity: 1, UnitPrice: 1.5},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, step := range tc.steps {
+				if err = step(&o); err != nil {
+					break
+				}
+			}
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+			var terr *aggregate.TransitionError
+			if tc.expectedErr != nil && !errors.As(err, &terr) {
+				t.Errorf("Expected a TransitionError, got %T", err)
+			}
+			if o.GetStatus() != tc.expected {
+				t.Errorf("Expected status %s, got %s", tc.expected, o.GetStatus())
+			}
+		})
+	}
+}
+
+func TestOrder_CancelInvoiced(t *testing.T) {
+	o, err := aggregate.NewOrder(uuid.New(), []valueobject.OrderItem{
+		{ProductID: uuid.New(), Name: "Beer", Quantity: 1, UnitPrice: 1.5},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := o.SetInvoiceNumber("2026-000001"); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Cancel(); err != aggregate.ErrOrderInvoiced {
+		t.Errorf("Expected error %v, got %v", aggregate.ErrOrderInvoiced, err)
+	}
+	if o.GetStatus() != aggregate.OrderPlaced {
+		t.Errorf("Expected status %s, got %s", aggregate.OrderPlaced, o.GetStatus())
+	}
+}
+
+func TestOrder_DiscountedItemTotals(t *testing.T) {
+	beer, peanuts := uuid.New(), uuid.New()
+	o, err := aggregate.NewOrder(uuid.New(), []valueobject.OrderItem{
+		{ProductID: beer, Name: "Beer", Quantity: 2, UnitPrice: 5},
+		{ProductID: peanuts, Name: "Peanuts", Quantity: 1, UnitPrice: 2},
+		{ProductID: beer, Name: "Beer", Quantity: 1, UnitPrice: 5},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = o.ApplyDiscounts(
+		valueobject.Discount{Description: "Happy hour", ProductID: beer, Amount: 3},
+		valueobject.Discount{Description: "Coupon", Amount: 1},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// beer 15 - 3 = 12 split 8/4, then 1 spread over 14 total
+	expected := []float64{7.42, 1.86, 3.72}
+	totals := o.DiscountedItemTotals()
+	var sum float64
+	for i, total := range totals {
+		if total != expected[i] {
+			t.Errorf("Expected item %d to total %.2f, got %.2f", i, expected[i], total)
+		}
+		sum += total
+	}
+	if math.Abs(sum-o.Total()) > 0.0001 {
+		t.Errorf("Expected items to sum up to %.2f, got %.2f", o.Total(), sum)
+	}
+
+	if err := o.ApplyDiscounts(valueobject.Discount{Amount: 100}); err != aggregate.ErrInvalidDiscount {
+		t.Errorf("Expected error %v, got %v", aggregate.ErrInvalidDiscount, err)
+	}
+}
+
+func TestOrder_IssueRefund(t *testing.T) {
+	ord, err := aggregate.NewOrder(uuid.New(), []valueobject.OrderItem{
+		{Name: "Beer", Quantity: 3, UnitPrice: 2},
+		{Name: "Burger", Quantity: 1, UnitPrice: 9},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 5.40 is paid for the beers and 8.10 for the burger
+	if err := ord.ApplyDiscounts(valueobject.Discount{Description: "Regular", Amount: 1.5}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ord.IssueRefund(nil, 1, ""); err != aggregate.ErrOrderNotBilled {
+		t.Errorf("Expected error %v, got %v", aggregate.ErrOrderNotBilled, err)
+	}
+	if err := ord.SetInvoiceNumber("2024-000001"); err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		test            string
+		returned        []valueobject.ReturnedItem
+		extra           float64
+		expectedAmounts []float64
+		expectedErr     error
+	}
+
+	testCases := []testCase{
+		{
+			test:            "Return a beer",
+			returned:        []valueobject.ReturnedItem{{Item: 0, Quantity: 1}},
+			expectedAmounts: []float64{1.8, 0},
+		},
+		{
+			test:        "Return more beers than are left",
+			returned:    []valueobject.ReturnedItem{{Item: 0, Quantity: 3}},
+			expectedErr: aggregate.ErrInvalidReturn,
+		},
+		{
+			test:        "Return an item which was not ordered",
+			returned:    []valueobject.ReturnedItem{{Item: 2, Quantity: 1}},
+			expectedErr: aggregate.ErrInvalidReturn,
+		},
+		{
+			test:        "Refund more than was paid",
+			extra:       20,
+			expectedErr: aggregate.ErrRefundExceedsPayment,
+		},
+		{
+			test:        "Refund nothing",
+			expectedErr: aggregate.ErrInvalidRefund,
+		},
+		{
+			test:            "Refund an amount on top",
+			extra:           1.17,
+			expectedAmounts: []float64{0.36, 0.81},
+		},
+		{
+			test:     "Return the other beers",
+			returned: []valueobject.ReturnedItem{{Item: 0, Quantity: 2}},
+			// the amount refunded on top already paid back part of the beers
+			expectedAmounts: []float64{3.24, 0},
+		},
+		{
+			test:            "Refund the rest",
+			extra:           7.29,
+			expectedAmounts: []float64{0, 7.29},
+		},
+		{
+			test:        "Refund a cent more",
+			extra:       0.01,
+			expectedErr: aggregate.ErrRefundExceedsPayment,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			r, err := ord.IssueRefund(tc.returned, tc.extra, tc.test)
+			if err != tc.expectedErr {
+				t.Fatalf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+			for i, expected := range tc.expectedAmounts {
+				if math.Abs(r.Amounts[i]-expected) > 1e-9 {
+					t.Errorf("Expected %.2f refunded of item %d, got %.2f", expected, i, r.Amounts[i])
+				}
+			}
+		})
+	}
+
+	if ord.Refunded() != ord.Total() {
+		t.Errorf("Expected %.2f refunded, got %.2f", ord.Total(), ord.Refunded())
+	}
+	if ord.Returned(0) != 3 || len(ord.GetRefunds()) != 4 {
+		t.Errorf("Expected 3 beers returned with 4 refunds, got %d with %d", ord.Returned(0), len(ord.GetRefunds()))
+	}
+}
+
+func TestOrder_IssueRefundPaid(t *testing.T) {
+	ord, err := aggregate.NewOrder(uuid.New(), []valueobject.OrderItem{{Name: "Beer", Quantity: 2, UnitPrice: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ord.SetInvoiceNumber("2024-000001"); err != nil {
+		t.Fatal(err)
+	}
+	for _, transition := range []func() error{ord.Prepare, ord.Serve, ord.Pay} {
+		if err := transition(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a partial refund keeps the order paid, paying back everything refunds it
+	if _, err := ord.IssueRefund([]valueobject.ReturnedItem{{Item: 0, Quantity: 1}}, 0, ""); err != nil {
+		t.Fatal(err)
+	}
+	if ord.GetStatus() != aggregate.OrderPaid {
+		t.Errorf("Expected status %s, got %s", aggregate.OrderPaid, ord.GetStatus())
+	}
+	if _, err := ord.IssueRefund([]valueobject.ReturnedItem{{Item: 0, Quantity: 1}}, 0, ""); err != nil {
+		t.Fatal(err)
+	}
+	if ord.GetStatus() != aggregate.OrderRefunded {
+		t.Errorf("Expected status %s, got %s", aggregate.OrderRefunded, ord.GetStatus())
+	}
+
+	// revoking the last refund, which was never paid out, makes the order paid again
+	last := ord.GetRefunds()[1]
+	if err := ord.RevokeRefund(last.ID); err != nil {
+		t.Fatal(err)
+	}
+	if ord.GetStatus() != aggregate.OrderPaid || ord.Refunded() != 2 {
+		t.Errorf("Expected status %s with 2.00 refunded, got %s with %.2f", aggregate.OrderPaid, ord.GetStatus(), ord.Refunded())
+	}
+	if err := ord.RevokeRefund(last.ID); err != aggregate.ErrRefundNotFound {
+		t.Errorf("Expected error %v, got %v", aggregate.ErrRefundNotFound, err)
+	}
+}
@@ -3,7 +3,11 @@ package aggregate
 
 import (
 	"errors"
+	"fmt"
+	"sort"
 	"taverne/entity"
+	"taverne/valueobject"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -11,15 +15,48 @@ import (
 var (
 	// ErrMissingValues is returned when a product is created without a name or description
 	ErrMissingValues = errors.New("missing value")
+	// ErrInsufficientStock is returned when more products are taken out of stock than available
+	ErrInsufficientStock = errors.New("not enough products in stock")
+	// ErrProductUnavailable is returned when an unavailable or archived product is ordered
+	ErrProductUnavailable = errors.New("the product is not available")
+	// ErrInvalidPrice is returned when a price or surcharge is negative
+	ErrInvalidPrice = errors.New("a price can not be negative")
+	// ErrVariantNotFound is returned when a product has no variant with the given ID
+	ErrVariantNotFound = errors.New("the product has no such variant")
+	// ErrVariantRequired is returned when a product with variants is ordered without choosing one
+	ErrVariantRequired = errors.New("a variant of the product has to be chosen")
+	// ErrModifierNotFound is returned when a product has no modifier with the given ID
+	ErrModifierNotFound = errors.New("the product has no such modifier")
+	// ErrInvalidStockChange is returned when stock is changed by a quantity below one
+	ErrInvalidStockChange = errors.New("stock can only be changed by a positive quantity")
+	// ErrInvalidMinimumAge is returned when the minimum age of a product is negative
+	ErrInvalidMinimumAge = errors.New("a minimum age can not be negative")
 )
 
 // Product is a aggregate that combines item with a price and quantity
 type Product struct {
 	// item is the root entity which is an item
-	item  *entity.Item
-	price float64
+	item *entity.Item
+	// prices is the price history sorted by the time each price is in effect from
+	prices []valueobject.PriceChange
 	// Quantity is the number of products in stock
 	quantity int
+	// taxCategory decides at which rate the product is taxed
+	taxCategory valueobject.TaxCategory
+	// allergens are the declared allergens the product contains
+	allergens []valueobject.Allergen
+	// minimumAge is the age a customer has to be to order the product, zero for everyone
+	minimumAge int
+	// categories are the IDs of all menu categories the product is listed in
+	categories []uuid.UUID
+	// variants are the sizes or kinds the product is sold in, each with its own price and stock
+	variants []entity.Variant
+	// modifiers are the extras which can be ordered with the product
+	modifiers []entity.Modifier
+	// unavailable marks a product which ran out for the day (86'd)
+	unavailable bool
+	// archived marks a product which was taken off the menu, it is kept for historic orders
+	archived bool
 }
 
 // NewProduct will create a new product
@@ -35,7 +72,8 @@ func NewProduct(name, description string, price float64) (Product, error) {
 			Name:        name,
 			Description: description,
 		},
-		price:    price,
+		// the initial price is in effect since forever
+		prices:   []valueobject.PriceChange{{Price: price}},
 		quantity: 0,
 	}, nil
 }
@@ -48,6 +86,397 @@ func (p Product) GetItem() *entity.Item {
 	return p.item
 }
 
+// SetID sets the root ID
+func (p *Product) SetID(id uuid.UUID) {
+	p.changeItem(func(i *entity.Item) { i.ID = id })
+}
+
+// SetName changes the name of the product
+func (p *Product) SetName(name string) error {
+	if name == "" {
+		return ErrMissingValues
+	}
+	p.changeItem(func(i *entity.Item) { i.Name = name })
+	return nil
+}
+
+// SetDescription changes the description of the product
+func (p *Product) SetDescription(description string) error {
+	if description == "" {
+		return ErrMissingValues
+	}
+	p.changeItem(func(i *entity.Item) { i.Description = description })
+	return nil
+}
+
+// changeItem changes a copy of the item, copies of the product share the item pointer
+func (p *Product) changeItem(change func(i *entity.Item)) {
+	item := entity.Item{}
+	if p.item != nil {
+		item = *p.item
+	}
+	change(&item)
+	p.item = &item
+}
+
+// GetPrice returns the price in effect right now
 func (p Product) GetPrice() float64 {
-	return p.price
+	return p.PriceAt(time.Now())
+}
+
+// PriceAt returns the price which was, or will be, in effect at a point in time
+func (p Product) PriceAt(at time.Time) float64 {
+	return priceAt(0, p.prices, at)
+}
+
+// GetPriceHistory returns a copy of all prices sorted by the time they are in effect from
+func (p Product) GetPriceHistory() []valueobject.PriceChange {
+	return append([]valueobject.PriceChange(nil), p.prices...)
+}
+
+// ChangePrice schedules a new price which is in effect from effectiveFrom on.
+// A price already scheduled for the very same time is replaced
+func (p *Product) ChangePrice(price float64, effectiveFrom time.Time) error {
+	if price < 0 {
+		return ErrInvalidPrice
+	}
+	p.prices = schedule(p.prices, valueobject.PriceChange{Price: price, EffectiveFrom: effectiveFrom})
+	return nil
+}
+
+// priceAt returns the price of a history in effect at a point in time, initial before the first change
+func priceAt(initial float64, prices []valueobject.PriceChange, at time.Time) float64 {
+	price := initial
+	for _, c := range prices {
+		if c.EffectiveFrom.After(at) {
+			break
+		}
+		price = c.Price
+	}
+	return price
+}
+
+// schedule returns a copy of a price history with the change added in order of time,
+// replacing a price which was scheduled for the very same time
+func schedule(history []valueobject.PriceChange, change valueobject.PriceChange) []valueobject.PriceChange {
+	prices := make([]valueobject.PriceChange, 0, len(history)+1)
+	for _, c := range history {
+		if !c.EffectiveFrom.Equal(change.EffectiveFrom) {
+			prices = append(prices, c)
+		}
+	}
+	prices = append(prices, change)
+	sort.SliceStable(prices, func(i, j int) bool {
+		return prices[i].EffectiveFrom.Before(prices[j].EffectiveFrom)
+	})
+	return prices
+}
+
+// GetQuantity returns the number of products in stock
+func (p Product) GetQuantity() int {
+	return p.quantity
+}
+
+// AddStock puts n products into stock
+func (p *Product) AddStock(n int) error {
+	if n < 1 {
+		return ErrInvalidStockChange
+	}
+	p.quantity += n
+	return nil
+}
+
+// RemoveStock takes n products out of stock
+// will return error if there are less than n products in stock
+func (p *Product) RemoveStock(n int) error {
+	if n < 1 {
+		return ErrInvalidStockChange
+	}
+	if p.quantity < n {
+		return ErrInsufficientStock
+	}
+	p.quantity -= n
+	return nil
+}
+
+// GetTaxCategory returns the tax category of the product
+func (p Product) GetTaxCategory() valueobject.TaxCategory {
+	return p.taxCategory
+}
+
+// SetTaxCategory changes the tax category of the product
+func (p *Product) SetTaxCategory(c valueobject.TaxCategory) {
+	p.taxCategory = c
+}
+
+// GetAllergens returns the allergens the product contains
+func (p Product) GetAllergens() []valueobject.Allergen {
+	return append([]valueobject.Allergen(nil), p.allergens...)
+}
+
+// SetAllergens replaces the allergens the product contains
+// will return error if any allergen is not one of the declared allergens
+func (p *Product) SetAllergens(allergens ...valueobject.Allergen) error {
+	for _, a := range allergens {
+		if !a.Valid() {
+			return fmt.Errorf("%q: %w", a, ErrInvalidAllergen)
+		}
+	}
+	p.allergens = append([]valueobject.Allergen(nil), allergens...)
+	return nil
+}
+
+// Contains returns the given allergens which the product contains
+func (p Product) Contains(allergens []valueobject.Allergen) []valueobject.Allergen {
+	var found []valueobject.Allergen
+	for _, a := range allergens {
+		for _, contained := range p.allergens {
+			if a == contained {
+				found = append(found, a)
+				break
+			}
+		}
+	}
+	return found
+}
+
+// GetMinimumAge returns the age a customer has to be to order the product
+func (p Product) GetMinimumAge() int {
+	return p.minimumAge
+}
+
+// SetMinimumAge restricts the product to customers of at least the given age, such as 18 for spirits
+// will return error if the age is negative
+func (p *Product) SetMinimumAge(age int) error {
+	if age < 0 {
+		return ErrInvalidMinimumAge
+	}
+	p.minimumAge = age
+	return nil
+}
+
+// GetCategories returns the IDs of all categories the product is listed in
+func (p Product) GetCategories() []uuid.UUID {
+	return append([]uuid.UUID(nil), p.categories...)
+}
+
+// InCategory reports whether the product is listed in a category
+func (p Product) InCategory(id uuid.UUID) bool {
+	for _, c := range p.categories {
+		if c == id {
+			return true
+		}
+	}
+	return false
+}
+
+// AddToCategory lists the product in a category, a product can be in many categories
+func (p *Product) AddToCategory(id uuid.UUID) {
+	if p.InCategory(id) {
+		return
+	}
+	p.categories = append(p.GetCategories(), id)
+}
+
+// RemoveFromCategory removes the product from a category
+func (p *Product) RemoveFromCategory(id uuid.UUID) {
+	categories := make([]uuid.UUID, 0, len(p.categories))
+	for _, c := range p.categories {
+		if c != id {
+			categories = append(categories, c)
+		}
+	}
+	p.categories = categories
+}
+
+// GetVariants returns a copy of all variants of the product
+func (p Product) GetVariants() []entity.Variant {
+	return append([]entity.Variant(nil), p.variants...)
+}
+
+// GetVariant returns the variant with the given ID
+func (p Product) GetVariant(id uuid.UUID) (entity.Variant, error) {
+	for _, v := range p.variants {
+		if v.ID == id {
+			return v, nil
+		}
+	}
+	return entity.Variant{}, ErrVariantNotFound
+}
+
+// AddVariant adds a variant with its own price and an empty stock
+func (p *Product) AddVariant(name string, price float64) (uuid.UUID, error) {
+	if name == "" {
+		return uuid.Nil, ErrMissingValues
+	}
+	if price < 0 {
+		return uuid.Nil, ErrInvalidPrice
+	}
+	v := entity.Variant{
+		ID:    uuid.New(),
+		Name:  name,
+		Price: price,
+	}
+	p.variants = append(p.GetVariants(), v)
+	return v.ID, nil
+}
+
+// VariantPriceAt returns the price of a variant which was, or will be, in effect at a point in time
+func (p Product) VariantPriceAt(id uuid.UUID, at time.Time) (float64, error) {
+	v, err := p.GetVariant(id)
+	if err != nil {
+		return 0, err
+	}
+	return priceAt(v.Price, v.PriceChanges, at), nil
+}
+
+// ChangeVariantPrice schedules a new price of a variant which is in effect from effectiveFrom on.
+// A price already scheduled for the very same time is replaced
+func (p *Product) ChangeVariantPrice(id uuid.UUID, price float64, effectiveFrom time.Time) error {
+	if price < 0 {
+		return ErrInvalidPrice
+	}
+	return p.changeVariant(id, func(v *entity.Variant) error {
+		v.PriceChanges = schedule(v.PriceChanges, valueobject.PriceChange{Price: price, EffectiveFrom: effectiveFrom})
+		return nil
+	})
+}
+
+// AddVariantStock puts n products of a variant into stock
+func (p *Product) AddVariantStock(id uuid.UUID, n int) error {
+	if n < 1 {
+		return ErrInvalidStockChange
+	}
+	return p.changeVariant(id, func(v *entity.Variant) error {
+		v.Quantity += n
+		return nil
+	})
+}
+
+// RemoveVariantStock takes n products of a variant out of stock
+// will return error if there are less than n of them in stock
+func (p *Product) RemoveVariantStock(id uuid.UUID, n int) error {
+	if n < 1 {
+		return ErrInvalidStockChange
+	}
+	return p.changeVariant(id, func(v *entity.Variant) error {
+		if v.Quantity < n {
+			return ErrInsufficientStock
+		}
+		v.Quantity -= n
+		return nil
+	})
+}
+
+// changeVariant changes a copy of the variants, so copies of the product do not share the change
+func (p *Product) changeVariant(id uuid.UUID, change func(v *entity.Variant) error) error {
+	variants := p.GetVariants()
+	for i := range variants {
+		if variants[i].ID == id {
+			if err := change(&variants[i]); err != nil {
+				return err
+			}
+			p.variants = variants
+			return nil
+		}
+	}
+	return ErrVariantNotFound
+}
+
+// GetModifiers returns a copy of all modifiers of the product
+func (p Product) GetModifiers() []entity.Modifier {
+	return append([]entity.Modifier(nil), p.modifiers...)
+}
+
+// GetModifier returns the modifier with the given ID
+func (p Product) GetModifier(id uuid.UUID) (entity.Modifier, error) {
+	for _, m := range p.modifiers {
+		if m.ID == id {
+			return m, nil
+		}
+	}
+	return entity.Modifier{}, ErrModifierNotFound
+}
+
+// AddModifier adds an optional extra which is charged with a surcharge
+func (p *Product) AddModifier(name string, surcharge float64) (uuid.UUID, error) {
+	if name == "" {
+		return uuid.Nil, ErrMissingValues
+	}
+	if surcharge < 0 {
+		return uuid.Nil, ErrInvalidPrice
+	}
+	m := entity.Modifier{
+		ID:        uuid.New(),
+		Name:      name,
+		Surcharge: surcharge,
+	}
+	p.modifiers = append(p.GetModifiers(), m)
+	return m.ID, nil
+}
+
+// InStock reports whether the product, or any of its variants, can be ordered
+func (p Product) InStock() bool {
+	if len(p.variants) == 0 {
+		return p.quantity > 0
+	}
+	for _, v := range p.variants {
+		if v.Quantity > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// PriceOf returns the unit price of a variant with modifiers at a point in time.
+// A product with variants has to be ordered as one of them, uuid.Nil orders a product without variants
+func (p Product) PriceOf(at time.Time, variant uuid.UUID, modifiers []uuid.UUID) (float64, error) {
+	price := p.PriceAt(at)
+	if variant != uuid.Nil {
+		var err error
+		if price, err = p.VariantPriceAt(variant, at); err != nil {
+			return 0, err
+		}
+	} else if len(p.variants) > 0 {
+		return 0, ErrVariantRequired
+	}
+
+	for _, id := range modifiers {
+		m, err := p.GetModifier(id)
+		if err != nil {
+			return 0, err
+		}
+		price += m.Surcharge
+	}
+	return price, nil
+}
+
+// IsAvailable reports whether the product can be ordered, it is neither 86'd nor archived
+func (p Product) IsAvailable() bool {
+	return !p.unavailable && !p.archived
+}
+
+// MarkUnavailable 86es the product, it stays on the menu but can not be ordered
+func (p *Product) MarkUnavailable() {
+	p.unavailable = true
+}
+
+// MarkAvailable makes an 86'd product orderable again
+func (p *Product) MarkAvailable() {
+	p.unavailable = false
+}
+
+// IsArchived reports whether the product was taken off the menu
+func (p Product) IsArchived() bool {
+	return p.archived
+}
+
+// Archive takes the product off the menu, instead of deleting it
+func (p *Product) Archive() {
+	p.archived = true
+}
+
+// Restore puts an archived product back on the menu
+func (p *Product) Restore() {
+	p.archived = false
 }
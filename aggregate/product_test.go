@@ -1,8 +1,14 @@
 package aggregate_test
 
 import (
+	"errors"
+	"math"
 	"taverne/aggregate"
+	"taverne/valueobject"
 	"testing"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 func TestProduct_NewProduct(t *testing.T) {
@@ -38,3 +44,255 @@ func TestProduct_NewProduct(t *testing.T) {
 		})
 	}
 }
+
+func TestProduct_PriceOf(t *testing.T) {
+	burger, err := aggregate.NewProduct("Burger", "With onions", 9.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cheese, err := burger.AddModifier("Extra cheese", 1.2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bacon, err := burger.AddModifier("Bacon", 1.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ale, err := aggregate.NewProduct("Ale", "Dark and cloudy", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	small, err := ale.AddVariant("0.3l", 3.2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	large, err := ale.AddVariant("0.5l", 4.8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		test          string
+		product       aggregate.Product
+		variant       uuid.UUID
+		modifiers     []uuid.UUID
+		expectedPrice float64
+		expectedErr   error
+	}
+
+	testCases := []testCase{
+		{
+			test:          "Product without variants",
+			product:       burger,
+			expectedPrice: 9.5,
+		},
+		{
+			test:          "Product with modifiers",
+			product:       burger,
+			modifiers:     []uuid.UUID{cheese, bacon},
+			expectedPrice: 12.2,
+		},
+		{
+			test:        "Unknown modifier",
+			product:     burger,
+			modifiers:   []uuid.UUID{uuid.New()},
+			expectedErr: aggregate.ErrModifierNotFound,
+		},
+		{
+			test:          "Small variant",
+			product:       ale,
+			variant:       small,
+			expectedPrice: 3.2,
+		},
+		{
+			test:          "Large variant",
+			product:       ale,
+			variant:       large,
+			expectedPrice: 4.8,
+		},
+		{
+			test:        "Variant required",
+			product:     ale,
+			expectedErr: aggregate.ErrVariantRequired,
+		},
+		{
+			test:        "Unknown variant",
+			product:     ale,
+			variant:     uuid.New(),
+			expectedErr: aggregate.ErrVariantNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			price, err := tc.product.PriceOf(time.Now(), tc.variant, tc.modifiers)
+			if err != tc.expectedErr {
+				t.Fatalf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+			if math.Abs(price-tc.expectedPrice) > 0.0001 {
+				t.Errorf("Expected price %.2f, got %.2f", tc.expectedPrice, price)
+			}
+		})
+	}
+}
+
+func TestProduct_VariantStock(t *testing.T) {
+	ale, err := aggregate.NewProduct("Ale", "Dark and cloudy", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	small, err := ale.AddVariant("0.3l", 3.2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ale.InStock() {
+		t.Error("Expected a product without variant stock to be out of stock")
+	}
+
+	if err := ale.AddVariantStock(small, 2); err != nil {
+		t.Fatal(err)
+	}
+	// a copy of the product does not see later changes
+	before := ale
+	if err := ale.RemoveVariantStock(small, 2); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := before.GetVariant(small); v.Quantity != 2 {
+		t.Errorf("Expected copy to keep 2 in stock, got %d", v.Quantity)
+	}
+	if err := ale.RemoveVariantStock(small, 1); err != aggregate.ErrInsufficientStock {
+		t.Errorf("Expected error %v, got %v", aggregate.ErrInsufficientStock, err)
+	}
+}
+
+func TestProduct_ChangePrice(t *testing.T) {
+	beer, err := aggregate.NewProduct("Beer", "Healthy Beverage", 3.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lastMonth := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	thisMonth := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	nextMonth := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+
+	// scheduled out of order on purpose
+	if err := beer.ChangePrice(4.2, nextMonth); err != nil {
+		t.Fatal(err)
+	}
+	if err := beer.ChangePrice(3.9, thisMonth); err != nil {
+		t.Fatal(err)
+	}
+	if err := beer.ChangePrice(-1, thisMonth); err != aggregate.ErrInvalidPrice {
+		t.Errorf("Expected error %v, got %v", aggregate.ErrInvalidPrice, err)
+	}
+
+	type testCase struct {
+		test     string
+		at       time.Time
+		expected float64
+	}
+
+	testCases := []testCase{
+		{test: "Last month", at: lastMonth, expected: 3.5},
+		{test: "Start of this month", at: thisMonth, expected: 3.9},
+		{test: "Just before next month", at: nextMonth.Add(-time.Second), expected: 3.9},
+		{test: "Next month", at: nextMonth, expected: 4.2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			if price := beer.PriceAt(tc.at); price != tc.expected {
+				t.Errorf("Expected price %.2f, got %.2f", tc.expected, price)
+			}
+		})
+	}
+
+	if history := beer.GetPriceHistory(); len(history) != 3 {
+		t.Errorf("Expected 3 prices in history, got %v", history)
+	}
+}
+
+func TestProduct_ChangeVariantPrice(t *testing.T) {
+	ale, err := aggregate.NewProduct("Ale", "Brewed in the cellar", 3.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	small, err := ale.AddVariant("0.3l", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	large, err := ale.AddVariant("0.5l", 4.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	thisMonth := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	nextMonth := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	before := ale
+
+	if err := ale.ChangeVariantPrice(small, 3.6, nextMonth); err != nil {
+		t.Fatal(err)
+	}
+	if err := ale.ChangeVariantPrice(small, 3.3, thisMonth); err != nil {
+		t.Fatal(err)
+	}
+	if err := ale.ChangeVariantPrice(small, -1, thisMonth); err != aggregate.ErrInvalidPrice {
+		t.Errorf("Expected error %v, got %v", aggregate.ErrInvalidPrice, err)
+	}
+	if err := ale.ChangeVariantPrice(uuid.New(), 3, thisMonth); err != aggregate.ErrVariantNotFound {
+		t.Errorf("Expected error %v, got %v", aggregate.ErrVariantNotFound, err)
+	}
+
+	type testCase struct {
+		test     string
+		at       time.Time
+		variant  uuid.UUID
+		expected float64
+	}
+
+	testCases := []testCase{
+		{test: "Before the first change", at: thisMonth.Add(-time.Second), variant: small, expected: 3},
+		{test: "Start of this month", at: thisMonth, variant: small, expected: 3.3},
+		{test: "Next month", at: nextMonth, variant: small, expected: 3.6},
+		{test: "Other variant keeps its price", at: nextMonth, variant: large, expected: 4.5},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			price, err := ale.PriceOf(tc.at, tc.variant, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if price != tc.expected {
+				t.Errorf("Expected price %.2f, got %.2f", tc.expected, price)
+			}
+		})
+	}
+
+	// copies of the product do not share the price changes
+	if price, _ := before.VariantPriceAt(small, nextMonth); price != 3 {
+		t.Errorf("Expected copy to keep price 3.00, got %.2f", price)
+	}
+}
+
+func TestProduct_Allergens(t *testing.T) {
+	p, err := aggregate.NewProduct("Pesto", "Basil and pine nuts", 4.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SetAllergens("basil"); !errors.Is(err, aggregate.ErrInvalidAllergen) {
+		t.Errorf("Expected error %v, got %v", aggregate.ErrInvalidAllergen, err)
+	}
+	if err := p.SetAllergens(valueobject.AllergenNuts, valueobject.AllergenMilk); err != nil {
+		t.Fatal(err)
+	}
+
+	found := p.Contains([]valueobject.Allergen{valueobject.AllergenGluten, valueobject.AllergenMilk})
+	if len(found) != 1 || found[0] != valueobject.AllergenMilk {
+		t.Errorf("Expected milk, got %v", found)
+	}
+	if found := p.Contains([]valueobject.Allergen{valueobject.AllergenFish}); len(found) != 0 {
+		t.Errorf("Expected no allergens, got %v", found)
+	}
+}
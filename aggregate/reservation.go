@@ -0,0 +1,184 @@
+package aggregate
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReservationStatus is the step of the lifecycle a reservation is in
+type ReservationStatus string
+
+const (
+	ReservationBooked    ReservationStatus = "booked"
+	ReservationSeated    ReservationStatus = "seated"
+	ReservationCancelled ReservationStatus = "cancelled"
+	ReservationNoShow    ReservationStatus = "no-show"
+)
+
+var (
+	// ErrInvalidPartySize is returned when a reservation is made for less than one guest
+	ErrInvalidPartySize = errors.New("a reservation has to be for at least one guest")
+	// ErrInvalidSlot is returned when the time slot of a reservation does not end after it starts
+	ErrInvalidSlot = errors.New("the time slot has to end after it starts")
+	// ErrReservationNotBooked is returned when a cancelled or missed reservation is changed
+	ErrReservationNotBooked = errors.New("the reservation is not booked")
+	// ErrReservationNotStarted is returned when a reservation is marked as no-show before its slot started
+	ErrReservationNotStarted = errors.New("the time slot of the reservation has not started yet")
+)
+
+// Reservation is a table booked by a customer for a party in a time slot
+type Reservation struct {
+	// id is the root identifier of the reservation
+	id        uuid.UUID
+	customer  uuid.UUID
+	partySize int
+	// table is the table held for the party, uuid.Nil until one is assigned
+	table uuid.UUID
+	// the slot starts at start and ends right before end
+	start     time.Time
+	end       time.Time
+	status    ReservationStatus
+	createdAt time.Time
+}
+
+// NewReservation is a factory to create a new booked Reservation
+// will return error if the party is empty or the slot does not end after it starts
+func NewReservation(customer uuid.UUID, partySize int, start, end time.Time) (Reservation, error) {
+	if partySize < 1 {
+		return Reservation{}, ErrInvalidPartySize
+	}
+	if !end.After(start) {
+		return Reservation{}, ErrInvalidSlot
+	}
+	return Reservation{
+		id:        uuid.New(),
+		customer:  customer,
+		partySize: partySize,
+		start:     start,
+		end:       end,
+		status:    ReservationBooked,
+		createdAt: time.Now(),
+	}, nil
+}
+
+// RestoreReservation rebuilds a reservation which was stored before
+func RestoreReservation(id, customer, table uuid.UUID, partySize int, start, end time.Time, status ReservationStatus, createdAt time.Time) Reservation {
+	return Reservation{
+		id:        id,
+		customer:  customer,
+		partySize: partySize,
+		table:     table,
+		start:     start,
+		end:       end,
+		status:    status,
+		createdAt: createdAt,
+	}
+}
+
+// GetID returns the reservations root ID
+func (r Reservation) GetID() uuid.UUID {
+	return r.id
+}
+
+// GetCustomerID returns the customer who booked the reservation
+func (r Reservation) GetCustomerID() uuid.UUID {
+	return r.customer
+}
+
+// GetPartySize returns for how many guests the reservation is
+func (r Reservation) GetPartySize() int {
+	return r.partySize
+}
+
+// GetTableID returns the table held for the party
+func (r Reservation) GetTableID() uuid.UUID {
+	return r.table
+}
+
+// GetStart returns when the time slot starts
+func (r Reservation) GetStart() time.Time {
+	return r.start
+}
+
+// GetEnd returns when the time slot ends
+func (r Reservation) GetEnd() time.Time {
+	return r.end
+}
+
+// GetStatus returns the step of the lifecycle the reservation is in
+func (r Reservation) GetStatus() ReservationStatus {
+	return r.status
+}
+
+// GetCreatedAt returns when the reservation was booked
+func (r Reservation) GetCreatedAt() time.Time {
+	return r.createdAt
+}
+
+// HoldsTable reports if the table of the reservation is taken for its slot
+func (r Reservation) HoldsTable() bool {
+	return r.status == ReservationBooked || r.status == ReservationSeated
+}
+
+// Overlaps reports if the time slot of the reservation overlaps the slot from start to end
+func (r Reservation) Overlaps(start, end time.Time) bool {
+	return r.start.Before(end) && start.Before(r.end)
+}
+
+// Change moves the reservation to another slot or party size
+func (r *Reservation) Change(partySize int, start, end time.Time) error {
+	if r.status != ReservationBooked {
+		return ErrReservationNotBooked
+	}
+	if partySize < 1 {
+		return ErrInvalidPartySize
+	}
+	if !end.After(start) {
+		return ErrInvalidSlot
+	}
+	r.partySize = partySize
+	r.start = start
+	r.end = end
+	return nil
+}
+
+// AssignTable holds a table for the party
+func (r *Reservation) AssignTable(table uuid.UUID) error {
+	if r.status != ReservationBooked {
+		return ErrReservationNotBooked
+	}
+	r.table = table
+	return nil
+}
+
+// Seat records that the party arrived and was seated at the table of the reservation
+func (r *Reservation) Seat() error {
+	if r.status != ReservationBooked {
+		return ErrReservationNotBooked
+	}
+	r.status = ReservationSeated
+	return nil
+}
+
+// Cancel cancels a booked reservation, which releases its table
+func (r *Reservation) Cancel() error {
+	if r.status != ReservationBooked {
+		return ErrReservationNotBooked
+	}
+	r.status = ReservationCancelled
+	return nil
+}
+
+// MarkNoShow records that the party did not turn up, which is only known once the slot started
+func (r *Reservation) MarkNoShow(at time.Time) error {
+	if r.status != ReservationBooked {
+		return ErrReservationNotBooked
+	}
+	if at.Before(r.start) {
+		return ErrReservationNotStarted
+	}
+	r.status = ReservationNoShow
+	return nil
+}
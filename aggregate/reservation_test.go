@@ -0,0 +1,72 @@
+package aggregate_test
+
+import (
+	"errors"
+	"taverne/aggregate"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestReservation_NewReservation(t *testing.T) {
+	start := time.Date(2024, time.June, 7, 19, 0, 0, 0, time.UTC)
+
+	type testCase struct {
+		test        string
+		partySize   int
+		end         time.Time
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			test:        "Valid reservation",
+			partySize:   4,
+			end:         start.Add(2 * time.Hour),
+			expectedErr: nil,
+		},
+		{
+			test:        "Empty party",
+			partySize:   0,
+			end:         start.Add(2 * time.Hour),
+			expectedErr: aggregate.ErrInvalidPartySize,
+		},
+		{
+			test:        "Slot ends when it starts",
+			partySize:   2,
+			end:         start,
+			expectedErr: aggregate.ErrInvalidSlot,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			_, err := aggregate.NewReservation(uuid.New(), tc.partySize, start, tc.end)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestReservation_MarkNoShow(t *testing.T) {
+	start := time.Date(2024, time.June, 7, 19, 0, 0, 0, time.UTC)
+	r, err := aggregate.NewReservation(uuid.New(), 2, start, start.Add(2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.MarkNoShow(start.Add(-time.Minute)); !errors.Is(err, aggregate.ErrReservationNotStarted) {
+		t.Errorf("Expected error %v, got %v", aggregate.ErrReservationNotStarted, err)
+	}
+	if err := r.MarkNoShow(start.Add(15 * time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if r.HoldsTable() {
+		t.Errorf("Expected a no-show to release its table")
+	}
+	if err := r.Cancel(); !errors.Is(err, aggregate.ErrReservationNotBooked) {
+		t.Errorf("Expected error %v, got %v", aggregate.ErrReservationNotBooked, err)
+	}
+}
@@ -0,0 +1,162 @@
+package aggregate
+
+import (
+	"errors"
+	"taverne/valueobject"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrTabClosed is returned when a closed tab is changed
+	ErrTabClosed = errors.New("the tab is already closed")
+	// ErrTabOtherCustomer is returned when an order of another customer is put on a tab
+	ErrTabOtherCustomer = errors.New("the order belongs to another customer than the tab")
+	// ErrTabSettled is returned when a payer settles their share of a tab twice
+	ErrTabSettled = errors.New("the payer has already settled their share of the tab")
+	// ErrTabSettling is returned when an order is put on a tab which payers started to settle
+	ErrTabSettling = errors.New("payers already settled part of the tab")
+	// ErrTabOrderNotFound is returned when an order is taken off a tab it is not on
+	ErrTabOrderNotFound = errors.New("the order is not on the tab")
+)
+
+// Tab is a running bill of a customer
+// Orders accumulate on an open tab and are billed once when the tab is closed
+type Tab struct {
+	// id is the root identifier of the tab
+	id       uuid.UUID
+	customer uuid.UUID
+	orders   []uuid.UUID
+	// total is the accumulated amount of the orders in cents
+	total    int
+	openedAt time.Time
+	closedAt time.Time
+	// settlements are the payments of the payers who settled their share while closing the tab
+	settlements []valueobject.Transaction
+}
+
+// NewTab is a factory to open a new Tab for a customer
+func NewTab(customer uuid.UUID) Tab {
+	return Tab{
+		id:       uuid.New(),
+		customer: customer,
+		orders:   make([]uuid.UUID, 0),
+		openedAt: time.Now(),
+	}
+}
+
+// GetID returns the tabs root ID
+func (t Tab) GetID() uuid.UUID {
+	return t.id
+}
+
+// GetCustomerID returns the ID of the customer the tab is running for
+func (t Tab) GetCustomerID() uuid.UUID {
+	return t.customer
+}
+
+// GetOrders returns the IDs of all orders put on the tab
+func (t Tab) GetOrders() []uuid.UUID {
+	return append([]uuid.UUID(nil), t.orders...)
+}
+
+// Total returns the accumulated amount of all orders on the tab
+func (t Tab) Total() float64 {
+	return float64(t.total) / 100
+}
+
+// IsOpen reports whether orders can still be put on the tab
+func (t Tab) IsOpen() bool {
+	return t.closedAt.IsZero()
+}
+
+// GetOpenedAt returns when the tab was opened
+func (t Tab) GetOpenedAt() time.Time {
+	return t.openedAt
+}
+
+// GetClosedAt returns when the tab was closed, the zero time while it is open
+func (t Tab) GetClosedAt() time.Time {
+	return t.closedAt
+}
+
+// GetSettlements returns the payments of the payers who settled their share of the tab
+func (t Tab) GetSettlements() []valueobject.Transaction {
+	return append([]valueobject.Transaction(nil), t.settlements...)
+}
+
+// Settled returns the cents a payer settled and whether the payer settled at all
+func (t Tab) Settled(payer uuid.UUID) (int, bool) {
+	for _, s := range t.settlements {
+		if s.GetFrom() == payer {
+			return s.GetAmount(), true
+		}
+	}
+	return 0, false
+}
+
+// AddOrder puts an order of the tabs customer on the tab
+func (t *Tab) AddOrder(o Order) error {
+	if !t.IsOpen() {
+		return ErrTabClosed
+	}
+	if o.GetCustomerID() != t.customer {
+		return ErrTabOtherCustomer
+	}
+	if len(t.settlements) > 0 {
+		return ErrTabSettling
+	}
+	t.orders = append(t.orders, o.GetID())
+	t.total += toCents(o.Total())
+	return nil
+}
+
+// HasOrder reports whether the order is on the tab
+func (t Tab) HasOrder(orderID uuid.UUID) bool {
+	for _, id := range t.orders {
+		if id == orderID {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveOrder takes an order off an open tab which no payer started to settle, such as when it is cancelled
+func (t *Tab) RemoveOrder(o Order) error {
+	if !t.IsOpen() {
+		return ErrTabClosed
+	}
+	if len(t.settlements) > 0 {
+		return ErrTabSettling
+	}
+	for i, id := range t.orders {
+		if id == o.GetID() {
+			t.orders = append(t.GetOrders()[:i], t.orders[i+1:]...)
+			t.total -= toCents(o.Total())
+			return nil
+		}
+	}
+	return ErrTabOrderNotFound
+}
+
+// Close closes the tab, no more orders can be added afterwards
+func (t *Tab) Close() error {
+	if !t.IsOpen() {
+		return ErrTabClosed
+	}
+	t.closedAt = time.Now()
+	return nil
+}
+
+// Settle records the payment of a payers share, the tab stays open until it is closed
+func (t *Tab) Settle(payment valueobject.Transaction) error {
+	if !t.IsOpen() {
+		return ErrTabClosed
+	}
+	if _, ok := t.Settled(payment.GetFrom()); ok {
+		return ErrTabSettled
+	}
+	t.settlements = append(t.GetSettlements(), payment)
+	return nil
+}
@@ -0,0 +1,83 @@
+package aggregate_test
+
+import (
+	"taverne/aggregate"
+	"taverne/valueobject"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestTab_RemoveOrder(t *testing.T) {
+	customer := uuid.New()
+	newOrder := func(t *testing.T) aggregate.Order {
+		o, err := aggregate.NewOrder(customer, []valueobject.OrderItem{
+			{ProductID: uuid.New(), Name: "Beer", Quantity: 1, UnitPrice: 0.1},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return o
+	}
+
+	type testCase struct {
+		test          string
+		settle        bool
+		onTab         bool
+		expectedErr   error
+		expectedTotal float64
+	}
+
+	testCases := []testCase{
+		{
+			test:          "Remove order",
+			onTab:         true,
+			expectedTotal: 0.2,
+		},
+		{
+			test:          "Order not on the tab",
+			expectedErr:   aggregate.ErrTabOrderNotFound,
+			expectedTotal: 0.3,
+		},
+		{
+			test:          "Tab being settled",
+			settle:        true,
+			onTab:         true,
+			expectedErr:   aggregate.ErrTabSettling,
+			expectedTotal: 0.3,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			tb := aggregate.NewTab(customer)
+			var orders []aggregate.Order
+			for i := 0; i < 3; i++ {
+				o := newOrder(t)
+				if err := tb.AddOrder(o); err != nil {
+					t.Fatal(err)
+				}
+				orders = append(orders, o)
+			}
+			if tc.settle {
+				if err := tb.Settle(valueobject.NewTransaction(valueobject.TransactionPayment, 10, customer, tb.GetID())); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			removed := newOrder(t)
+			if tc.onTab {
+				removed = orders[1]
+			}
+			if err := tb.RemoveOrder(removed); err != tc.expectedErr {
+				t.Fatalf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+			if tb.Total() != tc.expectedTotal {
+				t.Errorf("Expected total %.2f, got %.2f", tc.expectedTotal, tb.Total())
+			}
+			if tb.HasOrder(removed.GetID()) == tc.onTab && tc.expectedErr == nil {
+				t.Errorf("Expected the order taken off the tab, got %v", tb.GetOrders())
+			}
+		})
+	}
+}
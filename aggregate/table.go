@@ -0,0 +1,230 @@
+package aggregate
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TableStatus tells whether guests can be seated at a table
+type TableStatus string
+
+const (
+	TableFree     TableStatus = "free"
+	TableOccupied TableStatus = "occupied"
+	TableReserved TableStatus = "reserved"
+)
+
+var (
+	// ErrInvalidTable is returned when a table is created without a positive number and capacity
+	ErrInvalidTable = errors.New("a table has to have a positive number and capacity")
+	// ErrTableOccupied is returned when guests are seated at or a reservation is made for an occupied table
+	ErrTableOccupied = errors.New("the table is occupied")
+	// ErrTableReserved is returned when a reserved table is reserved again
+	ErrTableReserved = errors.New("the table is reserved")
+	// ErrTableNotOccupied is returned when a table without guests is cleared or its guests are moved
+	ErrTableNotOccupied = errors.New("no guests are seated at the table")
+	// ErrTableCapacity is returned when a party is larger than the table
+	ErrTableCapacity = errors.New("the party is larger than the table")
+	// ErrEmptyParty is returned when a party without any guest is seated
+	ErrEmptyParty = errors.New("a party has to have at least one guest")
+	// ErrGuestNotSeated is returned when a guest who is not seated at a table leaves it
+	ErrGuestNotSeated = errors.New("the guest is not seated at the table")
+	// ErrDuplicateGuest is returned when a guest is seated twice at the same table
+	ErrDuplicateGuest = errors.New("a guest can only be seated once")
+)
+
+// Table is a table of the tavern where a party of customers is seated
+type Table struct {
+	// id is the root identifier of the table
+	id       uuid.UUID
+	number   int
+	capacity int
+	status   TableStatus
+	// guests are the customers seated at the table since seatedAt
+	guests   []uuid.UUID
+	seatedAt time.Time
+	// orders are the orders placed by the guests since they were seated
+	orders []uuid.UUID
+}
+
+// NewTable is a factory to create a new free Table
+// will return error if the number or the capacity is below one
+func NewTable(number, capacity int) (Table, error) {
+	if number < 1 || capacity < 1 {
+		return Table{}, ErrInvalidTable
+	}
+	return Table{
+		id:       uuid.New(),
+		number:   number,
+		capacity: capacity,
+		status:   TableFree,
+	}, nil
+}
+
+// GetID returns the tables root ID
+func (t Table) GetID() uuid.UUID {
+	return t.id
+}
+
+// GetNumber returns the number of the table as it is shown to the staff
+func (t Table) GetNumber() int {
+	return t.number
+}
+
+// GetCapacity returns how many guests can be seated at the table
+func (t Table) GetCapacity() int {
+	return t.capacity
+}
+
+// GetStatus returns whether guests can be seated at the table
+func (t Table) GetStatus() TableStatus {
+	return t.status
+}
+
+// GetGuests returns the customers seated at the table
+func (t Table) GetGuests() []uuid.UUID {
+	return append([]uuid.UUID(nil), t.guests...)
+}
+
+// GetSeatedAt returns when the guests were seated, the zero time for a table without guests
+func (t Table) GetSeatedAt() time.Time {
+	return t.seatedAt
+}
+
+// GetOrders returns the orders placed by the guests since they were seated
+func (t Table) GetOrders() []uuid.UUID {
+	return append([]uuid.UUID(nil), t.orders...)
+}
+
+// AddOrder serves an order at the table
+func (t *Table) AddOrder(order uuid.UUID) error {
+	if t.status != TableOccupied {
+		return ErrTableNotOccupied
+	}
+	t.orders = append(append([]uuid.UUID(nil), t.orders...), order)
+	return nil
+}
+
+// RemoveOrder takes an order off the table, such as when the guest who placed it moves to another table
+func (t *Table) RemoveOrder(order uuid.UUID) {
+	orders := make([]uuid.UUID, 0, len(t.orders))
+	for _, o := range t.orders {
+		if o != order {
+			orders = append(orders, o)
+		}
+	}
+	t.orders = orders
+}
+
+// IsSeated reports if the customer is seated at the table
+func (t Table) IsSeated(customer uuid.UUID) bool {
+	return containsGuest(t.guests, customer)
+}
+
+// Seat seats a party at a free or reserved table
+// will return error if the table is occupied or the party does not fit
+func (t *Table) Seat(guests []uuid.UUID, at time.Time) error {
+	if t.status == TableOccupied {
+		return ErrTableOccupied
+	}
+	if len(guests) == 0 {
+		return ErrEmptyParty
+	}
+	if len(guests) > t.capacity {
+		return ErrTableCapacity
+	}
+	if err := uniqueGuests(nil, guests); err != nil {
+		return err
+	}
+	t.status = TableOccupied
+	t.guests = append([]uuid.UUID(nil), guests...)
+	t.seatedAt = at
+	return nil
+}
+
+// Join seats more guests at an occupied table, such as guests moving over from another table
+func (t *Table) Join(guests []uuid.UUID) error {
+	if t.status != TableOccupied {
+		return ErrTableNotOccupied
+	}
+	if len(guests) == 0 {
+		return ErrEmptyParty
+	}
+	if len(t.guests)+len(guests) > t.capacity {
+		return ErrTableCapacity
+	}
+	if err := uniqueGuests(t.guests, guests); err != nil {
+		return err
+	}
+	t.guests = append(append([]uuid.UUID(nil), t.guests...), guests...)
+	return nil
+}
+
+// Leave removes guests from the table, the table is free once the last guest left
+func (t *Table) Leave(guests []uuid.UUID) error {
+	if t.status != TableOccupied {
+		return ErrTableNotOccupied
+	}
+	rest := make([]uuid.UUID, 0, len(t.guests))
+	left := 0
+	for _, g := range t.guests {
+		if containsGuest(guests, g) {
+			left++
+			continue
+		}
+		rest = append(rest, g)
+	}
+	if left != len(guests) {
+		return ErrGuestNotSeated
+	}
+	if len(rest) == 0 {
+		return t.Clear()
+	}
+	t.guests = rest
+	return nil
+}
+
+// uniqueGuests makes sure no guest of the party is listed twice or is seated already
+func uniqueGuests(seated, guests []uuid.UUID) error {
+	for i, g := range guests {
+		if containsGuest(seated, g) || containsGuest(guests[:i], g) {
+			return ErrDuplicateGuest
+		}
+	}
+	return nil
+}
+
+func containsGuest(guests []uuid.UUID, guest uuid.UUID) bool {
+	for _, g := range guests {
+		if g == guest {
+			return true
+		}
+	}
+	return false
+}
+
+// Reserve holds a free table for a party which is about to arrive
+func (t *Table) Reserve() error {
+	switch t.status {
+	case TableOccupied:
+		return ErrTableOccupied
+	case TableReserved:
+		return ErrTableReserved
+	}
+	t.status = TableReserved
+	return nil
+}
+
+// Clear frees the table once the guests left or a reservation is released
+func (t *Table) Clear() error {
+	if t.status == TableFree {
+		return ErrTableNotOccupied
+	}
+	t.status = TableFree
+	t.guests = nil
+	t.seatedAt = time.Time{}
+	t.orders = nil
+	return nil
+}
@@ -0,0 +1,194 @@
+package aggregate_test
+
+import (
+	"errors"
+	"taverne/aggregate"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestTable_NewTable(t *testing.T) {
+	type testCase struct {
+		test        string
+		number      int
+		capacity    int
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			test:        "Valid table",
+			number:      1,
+			capacity:    4,
+			expectedErr: nil,
+		},
+		{
+			test:        "No number",
+			number:      0,
+			capacity:    4,
+			expectedErr: aggregate.ErrInvalidTable,
+		},
+		{
+			test:        "No seats",
+			number:      2,
+			capacity:    0,
+			expectedErr: aggregate.ErrInvalidTable,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			tb, err := aggregate.NewTable(tc.number, tc.capacity)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+			if err == nil && tb.GetStatus() != aggregate.TableFree {
+				t.Errorf("Expected a new table to be free, got %s", tb.GetStatus())
+			}
+		})
+	}
+}
+
+func TestTable_Seat(t *testing.T) {
+	party := func(n int) []uuid.UUID {
+		guests := make([]uuid.UUID, n)
+		for i := range guests {
+			guests[i] = uuid.New()
+		}
+		return guests
+	}
+	repeated := uuid.New()
+
+	type testCase struct {
+		test        string
+		prepare     func(tb *aggregate.Table) error
+		guests      []uuid.UUID
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			test:        "Seat at a free table",
+			guests:      party(2),
+			expectedErr: nil,
+		},
+		{
+			test:        "Seat at a reserved table",
+			prepare:     (*aggregate.Table).Reserve,
+			guests:      party(2),
+			expectedErr: nil,
+		},
+		{
+			test: "Seat at an occupied table",
+			prepare: func(tb *aggregate.Table) error {
+				return tb.Seat(party(1), time.Now())
+			},
+			guests:      party(2),
+			expectedErr: aggregate.ErrTableOccupied,
+		},
+		{
+			test:        "Party too large",
+			guests:      party(3),
+			expectedErr: aggregate.ErrTableCapacity,
+		},
+		{
+			test:        "Empty party",
+			guests:      nil,
+			expectedErr: aggregate.ErrEmptyParty,
+		},
+		{
+			test:        "Same guest twice",
+			guests:      []uuid.UUID{repeated, repeated},
+			expectedErr: aggregate.ErrDuplicateGuest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			tb, err := aggregate.NewTable(1, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.prepare != nil {
+				if err := tc.prepare(&tb); err != nil {
+					t.Fatal(err)
+				}
+			}
+			err = tb.Seat(tc.guests, time.Now())
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+			if err == nil && (tb.GetStatus() != aggregate.TableOccupied || !tb.IsSeated(tc.guests[0])) {
+				t.Errorf("Expected the party to be seated, got %s %v", tb.GetStatus(), tb.GetGuests())
+			}
+		})
+	}
+}
+
+func TestTable_Join(t *testing.T) {
+	seated, other := uuid.New(), uuid.New()
+
+	type testCase struct {
+		test        string
+		guests      []uuid.UUID
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{test: "Join the party", guests: []uuid.UUID{other}},
+		{test: "Guest seated already", guests: []uuid.UUID{seated}, expectedErr: aggregate.ErrDuplicateGuest},
+		{test: "Same guest twice", guests: []uuid.UUID{other, other}, expectedErr: aggregate.ErrDuplicateGuest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			tb, err := aggregate.NewTable(1, 4)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tb.Seat([]uuid.UUID{seated}, time.Now()); err != nil {
+				t.Fatal(err)
+			}
+			if err := tb.Join(tc.guests); err != tc.expectedErr {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+			if tc.expectedErr != nil && len(tb.GetGuests()) != 1 {
+				t.Errorf("Expected the party to stay as it was, got %v", tb.GetGuests())
+			}
+		})
+	}
+}
+
+func TestTable_Leave(t *testing.T) {
+	tb, err := aggregate.NewTable(1, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, second := uuid.New(), uuid.New()
+	if err := tb.Seat([]uuid.UUID{first, second}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err := tb.AddOrder(uuid.New()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tb.Leave([]uuid.UUID{uuid.New()}); !errors.Is(err, aggregate.ErrGuestNotSeated) {
+		t.Errorf("Expected error %v, got %v", aggregate.ErrGuestNotSeated, err)
+	}
+	if err := tb.Leave([]uuid.UUID{first}); err != nil {
+		t.Fatal(err)
+	}
+	if tb.GetStatus() != aggregate.TableOccupied || tb.IsSeated(first) || !tb.IsSeated(second) {
+		t.Errorf("Expected only the second guest to stay, got %v", tb.GetGuests())
+	}
+
+	// the last guest leaving frees the table
+	if err := tb.Leave([]uuid.UUID{second}); err != nil {
+		t.Fatal(err)
+	}
+	if tb.GetStatus() != aggregate.TableFree || len(tb.GetOrders()) != 0 || !tb.GetSeatedAt().IsZero() {
+		t.Errorf("Expected a free table, got %s with orders %v", tb.GetStatus(), tb.GetOrders())
+	}
+}
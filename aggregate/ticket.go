@@ -0,0 +1,135 @@
+package aggregate
+
+import (
+	"errors"
+	"taverne/valueobject"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Station is where the items of a ticket are prepared
+type Station string
+
+const (
+	StationKitchen Station = "kitchen"
+	StationBar     Station = "bar"
+)
+
+// TicketStatus is the step of the preparation a ticket is in
+type TicketStatus string
+
+const (
+	TicketNew        TicketStatus = "new"
+	TicketInProgress TicketStatus = "in-progress"
+	TicketReady      TicketStatus = "ready"
+	// TicketCancelled is a ticket of a cancelled order, it is taken off the screens
+	TicketCancelled TicketStatus = "cancelled"
+)
+
+var (
+	// ErrEmptyTicket is returned when a ticket is created without items
+	ErrEmptyTicket = errors.New("a ticket has to contain at least one item")
+	// ErrTicketDone is returned when a ready or cancelled ticket is bumped or cancelled
+	ErrTicketDone = errors.New("the ticket is ready or cancelled already")
+)
+
+// Ticket is the part of an order one station prepares
+type Ticket struct {
+	// id is the root identifier of the ticket
+	id      uuid.UUID
+	order   uuid.UUID
+	table   uuid.UUID
+	station Station
+	items   []valueobject.OrderItem
+	status  TicketStatus
+	// createdAt is when the order reached the station, updatedAt when the ticket was last bumped
+	createdAt time.Time
+	updatedAt time.Time
+}
+
+// NewTicket is a factory to create a new ticket for the items of an order one station prepares
+func NewTicket(order, table uuid.UUID, station Station, items []valueobject.OrderItem, at time.Time) (Ticket, error) {
+	if len(items) == 0 {
+		return Ticket{}, ErrEmptyTicket
+	}
+	return Ticket{
+		id:        uuid.New(),
+		order:     order,
+		table:     table,
+		station:   station,
+		items:     append([]valueobject.OrderItem(nil), items...),
+		status:    TicketNew,
+		createdAt: at,
+		updatedAt: at,
+	}, nil
+}
+
+// GetID returns the tickets root ID
+func (t Ticket) GetID() uuid.UUID {
+	return t.id
+}
+
+// GetOrderID returns the order the ticket belongs to
+func (t Ticket) GetOrderID() uuid.UUID {
+	return t.order
+}
+
+// GetTableID returns the table the order is served at, uuid.Nil if it is not served at a table
+func (t Ticket) GetTableID() uuid.UUID {
+	return t.table
+}
+
+// GetStation returns where the ticket is prepared
+func (t Ticket) GetStation() Station {
+	return t.station
+}
+
+// GetItems returns the items to prepare
+func (t Ticket) GetItems() []valueobject.OrderItem {
+	return append([]valueobject.OrderItem(nil), t.items...)
+}
+
+// GetStatus returns the step of the preparation the ticket is in
+func (t Ticket) GetStatus() TicketStatus {
+	return t.status
+}
+
+// GetCreatedAt returns when the ticket reached the station
+func (t Ticket) GetCreatedAt() time.Time {
+	return t.createdAt
+}
+
+// GetUpdatedAt returns when the ticket was last bumped
+func (t Ticket) GetUpdatedAt() time.Time {
+	return t.updatedAt
+}
+
+// IsOpen reports if the ticket still has to be prepared
+func (t Ticket) IsOpen() bool {
+	return t.status == TicketNew || t.status == TicketInProgress
+}
+
+// Bump moves the ticket one step on, a new ticket is started and a started one is ready
+func (t *Ticket) Bump(at time.Time) error {
+	switch t.status {
+	case TicketNew:
+		t.status = TicketInProgress
+	case TicketInProgress:
+		t.status = TicketReady
+	default:
+		return ErrTicketDone
+	}
+	t.updatedAt = at
+	return nil
+}
+
+// Cancel takes the ticket of a cancelled order off the station
+func (t *Ticket) Cancel(at time.Time) error {
+	if !t.IsOpen() {
+		return ErrTicketDone
+	}
+	t.status = TicketCancelled
+	t.updatedAt = at
+	return nil
+}
@@ -0,0 +1,66 @@
+package aggregate_test
+
+import (
+	"errors"
+	"taverne/aggregate"
+	"taverne/valueobject"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestTicket_Bump(t *testing.T) {
+	now := time.Date(2024, time.June, 7, 19, 0, 0, 0, time.UTC)
+	items := []valueobject.OrderItem{{ProductID: uuid.New(), Name: "Stew", Quantity: 2}}
+
+	if _, err := aggregate.NewTicket(uuid.New(), uuid.Nil, aggregate.StationKitchen, nil, now); !errors.Is(err, aggregate.ErrEmptyTicket) {
+		t.Errorf("Expected error %v, got %v", aggregate.ErrEmptyTicket, err)
+	}
+	ticket, err := aggregate.NewTicket(uuid.New(), uuid.Nil, aggregate.StationKitchen, items, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		test           string
+		expectedStatus aggregate.TicketStatus
+		expectedErr    error
+	}
+
+	testCases := []testCase{
+		{
+			test:           "Start a new ticket",
+			expectedStatus: aggregate.TicketInProgress,
+		},
+		{
+			test:           "Finish a started ticket",
+			expectedStatus: aggregate.TicketReady,
+		},
+		{
+			test:           "Bump a ready ticket",
+			expectedStatus: aggregate.TicketReady,
+			expectedErr:    aggregate.ErrTicketDone,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			at := now.Add(time.Duration(i+1) * time.Minute)
+			err := ticket.Bump(at)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+			if ticket.GetStatus() != tc.expectedStatus {
+				t.Errorf("Expected status %s, got %s", tc.expectedStatus, ticket.GetStatus())
+			}
+			if err == nil && !ticket.GetUpdatedAt().Equal(at) {
+				t.Errorf("Expected the ticket to be updated at %v, got %v", at, ticket.GetUpdatedAt())
+			}
+		})
+	}
+
+	if err := ticket.Cancel(now); !errors.Is(err, aggregate.ErrTicketDone) {
+		t.Errorf("Expected error %v, got %v", aggregate.ErrTicketDone, err)
+	}
+}
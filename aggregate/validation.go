@@ -0,0 +1,50 @@
+package aggregate
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FieldError is a validation error of a single field of an aggregate
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Field, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationErrors collects the errors of every invalid field, so all of them can be reported at once
+type ValidationErrors []*FieldError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, fe := range e {
+		msgs = append(msgs, fe.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap allows errors.Is and errors.As to match the error of any field
+func (e ValidationErrors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, fe := range e {
+		errs = append(errs, fe)
+	}
+	return errs
+}
+
+// Field returns the error of the given field, nil if the field is valid
+func (e ValidationErrors) Field(field string) error {
+	for _, fe := range e {
+		if fe.Field == field {
+			return fe.Err
+		}
+	}
+	return nil
+}
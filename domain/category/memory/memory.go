@@ -0,0 +1,82 @@
+// Package memory is a in memory implementation of the CategoryRepository interface
+package memory
+
+import (
+	"sync"
+	"taverne/aggregate"
+	"taverne/domain/category"
+
+	"github.com/google/uuid"
+)
+
+type MemoryCategoryRepository struct {
+	categories map[uuid.UUID]aggregate.Category
+	sync.Mutex
+}
+
+// New is a factory function to generate a new repository of categories
+func New() *MemoryCategoryRepository {
+	return &MemoryCategoryRepository{
+		categories: make(map[uuid.UUID]aggregate.Category),
+	}
+}
+
+// GetAll returns all categories in the order they appear on the menu
+func (mcr *MemoryCategoryRepository) GetAll() ([]aggregate.Category, error) {
+	mcr.Lock()
+	defer mcr.Unlock()
+
+	categories := make([]aggregate.Category, 0, len(mcr.categories))
+	for _, c := range mcr.categories {
+		categories = append(categories, c)
+	}
+	category.SortByPosition(categories)
+	return categories, nil
+}
+
+// Get finds a category by ID
+func (mcr *MemoryCategoryRepository) Get(id uuid.UUID) (aggregate.Category, error) {
+	mcr.Lock()
+	defer mcr.Unlock()
+
+	if c, ok := mcr.categories[id]; ok {
+		return c, nil
+	}
+	return aggregate.Category{}, category.ErrCategoryNotFound
+}
+
+// Add will add a new category to the repository
+func (mcr *MemoryCategoryRepository) Add(c aggregate.Category) error {
+	mcr.Lock()
+	defer mcr.Unlock()
+
+	if _, ok := mcr.categories[c.GetID()]; ok {
+		return category.ErrCategoryAlreadyExist
+	}
+	mcr.categories[c.GetID()] = c
+	return nil
+}
+
+// Update will replace an existing category
+func (mcr *MemoryCategoryRepository) Update(c aggregate.Category) error {
+	mcr.Lock()
+	defer mcr.Unlock()
+
+	if _, ok := mcr.categories[c.GetID()]; !ok {
+		return category.ErrCategoryNotFound
+	}
+	mcr.categories[c.GetID()] = c
+	return nil
+}
+
+// Delete removes a category from the repository
+func (mcr *MemoryCategoryRepository) Delete(id uuid.UUID) error {
+	mcr.Lock()
+	defer mcr.Unlock()
+
+	if _, ok := mcr.categories[id]; !ok {
+		return category.ErrCategoryNotFound
+	}
+	delete(mcr.categories, id)
+	return nil
+}
@@ -0,0 +1,80 @@
+package memory
+
+import (
+	"taverne/aggregate"
+	"taverne/domain/category"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestMemoryCategoryRepository_GetAll(t *testing.T) {
+	repo := New()
+	for _, c := range []struct {
+		name     string
+		position int
+	}{
+		{"Drinks", 3},
+		{"Mains", 2},
+		{"Desserts", 3},
+		{"Starters", 1},
+	} {
+		cat, err := aggregate.NewCategory(c.name, c.position)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.Add(cat); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	categories, err := repo.GetAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"Starters", "Mains", "Desserts", "Drinks"}
+	for i, c := range categories {
+		if c.GetName() != expected[i] {
+			t.Errorf("Expected %s at position %d, got %s", expected[i], i, c.GetName())
+		}
+	}
+}
+
+func TestMemoryCategoryRepository_Delete(t *testing.T) {
+	repo := New()
+	existing, err := aggregate.NewCategory("Drinks", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Add(existing); err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		name        string
+		id          uuid.UUID
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			name:        "Delete existing category",
+			id:          existing.GetID(),
+			expectedErr: nil,
+		},
+		{
+			name:        "Delete non-existing category",
+			id:          uuid.New(),
+			expectedErr: category.ErrCategoryNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := repo.Delete(tc.id)
+			if err != tc.expectedErr {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
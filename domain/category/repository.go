@@ -0,0 +1,37 @@
+// Package category holds the repository and the implementations for a CategoryRepository
+package category
+
+import (
+	"errors"
+	"sort"
+	"taverne/aggregate"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrCategoryNotFound is returned when a category is not found
+	ErrCategoryNotFound = errors.New("the category was not found")
+	// ErrCategoryAlreadyExist is returned when trying to add a category that already exists
+	ErrCategoryAlreadyExist = errors.New("the category already exists")
+)
+
+// CategoryRepository is the repository interface to fulfill to persist the category aggregate
+type CategoryRepository interface {
+	// GetAll returns all categories in the order they appear on the menu
+	GetAll() ([]aggregate.Category, error)
+	Get(id uuid.UUID) (aggregate.Category, error)
+	Add(category aggregate.Category) error
+	Update(category aggregate.Category) error
+	Delete(id uuid.UUID) error
+}
+
+// SortByPosition sorts categories in the order they appear on the menu, by position and then by name
+func SortByPosition(categories []aggregate.Category) {
+	sort.SliceStable(categories, func(i, j int) bool {
+		if categories[i].GetPosition() != categories[j].GetPosition() {
+			return categories[i].GetPosition() < categories[j].GetPosition()
+		}
+		return categories[i].GetName() < categories[j].GetName()
+	})
+}
@@ -0,0 +1,164 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"taverne/aggregate"
+	"taverne/domain/category"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type SqliteRepository struct {
+	db *sql.DB
+}
+
+// sqliteCategory is an internal type that is used to store a Category aggregate
+type sqliteCategory struct {
+	ID       uuid.UUID
+	Name     string
+	Position int
+}
+
+// NewFromCategory takes in a aggregate and converts into internal structure
+func NewFromCategory(c aggregate.Category) sqliteCategory {
+	return sqliteCategory{
+		ID:       c.GetID(),
+		Name:     c.GetName(),
+		Position: c.GetPosition(),
+	}
+}
+
+// ToAggregate converts into a aggregate.Category
+func (s sqliteCategory) ToAggregate() aggregate.Category {
+	c := aggregate.Category{}
+
+	c.SetID(s.ID)
+	// the name was validated before it was stored
+	_ = c.SetName(s.Name)
+	c.SetPosition(s.Position)
+
+	return c
+}
+
+// Create a new sqlite repository
+func New(ctx context.Context, connectionString string) (*SqliteRepository, error) {
+	db, err := sql.Open("sqlite3", connectionString)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.ExecContext(ctx,
+		`CREATE TABLE IF NOT EXISTS category (
+			id TEXT PRIMARY KEY,
+			name TEXT NOT NULL,
+			position INT NOT NULL
+		)`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error creating table category, got %v", err)
+	}
+
+	return &SqliteRepository{
+		db: db,
+	}, nil
+}
+
+func (sr *SqliteRepository) GetAll() ([]aggregate.Category, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	rows, err := sr.db.QueryContext(ctx, `SELECT id, name, position FROM category`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []aggregate.Category
+	for rows.Next() {
+		var result sqliteCategory
+		if err := rows.Scan(&result.ID, &result.Name, &result.Position); err != nil {
+			return nil, err
+		}
+		categories = append(categories, result.ToAggregate())
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	category.SortByPosition(categories)
+	return categories, nil
+}
+
+func (sr *SqliteRepository) Get(id uuid.UUID) (aggregate.Category, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	query := `SELECT id, name, position FROM category WHERE id = ?`
+	var result sqliteCategory
+
+	err := sr.db.QueryRowContext(ctx, query, id.String()).Scan(&result.ID, &result.Name, &result.Position)
+	if errors.Is(err, sql.ErrNoRows) {
+		return aggregate.Category{}, category.ErrCategoryNotFound
+	}
+	if err != nil {
+		return aggregate.Category{}, err
+	}
+	return result.ToAggregate(), nil
+}
+
+func (sr *SqliteRepository) Add(c aggregate.Category) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := sr.Get(c.GetID()); err == nil {
+		return category.ErrCategoryAlreadyExist
+	}
+
+	internal := NewFromCategory(c)
+	query := `INSERT INTO category (id, name, position) VALUES (?, ?, ?)`
+	_, err := sr.db.ExecContext(ctx, query, internal.ID.String(), internal.Name, internal.Position)
+	if err != nil {
+		return fmt.Errorf("insert into category failed, got %v", err)
+	}
+	return nil
+}
+
+func (sr *SqliteRepository) Update(c aggregate.Category) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	internal := NewFromCategory(c)
+	query := `UPDATE category SET name = ?, position = ? WHERE id = ?`
+	res, err := sr.db.ExecContext(ctx, query, internal.Name, internal.Position, internal.ID.String())
+	if err != nil {
+		return fmt.Errorf("update category failed, got %v", err)
+	}
+	return expectOneRow(res)
+}
+
+func (sr *SqliteRepository) Delete(id uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	res, err := sr.db.ExecContext(ctx, `DELETE FROM category WHERE id = ?`, id.String())
+	if err != nil {
+		return fmt.Errorf("delete category failed, got %v", err)
+	}
+	return expectOneRow(res)
+}
+
+// expectOneRow turns a statement which changed no row into ErrCategoryNotFound
+func expectOneRow(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return category.ErrCategoryNotFound
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"taverne/aggregate"
+	"taverne/domain/category"
+	"testing"
+)
+
+func TestSqliteRepository_Category(t *testing.T) {
+	repo, err := New(context.Background(), filepath.Join(t.TempDir(), "category.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	drinks, err := aggregate.NewCategory("Drinks", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	starters, err := aggregate.NewCategory("Starters", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []aggregate.Category{drinks, starters} {
+		if err := repo.Add(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.Add(drinks); err != category.ErrCategoryAlreadyExist {
+		t.Errorf("Expected error %v, got %v", category.ErrCategoryAlreadyExist, err)
+	}
+
+	drinks.SetPosition(0)
+	if err := repo.Update(drinks); err != nil {
+		t.Fatal(err)
+	}
+
+	categories, err := repo.GetAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(categories) != 2 || categories[0].GetID() != drinks.GetID() {
+		t.Errorf("Expected drinks to come first, got %v", categories)
+	}
+
+	if err := repo.Delete(drinks.GetID()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Get(drinks.GetID()); err != category.ErrCategoryNotFound {
+		t.Errorf("Expected error %v, got %v", category.ErrCategoryNotFound, err)
+	}
+}
@@ -27,6 +27,9 @@ func New() *MemoryRepository {
 
 // Get finds a customer by ID
 func (mr *MemoryRepository) Get(id uuid.UUID) (aggregate.Customer, error) {
+	mr.Lock()
+	defer mr.Unlock()
+
 	if customer, ok := mr.customers[id]; ok {
 		return customer, nil
 	}
@@ -35,30 +38,46 @@ func (mr *MemoryRepository) Get(id uuid.UUID) (aggregate.Customer, error) {
 
 // Add will add a new customer to the repository
 func (mr *MemoryRepository) Add(c aggregate.Customer) error {
+	mr.Lock()
+	defer mr.Unlock()
+
 	if mr.customers == nil {
 		// saftey check if customers is not create
-		mr.Lock()
 		mr.customers = make(map[uuid.UUID]aggregate.Customer)
-		mr.Unlock()
 	}
 	// Make sure customer isn't already in the repository
 	if _, ok := mr.customers[c.GetID()]; ok {
 		return fmt.Errorf("customer already exists: %w", customer.ErrFailedToAddCustomer)
 	}
-	mr.Lock()
 	mr.customers[c.GetID()] = c
-	mr.Unlock()
 	return nil
 }
 
 // Update will replace an existing customer information with the new customer information
 func (mr *MemoryRepository) Update(c aggregate.Customer) error {
+	mr.Lock()
+	defer mr.Unlock()
+
 	// Make sure Customer is in the repository
 	if _, ok := mr.customers[c.GetID()]; !ok {
 		return fmt.Errorf("customer does not exists: %w", customer.ErrUpdateCustomer)
 	}
-	mr.Lock()
 	mr.customers[c.GetID()] = c
-	mr.Unlock()
 	return nil
 }
+
+// Change applies change to the stored customer while holding the lock of the repository
+func (mr *MemoryRepository) Change(id uuid.UUID, change func(*aggregate.Customer) error) (aggregate.Customer, error) {
+	mr.Lock()
+	defer mr.Unlock()
+
+	c, ok := mr.customers[id]
+	if !ok {
+		return aggregate.Customer{}, customer.ErrCustomerNotFound
+	}
+	if err := change(&c); err != nil {
+		return aggregate.Customer{}, err
+	}
+	mr.customers[id] = c
+	return c, nil
+}
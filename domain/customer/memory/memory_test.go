@@ -94,3 +94,59 @@ func TestMemory_AddCustomer(t *testing.T) {
 		})
 	}
 }
+
+func TestMemory_ChangeCustomer(t *testing.T) {
+	type testCase struct {
+		name        string
+		id          uuid.UUID
+		points      int
+		expectedErr error
+	}
+
+	cust, err := aggregate.NewCustomer("Donald")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := New()
+	if err := repo.Add(cust); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []testCase{
+		{
+			name:        "No customer by ID",
+			id:          uuid.New(),
+			points:      1,
+			expectedErr: customer.ErrCustomerNotFound,
+		}, {
+			name:        "Failing change is not stored",
+			id:          cust.GetID(),
+			points:      -1,
+			expectedErr: aggregate.ErrInvalidPoints,
+		}, {
+			name:        "Change is stored",
+			id:          cust.GetID(),
+			points:      10,
+			expectedErr: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := repo.Change(tc.id, func(c *aggregate.Customer) error {
+				return c.AddPoints(tc.points)
+			})
+			if err != tc.expectedErr {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+
+	found, err := repo.Get(cust.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.GetPoints() != 10 {
+		t.Errorf("Expected 10 points, got %d", found.GetPoints())
+	}
+}
@@ -19,4 +19,7 @@ type CustomerRepository interface {
 	Get(uuid.UUID) (aggregate.Customer, error)
 	Add(aggregate.Customer) error
 	Update(aggregate.Customer) error
+	// Change applies change to the stored customer and stores the result unless change fails.
+	// No other change of the customer can slip in between, so concurrent changes are never lost
+	Change(uuid.UUID, func(*aggregate.Customer) error) (aggregate.Customer, error)
 }
@@ -3,8 +3,13 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
 	"taverne/aggregate"
+	"taverne/domain/customer"
+	"taverne/valueobject"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,22 +18,71 @@ import (
 
 type SqliteRepository struct {
 	db *sql.DB
+	// mu serializes writes, so a change never works on a customer another write is replacing
+	mu sync.Mutex
 }
 
 // sqliteCustomer is an internal type that is used to store a CustomerAggregate
 // we make an internal struct for this to avoid coupling this sqlite implementation to the customeraggregate.
 // sqlite uses
 type sqliteCustomer struct {
-	ID   uuid.UUID
-	Name string
+	ID        uuid.UUID
+	Name      string
+	BirthDate sql.NullTime
+	Email     string
+	Phone     string
+	// Allergies and Diets are stored comma separated
+	Allergies        string
+	Diets            string
+	MarketingConsent bool
+	Points           int
+	Balance          int
+	CreditLimit      int
+	Transactions     []sqliteTransaction
+}
+
+// sqliteTransaction is an internal type that is used to store the transactions of a customer
+type sqliteTransaction struct {
+	Kind      string
+	Amount    int
+	From      uuid.UUID
+	To        uuid.UUID
+	CreatedAt time.Time
 }
 
 // NewFromCustomer takes in a aggregate and converts into internal structure
 func NewFromCustomer(c aggregate.Customer) sqliteCustomer {
-	return sqliteCustomer{
-		ID:   c.GetID(),
-		Name: c.GetName(),
+	s := sqliteCustomer{
+		ID:          c.GetID(),
+		Name:        c.GetName(),
+		BirthDate:   sql.NullTime{Time: c.GetBirthDate(), Valid: !c.GetBirthDate().IsZero()},
+		Email:       c.GetEmail(),
+		Phone:       c.GetPhone(),
+		Points:      c.GetPoints(),
+		Balance:     c.GetBalance(),
+		CreditLimit: c.GetCreditLimit(),
+	}
+	allergies := make([]string, 0, len(c.GetAllergies()))
+	for _, a := range c.GetAllergies() {
+		allergies = append(allergies, string(a))
 	}
+	s.Allergies = strings.Join(allergies, ",")
+	diets := make([]string, 0, len(c.GetDiets()))
+	for _, d := range c.GetDiets() {
+		diets = append(diets, string(d))
+	}
+	s.Diets = strings.Join(diets, ",")
+	s.MarketingConsent = c.HasMarketingConsent()
+	for _, t := range c.GetTransactions() {
+		s.Transactions = append(s.Transactions, sqliteTransaction{
+			Kind:      string(t.GetKind()),
+			Amount:    t.GetAmount(),
+			From:      t.GetFrom(),
+			To:        t.GetTo(),
+			CreatedAt: t.GetCreatedAt(),
+		})
+	}
+	return s
 }
 
 // ToAggregate converts into a aggregate.Customer
@@ -38,20 +92,53 @@ func (s sqliteCustomer) ToAggregate() aggregate.Customer {
 
 	c.SetID(s.ID)
 	c.SetName(s.Name)
+	if s.BirthDate.Valid {
+		// the birth date was validated before it was stored
+		_ = c.SetBirthDate(s.BirthDate.Time)
+	}
+	// the profile was validated before it was stored
+	_ = c.SetEmail(s.Email)
+	_ = c.SetPhone(s.Phone)
+	var allergies []valueobject.Allergen
+	for _, a := range splitList(s.Allergies) {
+		allergies = append(allergies, valueobject.Allergen(a))
+	}
+	_ = c.SetAllergies(allergies...)
+	var diets []valueobject.Diet
+	for _, d := range splitList(s.Diets) {
+		diets = append(diets, valueobject.Diet(d))
+	}
+	_ = c.SetDiets(diets...)
+	c.SetMarketingConsent(s.MarketingConsent)
+	c.SetPoints(s.Points)
+	c.SetBalance(s.Balance)
+	// the credit limit was validated before it was stored
+	_ = c.SetCreditLimit(s.CreditLimit)
+	for _, t := range s.Transactions {
+		c.AddTransaction(valueobject.RestoreTransaction(valueobject.TransactionKind(t.Kind), t.Amount, t.From, t.To, t.CreatedAt))
+	}
 
 	return c
 }
 
+// splitList splits a comma separated column, an empty column is an empty list
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
 // Create a new sqlite repository
 func New(ctx context.Context, connectionString string) (*SqliteRepository, error) {
-	db, err := sql.Open("sqlite3", "/tmp/ddd.db")
+	db, err := sql.Open("sqlite3", connectionString)
 	//defer db.Close()
 	if err != nil {
 		return nil, err
 	}
 
 	// create tabke customers
-	_, err = db.ExecContext(context.Background(),
+	_, err = db.ExecContext(ctx,
 		`CREATE TABLE IF NOT EXISTS customer (
 			id TEXT PRIMARY KEY, 
 			name TEXT NOT NULL,
@@ -63,23 +150,124 @@ func New(ctx context.Context, connectionString string) (*SqliteRepository, error
 		return nil, fmt.Errorf("error creating table customer, got %v", err)
 	}
 
+	// columns added after the table was first created
+	for column, definition := range map[string]string{
+		"points":            "INT NOT NULL DEFAULT 0",
+		"balance":           "INT NOT NULL DEFAULT 0",
+		"credit_limit":      "INT NOT NULL DEFAULT 0",
+		"birth_date":        "TIMESTAMP",
+		"email":             "TEXT NOT NULL DEFAULT ''",
+		"phone":             "TEXT NOT NULL DEFAULT ''",
+		"allergies":         "TEXT NOT NULL DEFAULT ''",
+		"diets":             "TEXT NOT NULL DEFAULT ''",
+		"marketing_consent": "BOOLEAN NOT NULL DEFAULT 0",
+	} {
+		if err := ensureColumn(ctx, db, "customer", column, definition); err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = db.ExecContext(ctx,
+		`CREATE TABLE IF NOT EXISTS customer_transaction (
+			customer_id TEXT NOT NULL REFERENCES customer(id),
+			seq INT NOT NULL,
+			kind TEXT NOT NULL,
+			amount INT NOT NULL,
+			from_id TEXT NOT NULL,
+			to_id TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (customer_id, seq)
+		)`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error creating table customer_transaction, got %v", err)
+	}
+
 	return &SqliteRepository{
 		db: db,
 	}, nil
 
 }
 
+// ensureColumn adds a column to an existing table unless it is there already
+func ensureColumn(ctx context.Context, db *sql.DB, table, column, definition string) error {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(`PRAGMA table_info(%s)`, table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid, notNull, pk int
+			name, typ        string
+			dflt             sql.NullString
+		)
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &dflt, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = db.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, definition))
+	if err != nil {
+		return fmt.Errorf("error adding column %s to %s, got %v", column, table, err)
+	}
+	return nil
+}
+
 func (sr *SqliteRepository) Get(id uuid.UUID) (aggregate.Customer, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	query := `SELECT id, name FROM customer WHERE id = ?`
+	return get(ctx, sr.db, id)
+}
+
+// querier is implemented by sql.DB and sql.Tx, so customers can be read inside a transaction
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// get reads a customer together with all its transactions
+func get(ctx context.Context, q querier, id uuid.UUID) (aggregate.Customer, error) {
+	query := `SELECT id, name, birth_date, email, phone, allergies, diets, marketing_consent, points, balance, credit_limit FROM customer WHERE id = ?`
 	var result sqliteCustomer
 
-	err := sr.db.QueryRowContext(ctx, query, id).Scan(&result.ID, &result.Name)
+	err := q.QueryRowContext(ctx, query, id.String()).Scan(
+		&result.ID, &result.Name, &result.BirthDate, &result.Email, &result.Phone,
+		&result.Allergies, &result.Diets, &result.MarketingConsent, &result.Points, &result.Balance, &result.CreditLimit,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return aggregate.Customer{}, customer.ErrCustomerNotFound
+	}
 	if err != nil {
 		return aggregate.Customer{}, err
 	}
+
+	rows, err := q.QueryContext(ctx,
+		`SELECT kind, amount, from_id, to_id, created_at FROM customer_transaction WHERE customer_id = ? ORDER BY seq`,
+		id.String(),
+	)
+	if err != nil {
+		return aggregate.Customer{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var t sqliteTransaction
+		if err := rows.Scan(&t.Kind, &t.Amount, &t.From, &t.To, &t.CreatedAt); err != nil {
+			return aggregate.Customer{}, err
+		}
+		result.Transactions = append(result.Transactions, t)
+	}
+	if err := rows.Err(); err != nil {
+		return aggregate.Customer{}, err
+	}
 	return result.ToAggregate(), nil
 }
 
@@ -87,14 +275,121 @@ func (sr *SqliteRepository) Add(c aggregate.Customer) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	internal := NewFromCustomer(c)
-	query := `INSERT INTO customer (id, name, age) VALUES (?, ?, ?)`
-	_, err := sr.db.ExecContext(ctx, query, internal.ID.String(), internal.Name, nil)
+
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	tx, err := sr.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("insert into customers failed, got %v\n", err)
+		return err
 	}
-	return nil
+	defer tx.Rollback()
+
+	query := `INSERT INTO customer (id, name, age, birth_date, email, phone, allergies, diets, marketing_consent, points, balance, credit_limit)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err = tx.ExecContext(ctx, query,
+		internal.ID.String(), internal.Name, nil, internal.BirthDate, internal.Email, internal.Phone,
+		internal.Allergies, internal.Diets, internal.MarketingConsent, internal.Points, internal.Balance, internal.CreditLimit,
+	)
+	if err != nil {
+		return fmt.Errorf("insert into customers failed, got %v: %w", err, customer.ErrFailedToAddCustomer)
+	}
+	if err := insertTransactions(ctx, tx, internal, 0); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
+// Update stores the changed values of a customer and all transactions which are not stored yet
 func (sr *SqliteRepository) Update(c aggregate.Customer) error {
-	panic("to implement")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	tx, err := sr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := update(ctx, tx, c); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Change reads, changes and stores the customer in one transaction while no other write runs
+func (sr *SqliteRepository) Change(id uuid.UUID, change func(*aggregate.Customer) error) (aggregate.Customer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	tx, err := sr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return aggregate.Customer{}, err
+	}
+	defer tx.Rollback()
+
+	c, err := get(ctx, tx, id)
+	if err != nil {
+		return aggregate.Customer{}, err
+	}
+	if err := change(&c); err != nil {
+		return aggregate.Customer{}, err
+	}
+	if err := update(ctx, tx, c); err != nil {
+		return aggregate.Customer{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return aggregate.Customer{}, err
+	}
+	return c, nil
+}
+
+// update stores the values of a customer and the transactions which are not stored yet inside tx
+func update(ctx context.Context, tx *sql.Tx, c aggregate.Customer) error {
+	internal := NewFromCustomer(c)
+
+	res, err := tx.ExecContext(ctx,
+		`UPDATE customer SET name = ?, birth_date = ?, email = ?, phone = ?, allergies = ?, diets = ?, marketing_consent = ?,
+			points = ?, balance = ?, credit_limit = ? WHERE id = ?`,
+		internal.Name, internal.BirthDate, internal.Email, internal.Phone,
+		internal.Allergies, internal.Diets, internal.MarketingConsent,
+		internal.Points, internal.Balance, internal.CreditLimit, internal.ID.String(),
+	)
+	if err != nil {
+		return fmt.Errorf("update customer failed, got %v: %w", err, customer.ErrUpdateCustomer)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("customer does not exists: %w", customer.ErrUpdateCustomer)
+	}
+
+	// transactions are only ever appended, so the stored ones are a prefix of the customers transactions
+	var stored int
+	err = tx.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM customer_transaction WHERE customer_id = ?`, internal.ID.String(),
+	).Scan(&stored)
+	if err != nil {
+		return err
+	}
+	return insertTransactions(ctx, tx, internal, stored)
+}
+
+// insertTransactions stores the transactions of a customer from position from on
+func insertTransactions(ctx context.Context, tx *sql.Tx, c sqliteCustomer, from int) error {
+	query := `INSERT INTO customer_transaction (customer_id, seq, kind, amount, from_id, to_id, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	for seq := from; seq < len(c.Transactions); seq++ {
+		t := c.Transactions[seq]
+		_, err := tx.ExecContext(ctx, query, c.ID.String(), seq, t.Kind, t.Amount, t.From.String(), t.To.String(), t.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("insert into customer_transaction failed, got %v", err)
+		}
+	}
+	return nil
 }
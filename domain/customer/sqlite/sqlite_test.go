@@ -0,0 +1,135 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"taverne/aggregate"
+	"taverne/domain/customer"
+	"taverne/valueobject"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestSqliteRepository_Update(t *testing.T) {
+	repo, err := New(context.Background(), filepath.Join(t.TempDir(), "customer.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cust, err := aggregate.NewCustomer("Donald",
+		aggregate.WithEmail("donald@duckburg.org"),
+		aggregate.WithAllergies(valueobject.AllergenFish, valueobject.AllergenSesame),
+		aggregate.WithMarketingConsent(true),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Add(cust); err != nil {
+		t.Fatal(err)
+	}
+
+	birth := time.Date(1990, time.May, 17, 0, 0, 0, 0, time.UTC)
+	if err := cust.SetBirthDate(birth); err != nil {
+		t.Fatal(err)
+	}
+	order := uuid.New()
+	if err := cust.AddPoints(25); err != nil {
+		t.Fatal(err)
+	}
+	cust.AddTransaction(valueobject.NewTransaction(valueobject.TransactionLoyaltyAccrual, 25, order, cust.GetID()))
+	if err := repo.Update(cust); err != nil {
+		t.Fatal(err)
+	}
+	if err := cust.RedeemPoints(10); err != nil {
+		t.Fatal(err)
+	}
+	cust.AddTransaction(valueobject.NewTransaction(valueobject.TransactionLoyaltyRedemption, 10, cust.GetID(), order))
+	if err := repo.Update(cust); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := repo.Get(cust.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.GetPoints() != 15 {
+		t.Errorf("Expected 15 points, got %d", found.GetPoints())
+	}
+	if !found.GetBirthDate().Equal(birth) {
+		t.Errorf("Expected birth date %v, got %v", birth, found.GetBirthDate())
+	}
+	if found.GetEmail() != "donald@duckburg.org" || !found.HasMarketingConsent() {
+		t.Errorf("Expected the profile to be stored, got %s %v", found.GetEmail(), found.HasMarketingConsent())
+	}
+	if allergies := found.GetAllergies(); len(allergies) != 2 || allergies[1] != valueobject.AllergenSesame {
+		t.Errorf("Expected allergies to fish and sesame, got %v", allergies)
+	}
+	transactions := found.GetTransactions()
+	if len(transactions) != 2 {
+		t.Fatalf("Expected 2 transactions, got %d", len(transactions))
+	}
+	if transactions[1].GetKind() != valueobject.TransactionLoyaltyRedemption || transactions[1].GetTo() != order {
+		t.Errorf("Expected redemption towards %v, got %v", order, transactions[1])
+	}
+
+	if _, err := repo.Get(uuid.New()); err != customer.ErrCustomerNotFound {
+		t.Errorf("Expected error %v, got %v", customer.ErrCustomerNotFound, err)
+	}
+}
+
+func TestSqliteRepository_Change(t *testing.T) {
+	repo, err := New(context.Background(), filepath.Join(t.TempDir(), "customer.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cust, err := aggregate.NewCustomer("Donald")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Add(cust); err != nil {
+		t.Fatal(err)
+	}
+
+	// concurrent changes are applied one after another, so no point gets lost
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.Change(cust.GetID(), func(c *aggregate.Customer) error {
+				return c.AddPoints(1)
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// a failing change stores nothing
+	_, err = repo.Change(cust.GetID(), func(c *aggregate.Customer) error {
+		if err := c.AddPoints(5); err != nil {
+			return err
+		}
+		return c.RedeemPoints(100)
+	})
+	if err != aggregate.ErrInsufficientPoints {
+		t.Errorf("Expected error %v, got %v", aggregate.ErrInsufficientPoints, err)
+	}
+
+	found, err := repo.Get(cust.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.GetPoints() != 20 {
+		t.Errorf("Expected 20 points, got %d", found.GetPoints())
+	}
+
+	_, err = repo.Change(uuid.New(), func(*aggregate.Customer) error { return nil })
+	if err != customer.ErrCustomerNotFound {
+		t.Errorf("Expected error %v, got %v", customer.ErrCustomerNotFound, err)
+	}
+}
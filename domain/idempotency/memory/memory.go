@@ -0,0 +1,80 @@
+// Package memory is a in memory implementation of the idempotency Repository interface
+package memory
+
+import (
+	"sync"
+	"taverne/domain/idempotency"
+	"time"
+)
+
+type MemoryRepository struct {
+	records map[string]idempotency.Record
+	sync.Mutex
+}
+
+// New is a factory function to generate a new repository of idempotency records
+func New() *MemoryRepository {
+	return &MemoryRepository{
+		records: make(map[string]idempotency.Record),
+	}
+}
+
+// Get finds the record of a key
+func (mr *MemoryRepository) Get(key string) (idempotency.Record, error) {
+	mr.Lock()
+	defer mr.Unlock()
+
+	if r, ok := mr.records[key]; ok {
+		return r, nil
+	}
+	return idempotency.Record{}, idempotency.ErrRecordNotFound
+}
+
+// Add reserves the key of a record
+func (mr *MemoryRepository) Add(r idempotency.Record) error {
+	mr.Lock()
+	defer mr.Unlock()
+
+	if _, ok := mr.records[r.Key]; ok {
+		return idempotency.ErrRecordAlreadyExist
+	}
+	mr.records[r.Key] = r
+	return nil
+}
+
+// Update replaces the record of a key
+func (mr *MemoryRepository) Update(r idempotency.Record) error {
+	mr.Lock()
+	defer mr.Unlock()
+
+	if _, ok := mr.records[r.Key]; !ok {
+		return idempotency.ErrRecordNotFound
+	}
+	mr.records[r.Key] = r
+	return nil
+}
+
+// Delete forgets the record of a key
+func (mr *MemoryRepository) Delete(key string) error {
+	mr.Lock()
+	defer mr.Unlock()
+
+	if _, ok := mr.records[key]; !ok {
+		return idempotency.ErrRecordNotFound
+	}
+	delete(mr.records, key)
+	return nil
+}
+
+// DeleteBefore forgets all records created before t
+func (mr *MemoryRepository) DeleteBefore(t time.Time) error {
+	mr.Lock()
+	defer mr.Unlock()
+
+	for key, r := range mr.records {
+		if r.CreatedAt.Before(t) {
+			delete(mr.records, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package memory
+
+import (
+	"taverne/domain/idempotency"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestMemoryRepository_Records(t *testing.T) {
+	repo := New()
+	now := time.Date(2024, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+	old := idempotency.Record{Key: "old", Fingerprint: "a", CreatedAt: now.Add(-48 * time.Hour)}
+	fresh := idempotency.Record{Key: "fresh", Fingerprint: "b", CreatedAt: now}
+	for _, r := range []idempotency.Record{old, fresh} {
+		if err := repo.Add(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.Add(fresh); err != idempotency.ErrRecordAlreadyExist {
+		t.Errorf("Expected error %v, got %v", idempotency.ErrRecordAlreadyExist, err)
+	}
+
+	fresh.Result = uuid.New()
+	if err := repo.Update(fresh); err != nil {
+		t.Fatal(err)
+	}
+	found, err := repo.Get("fresh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found.Done() || found.Result != fresh.Result {
+		t.Errorf("Expected result %v, got %v", fresh.Result, found.Result)
+	}
+
+	if err := repo.DeleteBefore(now.Add(-24 * time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Get("old"); err != idempotency.ErrRecordNotFound {
+		t.Errorf("Expected error %v, got %v", idempotency.ErrRecordNotFound, err)
+	}
+	if err := repo.Delete("fresh"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Delete("fresh"); err != idempotency.ErrRecordNotFound {
+		t.Errorf("Expected error %v, got %v", idempotency.ErrRecordNotFound, err)
+	}
+}
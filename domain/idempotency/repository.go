@@ -0,0 +1,43 @@
+// Package idempotency holds the repository and the implementations which remember the results
+// of requests by their idempotency key, so a retried request is not executed twice
+package idempotency
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrRecordNotFound is returned when no request was recorded under the key
+	ErrRecordNotFound = errors.New("no request was recorded under the idempotency key")
+	// ErrRecordAlreadyExist is returned when a key is reserved which is recorded already
+	ErrRecordAlreadyExist = errors.New("a request was recorded under the idempotency key already")
+)
+
+// Record is a request remembered by its idempotency key
+type Record struct {
+	Key string
+	// Fingerprint identifies the payload of the request, a key may only be retried with the same payload
+	Fingerprint string
+	// Result is the ID of what the request created, uuid.Nil while the request is in progress
+	Result    uuid.UUID
+	CreatedAt time.Time
+}
+
+// Done reports if the request finished and its result is known
+func (r Record) Done() bool {
+	return r.Result != uuid.Nil
+}
+
+// Repository remembers requests by their idempotency key
+type Repository interface {
+	Get(key string) (Record, error)
+	// Add reserves the key of a request, it fails with ErrRecordAlreadyExist if the key is taken
+	Add(r Record) error
+	Update(r Record) error
+	Delete(key string) error
+	// DeleteBefore forgets all requests recorded before the time, once they are out of the retention window
+	DeleteBefore(t time.Time) error
+}
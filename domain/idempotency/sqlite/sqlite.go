@@ -0,0 +1,120 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"taverne/domain/idempotency"
+	"time"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+type SqliteRepository struct {
+	db *sql.DB
+}
+
+// Create a new sqlite repository
+func New(ctx context.Context, connectionString string) (*SqliteRepository, error) {
+	db, err := sql.Open("sqlite3", connectionString)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.ExecContext(ctx,
+		`CREATE TABLE IF NOT EXISTS idempotency_record (
+			key TEXT PRIMARY KEY,
+			fingerprint TEXT NOT NULL,
+			result TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL
+		)`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error creating table idempotency_record, got %v", err)
+	}
+
+	return &SqliteRepository{
+		db: db,
+	}, nil
+}
+
+func (sr *SqliteRepository) Get(key string) (idempotency.Record, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var r idempotency.Record
+	err := sr.db.QueryRowContext(ctx,
+		`SELECT key, fingerprint, result, created_at FROM idempotency_record WHERE key = ?`, key,
+	).Scan(&r.Key, &r.Fingerprint, &r.Result, &r.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return idempotency.Record{}, idempotency.ErrRecordNotFound
+	}
+	if err != nil {
+		return idempotency.Record{}, err
+	}
+	return r, nil
+}
+
+// Add reserves the key of a record, the primary key makes sure only one request gets it
+func (sr *SqliteRepository) Add(r idempotency.Record) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := sr.db.ExecContext(ctx,
+		`INSERT INTO idempotency_record (key, fingerprint, result, created_at) VALUES (?, ?, ?, ?)`,
+		r.Key, r.Fingerprint, r.Result.String(), r.CreatedAt.UTC(),
+	)
+	var serr sqlite3.Error
+	if errors.As(err, &serr) && serr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey {
+		return idempotency.ErrRecordAlreadyExist
+	}
+	return err
+}
+
+func (sr *SqliteRepository) Update(r idempotency.Record) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	res, err := sr.db.ExecContext(ctx,
+		`UPDATE idempotency_record SET fingerprint = ?, result = ?, created_at = ? WHERE key = ?`,
+		r.Fingerprint, r.Result.String(), r.CreatedAt.UTC(), r.Key,
+	)
+	if err != nil {
+		return err
+	}
+	return affected(res)
+}
+
+func (sr *SqliteRepository) Delete(key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	res, err := sr.db.ExecContext(ctx, `DELETE FROM idempotency_record WHERE key = ?`, key)
+	if err != nil {
+		return err
+	}
+	return affected(res)
+}
+
+// DeleteBefore forgets all records created before t
+// All times are stored in UTC, so they compare in the order they happened
+func (sr *SqliteRepository) DeleteBefore(t time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := sr.db.ExecContext(ctx, `DELETE FROM idempotency_record WHERE created_at < ?`, t.UTC())
+	return err
+}
+
+// affected returns ErrRecordNotFound if the statement did not change any record
+func affected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return idempotency.ErrRecordNotFound
+	}
+	return nil
+}
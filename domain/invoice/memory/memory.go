@@ -0,0 +1,52 @@
+// Package memory is a in memory implementation of the NumberRepository interface
+package memory
+
+import (
+	"sync"
+	"taverne/domain/invoice"
+
+	"github.com/google/uuid"
+)
+
+type MemoryNumberRepository struct {
+	// last is the last allocated sequence of every year
+	last    map[int]int
+	numbers map[uuid.UUID]invoice.Number
+	sync.Mutex
+}
+
+// New is a factory function to generate a new repository of invoice numbers
+func New() *MemoryNumberRepository {
+	return &MemoryNumberRepository{
+		last:    make(map[int]int),
+		numbers: make(map[uuid.UUID]invoice.Number),
+	}
+}
+
+// Allocate returns the number of the reference, allocating the next number of the year if it has none
+func (mnr *MemoryNumberRepository) Allocate(year int, reference uuid.UUID) (invoice.Number, error) {
+	if year < 1 {
+		return invoice.Number{}, invoice.ErrInvalidYear
+	}
+	mnr.Lock()
+	defer mnr.Unlock()
+
+	if n, ok := mnr.numbers[reference]; ok {
+		return n, nil
+	}
+	mnr.last[year]++
+	n := invoice.Number{Year: year, Sequence: mnr.last[year]}
+	mnr.numbers[reference] = n
+	return n, nil
+}
+
+// Get finds the number allocated to the reference
+func (mnr *MemoryNumberRepository) Get(reference uuid.UUID) (invoice.Number, error) {
+	mnr.Lock()
+	defer mnr.Unlock()
+
+	if n, ok := mnr.numbers[reference]; ok {
+		return n, nil
+	}
+	return invoice.Number{}, invoice.ErrNumberNotFound
+}
@@ -0,0 +1,76 @@
+package memory
+
+import (
+	"sync"
+	"taverne/domain/invoice"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestMemoryNumberRepository_Allocate(t *testing.T) {
+	repo := New()
+	first, second, nextYear := uuid.New(), uuid.New(), uuid.New()
+
+	type testCase struct {
+		name        string
+		year        int
+		reference   uuid.UUID
+		expected    invoice.Number
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{name: "First of the year", year: 2024, reference: first, expected: invoice.Number{Year: 2024, Sequence: 1}},
+		{name: "Second of the year", year: 2024, reference: second, expected: invoice.Number{Year: 2024, Sequence: 2}},
+		{name: "Same reference again", year: 2024, reference: first, expected: invoice.Number{Year: 2024, Sequence: 1}},
+		{name: "New year starts at one", year: 2025, reference: nextYear, expected: invoice.Number{Year: 2025, Sequence: 1}},
+		{name: "Invalid year", year: 0, reference: uuid.New(), expectedErr: invoice.ErrInvalidYear},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			n, err := repo.Allocate(tc.year, tc.reference)
+			if err != tc.expectedErr {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+			if n != tc.expected {
+				t.Errorf("Expected number %v, got %v", tc.expected, n)
+			}
+		})
+	}
+
+	if _, err := repo.Get(uuid.New()); err != invoice.ErrNumberNotFound {
+		t.Errorf("Expected error %v, got %v", invoice.ErrNumberNotFound, err)
+	}
+}
+
+func TestMemoryNumberRepository_Concurrent(t *testing.T) {
+	repo := New()
+
+	const orders = 50
+	var wg sync.WaitGroup
+	numbers := make([]invoice.Number, orders)
+	for i := 0; i < orders; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			n, err := repo.Allocate(2024, uuid.New())
+			if err != nil {
+				t.Error(err)
+			}
+			numbers[i] = n
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make(map[int]bool)
+	for _, n := range numbers {
+		seen[n.Sequence] = true
+	}
+	for i := 1; i <= orders; i++ {
+		if !seen[i] {
+			t.Errorf("Expected number %d to be allocated", i)
+		}
+	}
+}
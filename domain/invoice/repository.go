@@ -0,0 +1,38 @@
+// Package invoice holds the repository and the implementations for gap-free invoice numbers
+package invoice
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrNumberNotFound is returned when no invoice number was allocated to a reference
+	ErrNumberNotFound = errors.New("no invoice number was allocated to the reference")
+	// ErrInvalidYear is returned when a number is allocated in a year before the common era
+	ErrInvalidYear = errors.New("the year of an invoice number has to be positive")
+)
+
+// Number is an invoice number, it counts the invoices of one year starting at one
+type Number struct {
+	Year     int
+	Sequence int
+}
+
+// String formats the number as year and sequence, such as 2024-000042
+func (n Number) String() string {
+	return fmt.Sprintf("%04d-%06d", n.Year, n.Sequence)
+}
+
+// NumberRepository hands out invoice numbers without gaps, every year is a series of its own.
+// A reference, such as an order, gets exactly one number, so allocating again after a failure
+// returns the number it got before and leaves no gap
+type NumberRepository interface {
+	// Allocate returns the number of the reference, it allocates the next number of the year
+	// if the reference has none yet
+	Allocate(year int, reference uuid.UUID) (Number, error)
+	// Get returns the number allocated to the reference
+	Get(reference uuid.UUID) (Number, error)
+}
@@ -0,0 +1,126 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"taverne/domain/invoice"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type SqliteRepository struct {
+	db *sql.DB
+}
+
+// Create a new sqlite repository
+func New(ctx context.Context, connectionString string) (*SqliteRepository, error) {
+	db, err := sql.Open("sqlite3", connectionString)
+	if err != nil {
+		return nil, err
+	}
+	// a single connection serializes all allocations, so two transactions never read the same counter
+	db.SetMaxOpenConns(1)
+
+	_, err = db.ExecContext(ctx,
+		`CREATE TABLE IF NOT EXISTS invoice_counter (
+			year INT PRIMARY KEY,
+			last INT NOT NULL
+		)`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error creating table invoice_counter, got %v", err)
+	}
+	_, err = db.ExecContext(ctx,
+		`CREATE TABLE IF NOT EXISTS invoice_number (
+			reference TEXT PRIMARY KEY,
+			year INT NOT NULL,
+			seq INT NOT NULL,
+			UNIQUE (year, seq)
+		)`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error creating table invoice_number, got %v", err)
+	}
+
+	return &SqliteRepository{
+		db: db,
+	}, nil
+}
+
+// Allocate returns the number of the reference, allocating the next number of the year if it has none.
+// The counter and the number of the reference are written in one transaction, so a failed
+// allocation leaves no gap
+func (sr *SqliteRepository) Allocate(year int, reference uuid.UUID) (invoice.Number, error) {
+	if year < 1 {
+		return invoice.Number{}, invoice.ErrInvalidYear
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := sr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return invoice.Number{}, err
+	}
+	defer tx.Rollback()
+
+	n, err := get(ctx, tx, reference)
+	if err == nil {
+		return n, nil
+	}
+	if !errors.Is(err, invoice.ErrNumberNotFound) {
+		return invoice.Number{}, err
+	}
+
+	n = invoice.Number{Year: year}
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO invoice_counter (year, last) VALUES (?, 1)
+		ON CONFLICT (year) DO UPDATE SET last = last + 1
+		RETURNING last`,
+		year,
+	).Scan(&n.Sequence)
+	if err != nil {
+		return invoice.Number{}, fmt.Errorf("allocating invoice number failed, got %v", err)
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO invoice_number (reference, year, seq) VALUES (?, ?, ?)`,
+		reference.String(), n.Year, n.Sequence,
+	)
+	if err != nil {
+		return invoice.Number{}, fmt.Errorf("storing invoice number failed, got %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return invoice.Number{}, err
+	}
+	return n, nil
+}
+
+// Get finds the number allocated to the reference
+func (sr *SqliteRepository) Get(reference uuid.UUID) (invoice.Number, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return get(ctx, sr.db, reference)
+}
+
+// querier is implemented by both sql.DB and sql.Tx
+type querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func get(ctx context.Context, q querier, reference uuid.UUID) (invoice.Number, error) {
+	var n invoice.Number
+	err := q.QueryRowContext(ctx,
+		`SELECT year, seq FROM invoice_number WHERE reference = ?`, reference.String(),
+	).Scan(&n.Year, &n.Sequence)
+	if errors.Is(err, sql.ErrNoRows) {
+		return invoice.Number{}, invoice.ErrNumberNotFound
+	}
+	if err != nil {
+		return invoice.Number{}, err
+	}
+	return n, nil
+}
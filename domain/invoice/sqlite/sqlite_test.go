@@ -0,0 +1,72 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"taverne/domain/invoice"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestSqliteRepository_Allocate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "invoice.db")
+	repo, err := New(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const orders = 20
+	var wg sync.WaitGroup
+	references := make([]uuid.UUID, orders)
+	for i := 0; i < orders; i++ {
+		references[i] = uuid.New()
+		wg.Add(1)
+		go func(reference uuid.UUID) {
+			defer wg.Done()
+			if _, err := repo.Allocate(2024, reference); err != nil {
+				t.Error(err)
+			}
+		}(references[i])
+	}
+	wg.Wait()
+
+	// the counters survive a restart
+	repo, err = New(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[int]bool)
+	for _, reference := range references {
+		n, err := repo.Get(reference)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// allocating again returns the number the reference already has
+		again, err := repo.Allocate(2024, reference)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if again != n {
+			t.Errorf("Expected number %v again, got %v", n, again)
+		}
+		seen[n.Sequence] = true
+	}
+	for i := 1; i <= orders; i++ {
+		if !seen[i] {
+			t.Errorf("Expected number %d to be allocated", i)
+		}
+	}
+
+	n, err := repo.Allocate(2025, uuid.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.String() != "2025-000001" {
+		t.Errorf("Expected 2025-000001, got %s", n)
+	}
+	if _, err := repo.Get(uuid.New()); err != invoice.ErrNumberNotFound {
+		t.Errorf("Expected error %v, got %v", invoice.ErrNumberNotFound, err)
+	}
+}
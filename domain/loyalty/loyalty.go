@@ -0,0 +1,40 @@
+// Package loyalty holds the rules of the loyalty points program for regulars
+package loyalty
+
+import (
+	"errors"
+	"math"
+)
+
+var (
+	// ErrNoProgram is returned when points are redeemed without a loyalty program
+	ErrNoProgram = errors.New("there is no loyalty program")
+)
+
+// Program decides how many points a customer earns and what they are worth
+type Program struct {
+	// PointsPerUnit is how many points are earned per 1.00 spent
+	PointsPerUnit float64
+	// PointValue is the discount one point is worth when redeemed
+	PointValue float64
+}
+
+// Accrue returns the points earned by spending amount, fractions of a point are dropped
+func (p Program) Accrue(amount float64) int {
+	if amount <= 0 {
+		return 0
+	}
+	// the epsilon keeps 2.00 × 10 from ending up as 19.999…
+	return int(math.Floor(amount*p.PointsPerUnit + 1e-9))
+}
+
+// Redeem returns how many of the offered points are redeemed on a bill of amount
+// and the discount they are worth. No more points are redeemed than needed to pay the bill
+func (p Program) Redeem(offered int, amount float64) (int, float64) {
+	if offered <= 0 || amount <= 0 || p.PointValue <= 0 {
+		return 0, 0
+	}
+	needed := int(math.Ceil(amount/p.PointValue - 1e-9))
+	points := min(offered, needed)
+	return points, math.Min(float64(points)*p.PointValue, amount)
+}
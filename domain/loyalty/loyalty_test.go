@@ -0,0 +1,58 @@
+package loyalty_test
+
+import (
+	"math"
+	"taverne/domain/loyalty"
+	"testing"
+)
+
+func TestLoyalty_Accrue(t *testing.T) {
+	program := loyalty.Program{PointsPerUnit: 10, PointValue: 0.01}
+
+	type testCase struct {
+		name     string
+		amount   float64
+		expected int
+	}
+
+	testCases := []testCase{
+		{name: "Whole amount", amount: 2, expected: 20},
+		{name: "Fractions are dropped", amount: 1.99, expected: 19},
+		{name: "Nothing spent", amount: 0, expected: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := program.Accrue(tc.amount); got != tc.expected {
+				t.Errorf("Expected %d points, got %d", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestLoyalty_Redeem(t *testing.T) {
+	program := loyalty.Program{PointsPerUnit: 10, PointValue: 0.1}
+
+	type testCase struct {
+		name           string
+		offered        int
+		amount         float64
+		expectedPoints int
+		expectedValue  float64
+	}
+
+	testCases := []testCase{
+		{name: "All points", offered: 10, amount: 5, expectedPoints: 10, expectedValue: 1},
+		{name: "Only what is needed", offered: 100, amount: 1.95, expectedPoints: 20, expectedValue: 1.95},
+		{name: "No points", offered: 0, amount: 5, expectedPoints: 0, expectedValue: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			points, value := program.Redeem(tc.offered, tc.amount)
+			if points != tc.expectedPoints || math.Abs(value-tc.expectedValue) > 0.0001 {
+				t.Errorf("Expected %d points worth %.2f, got %d worth %.2f", tc.expectedPoints, tc.expectedValue, points, value)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+// Package memory is a in memory implementation of the OrderRepository interface
+package memory
+
+import (
+	"sync"
+	"taverne/aggregate"
+	"taverne/domain/order"
+
+	"github.com/google/uuid"
+)
+
+type MemoryOrderRepository struct {
+	orders map[uuid.UUID]aggregate.Order
+	sync.Mutex
+}
+
+// New is a factory function to generate a new repository of orders
+func New() *MemoryOrderRepository {
+	return &MemoryOrderRepository{
+		orders: make(map[uuid.UUID]aggregate.Order),
+	}
+}
+
+// Get finds an order by ID
+func (mor *MemoryOrderRepository) Get(id uuid.UUID) (aggregate.Order, error) {
+	mor.Lock()
+	defer mor.Unlock()
+
+	if o, ok := mor.orders[id]; ok {
+		return o, nil
+	}
+	return aggregate.Order{}, order.ErrOrderNotFound
+}
+
+// Add will add a new order to the repository
+func (mor *MemoryOrderRepository) Add(o aggregate.Order) error {
+	mor.Lock()
+	defer mor.Unlock()
+
+	if _, ok := mor.orders[o.GetID()]; ok {
+		return order.ErrOrderAlreadyExist
+	}
+	mor.orders[o.GetID()] = o
+	return nil
+}
+
+// Update will replace an existing order
+func (mor *MemoryOrderRepository) Update(o aggregate.Order) error {
+	mor.Lock()
+	defer mor.Unlock()
+
+	if _, ok := mor.orders[o.GetID()]; !ok {
+		return order.ErrOrderNotFound
+	}
+	mor.orders[o.GetID()] = o
+	return nil
+}
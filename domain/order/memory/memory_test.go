@@ -0,0 +1,83 @@
+package memory
+
+import (
+	"taverne/aggregate"
+	"taverne/domain/order"
+	"taverne/valueobject"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func newTestOrder(t *testing.T) aggregate.Order {
+	o, err := aggregate.NewOrder(uuid.New(), []valueobject.OrderItem{
+		{ProductID: uuid.New(), Name: "Beer", Quantity: 1, UnitPrice: 1.99},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return o
+}
+
+func TestMemoryOrderRepository_Get(t *testing.T) {
+	repo := New()
+	existing := newTestOrder(t)
+	if err := repo.Add(existing); err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		name        string
+		id          uuid.UUID
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			name:        "Get order by id",
+			id:          existing.GetID(),
+			expectedErr: nil,
+		},
+		{
+			name:        "Get non-existing order by id",
+			id:          uuid.New(),
+			expectedErr: order.ErrOrderNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := repo.Get(tc.id)
+			if err != tc.expectedErr {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestMemoryOrderRepository_Update(t *testing.T) {
+	repo := New()
+	existing := newTestOrder(t)
+
+	if err := repo.Update(existing); err != order.ErrOrderNotFound {
+		t.Errorf("Expected error %v, got %v", order.ErrOrderNotFound, err)
+	}
+
+	if err := repo.Add(existing); err != nil {
+		t.Fatal(err)
+	}
+	if err := existing.Prepare(); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Update(existing); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := repo.Get(existing.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.GetStatus() != aggregate.OrderPreparing {
+		t.Errorf("Expected status %s, got %s", aggregate.OrderPreparing, found.GetStatus())
+	}
+}
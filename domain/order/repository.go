@@ -0,0 +1,23 @@
+// Package order holds the repository and the implementations for an OrderRepository
+package order
+
+import (
+	"errors"
+	"taverne/aggregate"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrOrderNotFound is returned when an order is not found
+	ErrOrderNotFound = errors.New("the order was not found")
+	// ErrOrderAlreadyExist is returned when trying to add an order that already exists
+	ErrOrderAlreadyExist = errors.New("the order already exists")
+)
+
+// OrderRepository is the repository interface to fulfill to persist the order aggregate
+type OrderRepository interface {
+	Get(id uuid.UUID) (aggregate.Order, error)
+	Add(order aggregate.Order) error
+	Update(order aggregate.Order) error
+}
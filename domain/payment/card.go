@@ -0,0 +1,101 @@
+package payment
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// CardConfiguration is an alias for a function that will take in a pointer to a FakeCardGateway and modify it
+type CardConfiguration func(fg *FakeCardGateway) error
+
+// FakeCardGateway is a local card payment provider for tests and demos.
+// It declines and times out as configured, everything else is approved
+type FakeCardGateway struct {
+	ledger *ledger
+	// declineAbove declines every authorization of more cents, zero declines nothing
+	declineAbove int
+	declined     map[uuid.UUID]bool
+	// timeouts is the number of upcoming calls which time out
+	timeouts int
+	mu       sync.Mutex
+}
+
+// NewFakeCardGateway takes a variable amount of CardConfigurations and builds a FakeCardGateway
+func NewFakeCardGateway(cfgs ...CardConfiguration) (*FakeCardGateway, error) {
+	fg := &FakeCardGateway{
+		ledger:   newLedger(),
+		declined: make(map[uuid.UUID]bool),
+	}
+	for _, cfg := range cfgs {
+		if err := cfg(fg); err != nil {
+			return nil, err
+		}
+	}
+	return fg, nil
+}
+
+// WithDeclineAbove declines every authorization of more than cents, like a card limit
+func WithDeclineAbove(cents int) CardConfiguration {
+	return func(fg *FakeCardGateway) error {
+		fg.declineAbove = cents
+		return nil
+	}
+}
+
+// WithDeclinedCustomers declines every authorization of the customers, like a blocked card
+func WithDeclinedCustomers(customers ...uuid.UUID) CardConfiguration {
+	return func(fg *FakeCardGateway) error {
+		for _, c := range customers {
+			fg.declined[c] = true
+		}
+		return nil
+	}
+}
+
+// WithTimeouts lets the next n calls time out.
+// Like a real provider the request is processed, only the answer gets lost
+func WithTimeouts(n int) CardConfiguration {
+	return func(fg *FakeCardGateway) error {
+		fg.timeouts = n
+		return nil
+	}
+}
+
+// Authorize reserves cents on the card of the customer
+func (fg *FakeCardGateway) Authorize(key string, customer uuid.UUID, cents int) (Authorization, error) {
+	a, err := fg.ledger.authorize(key, customer, cents, func() error {
+		if fg.declined[customer] || (fg.declineAbove > 0 && cents > fg.declineAbove) {
+			return ErrDeclined
+		}
+		return nil
+	})
+	return fg.answer(a, err)
+}
+
+// Capture charges the card
+func (fg *FakeCardGateway) Capture(id string) (Authorization, error) {
+	return fg.answer(fg.ledger.capture(id))
+}
+
+// Void releases the reserved amount on the card
+func (fg *FakeCardGateway) Void(id string) (Authorization, error) {
+	return fg.answer(fg.ledger.void(id))
+}
+
+// Refund pays cents back to the card
+func (fg *FakeCardGateway) Refund(id, key string, cents int) (Authorization, error) {
+	return fg.answer(fg.ledger.refund(id, key, cents))
+}
+
+// answer loses the answer of a processed call as long as timeouts are configured
+func (fg *FakeCardGateway) answer(a Authorization, err error) (Authorization, error) {
+	fg.mu.Lock()
+	defer fg.mu.Unlock()
+
+	if fg.timeouts > 0 {
+		fg.timeouts--
+		return Authorization{}, ErrTimeout
+	}
+	return a, err
+}
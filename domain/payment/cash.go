@@ -0,0 +1,35 @@
+package payment
+
+import "github.com/google/uuid"
+
+// CashGateway takes cash at the till, it never declines and never times out
+type CashGateway struct {
+	ledger *ledger
+}
+
+// NewCashGateway creates a gateway for cash payments
+func NewCashGateway() *CashGateway {
+	return &CashGateway{
+		ledger: newLedger(),
+	}
+}
+
+// Authorize records the cash handed over
+func (cg *CashGateway) Authorize(key string, customer uuid.UUID, cents int) (Authorization, error) {
+	return cg.ledger.authorize(key, customer, cents, func() error { return nil })
+}
+
+// Capture puts the cash into the till
+func (cg *CashGateway) Capture(id string) (Authorization, error) {
+	return cg.ledger.capture(id)
+}
+
+// Void hands the cash back before it was put into the till
+func (cg *CashGateway) Void(id string) (Authorization, error) {
+	return cg.ledger.void(id)
+}
+
+// Refund pays cash back out of the till
+func (cg *CashGateway) Refund(id, key string, cents int) (Authorization, error) {
+	return cg.ledger.refund(id, key, cents)
+}
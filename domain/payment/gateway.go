@@ -0,0 +1,73 @@
+// Package payment holds the payment gateways which take the money of a bill
+package payment
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrDeclined is returned when the payment provider declines an authorization
+	ErrDeclined = errors.New("the payment was declined")
+	// ErrTimeout is returned when the payment provider did not answer in time,
+	// the request may or may not have been processed and is safe to retry with the same key
+	ErrTimeout = errors.New("the payment provider did not answer in time")
+	// ErrAuthorizationNotFound is returned when no authorization has the given ID
+	ErrAuthorizationNotFound = errors.New("the authorization was not found")
+	// ErrInvalidAmount is returned when an amount below one cent is authorized or refunded
+	ErrInvalidAmount = errors.New("a payment has to be a positive amount")
+	// ErrInvalidStatus is returned when an authorization can not move into the requested status,
+	// such as capturing a voided authorization
+	ErrInvalidStatus = errors.New("the authorization is not in a status which allows this")
+	// ErrKeyReused is returned when an idempotency key is used again for a different payment
+	ErrKeyReused = errors.New("the idempotency key was used for a different payment")
+	// ErrRefundExceedsCapture is returned when more is refunded than was captured
+	ErrRefundExceedsCapture = errors.New("a refund can not exceed the captured amount")
+)
+
+// Method is how a customer pays
+type Method string
+
+const (
+	MethodCash Method = "cash"
+	MethodCard Method = "card"
+)
+
+// Status is the step of the lifecycle an authorization is in
+type Status string
+
+const (
+	// StatusAuthorized reserves the amount, nothing is charged yet
+	StatusAuthorized Status = "authorized"
+	// StatusCaptured charges the reserved amount
+	StatusCaptured Status = "captured"
+	// StatusVoided releases the reserved amount without charging it
+	StatusVoided Status = "voided"
+)
+
+// Authorization is an amount reserved and later charged by a payment provider
+type Authorization struct {
+	ID string
+	// Key is the idempotency key the authorization was requested with
+	Key      string
+	Customer uuid.UUID
+	// Amount and Refunded are in cents
+	Amount   int
+	Refunded int
+	Status   Status
+}
+
+// Gateway is a payment provider.
+// Authorize and Refund are idempotent: calling again with the same key returns the result of the
+// first request, so a retry after a timeout never charges or pays back twice
+type Gateway interface {
+	// Authorize reserves cents of the customer under the idempotency key
+	Authorize(key string, customer uuid.UUID, cents int) (Authorization, error)
+	// Capture charges an authorized amount, capturing twice is no error
+	Capture(id string) (Authorization, error)
+	// Void releases an authorized amount which was not captured, voiding twice is no error
+	Void(id string) (Authorization, error)
+	// Refund pays cents of a captured amount back under the idempotency key
+	Refund(id, key string, cents int) (Authorization, error)
+}
@@ -0,0 +1,126 @@
+package payment
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// ledger keeps the authorizations of a gateway in memory
+type ledger struct {
+	authorizations map[string]Authorization
+	// keys maps idempotency keys to authorization IDs
+	keys map[string]string
+	// refunds maps the idempotency keys of refunds to their authorization ID and amount
+	refunds map[string]refund
+	sync.Mutex
+}
+
+func newLedger() *ledger {
+	return &ledger{
+		authorizations: make(map[string]Authorization),
+		keys:           make(map[string]string),
+		refunds:        make(map[string]refund),
+	}
+}
+
+// refund is a refund which was made under an idempotency key
+type refund struct {
+	id    string
+	cents int
+}
+
+// authorize returns the authorization of the key, approve decides on a new one
+func (l *ledger) authorize(key string, customer uuid.UUID, cents int, approve func() error) (Authorization, error) {
+	if cents < 1 {
+		return Authorization{}, ErrInvalidAmount
+	}
+	l.Lock()
+	defer l.Unlock()
+
+	if id, ok := l.keys[key]; ok {
+		a := l.authorizations[id]
+		if a.Customer != customer || a.Amount != cents {
+			return Authorization{}, ErrKeyReused
+		}
+		return a, nil
+	}
+	if err := approve(); err != nil {
+		return Authorization{}, err
+	}
+	a := Authorization{
+		ID:       uuid.NewString(),
+		Key:      key,
+		Customer: customer,
+		Amount:   cents,
+		Status:   StatusAuthorized,
+	}
+	l.authorizations[a.ID] = a
+	l.keys[key] = a.ID
+	return a, nil
+}
+
+// change applies a change to the authorization with the given ID
+func (l *ledger) change(id string, change func(a *Authorization) error) (Authorization, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	a, ok := l.authorizations[id]
+	if !ok {
+		return Authorization{}, ErrAuthorizationNotFound
+	}
+	if err := change(&a); err != nil {
+		return Authorization{}, err
+	}
+	l.authorizations[id] = a
+	return a, nil
+}
+
+func (l *ledger) capture(id string) (Authorization, error) {
+	return l.change(id, func(a *Authorization) error {
+		switch a.Status {
+		case StatusCaptured:
+			return nil
+		case StatusAuthorized:
+			a.Status = StatusCaptured
+			return nil
+		}
+		return ErrInvalidStatus
+	})
+}
+
+func (l *ledger) void(id string) (Authorization, error) {
+	return l.change(id, func(a *Authorization) error {
+		switch a.Status {
+		case StatusVoided:
+			return nil
+		case StatusAuthorized:
+			a.Status = StatusVoided
+			return nil
+		}
+		return ErrInvalidStatus
+	})
+}
+
+func (l *ledger) refund(id, key string, cents int) (Authorization, error) {
+	if cents < 1 {
+		return Authorization{}, ErrInvalidAmount
+	}
+	return l.change(id, func(a *Authorization) error {
+		if r, ok := l.refunds[key]; ok {
+			if r.id != id || r.cents != cents {
+				return ErrKeyReused
+			}
+			return nil
+		}
+		if a.Status != StatusCaptured {
+			return ErrInvalidStatus
+		}
+		if a.Refunded+cents > a.Amount {
+			return ErrRefundExceedsCapture
+		}
+		a.Refunded += cents
+		l.refunds[key] = refund{id: id, cents: cents}
+		return nil
+	})
+}
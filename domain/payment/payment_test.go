@@ -0,0 +1,117 @@
+package payment_test
+
+import (
+	"errors"
+	"taverne/domain/payment"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestFakeCardGateway_Authorize(t *testing.T) {
+	blocked := uuid.New()
+	g, err := payment.NewFakeCardGateway(
+		payment.WithDeclineAbove(5000),
+		payment.WithDeclinedCustomers(blocked),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	customer := uuid.New()
+
+	type testCase struct {
+		name        string
+		key         string
+		customer    uuid.UUID
+		cents       int
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{name: "Approved", key: "a", customer: customer, cents: 1200},
+		{name: "Same key again", key: "a", customer: customer, cents: 1200},
+		{name: "Same key other amount", key: "a", customer: customer, cents: 1300, expectedErr: payment.ErrKeyReused},
+		{name: "Above the limit", key: "b", customer: customer, cents: 5001, expectedErr: payment.ErrDeclined},
+		{name: "Blocked card", key: "c", customer: blocked, cents: 100, expectedErr: payment.ErrDeclined},
+		{name: "Nothing to pay", key: "d", customer: customer, cents: 0, expectedErr: payment.ErrInvalidAmount},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := g.Authorize(tc.key, tc.customer, tc.cents)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestFakeCardGateway_Timeout(t *testing.T) {
+	g, err := payment.NewFakeCardGateway(payment.WithTimeouts(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	customer := uuid.New()
+
+	// the answer is lost, but the authorization was made
+	if _, err := g.Authorize("order-1", customer, 500); err != payment.ErrTimeout {
+		t.Fatalf("Expected error %v, got %v", payment.ErrTimeout, err)
+	}
+	first, err := g.Authorize("order-1", customer, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := g.Authorize("order-1", customer, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.ID != second.ID {
+		t.Errorf("Expected the retry to return authorization %s, got %s", first.ID, second.ID)
+	}
+}
+
+func TestCashGateway_Lifecycle(t *testing.T) {
+	g := payment.NewCashGateway()
+	customer := uuid.New()
+
+	auth, err := g.Authorize("order-1", customer, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Refund(auth.ID, "refund-1", 100); err != payment.ErrInvalidStatus {
+		t.Errorf("Expected error %v, got %v", payment.ErrInvalidStatus, err)
+	}
+	if _, err := g.Capture(auth.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Void(auth.ID); err != payment.ErrInvalidStatus {
+		t.Errorf("Expected error %v, got %v", payment.ErrInvalidStatus, err)
+	}
+
+	// refunding again with the same key does not pay back twice
+	for i := 0; i < 2; i++ {
+		if auth, err = g.Refund(auth.ID, "refund-1", 600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if auth.Refunded != 600 {
+		t.Errorf("Expected 600 cents refunded, got %d", auth.Refunded)
+	}
+	if _, err := g.Refund(auth.ID, "refund-2", 500); err != payment.ErrRefundExceedsCapture {
+		t.Errorf("Expected error %v, got %v", payment.ErrRefundExceedsCapture, err)
+	}
+
+	voided, err := g.Authorize("order-2", customer, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Void(voided.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Capture(voided.ID); err != payment.ErrInvalidStatus {
+		t.Errorf("Expected error %v, got %v", payment.ErrInvalidStatus, err)
+	}
+	if _, err := g.Capture("unknown"); err != payment.ErrAuthorizationNotFound {
+		t.Errorf("Expected error %v, got %v", payment.ErrAuthorizationNotFound, err)
+	}
+}
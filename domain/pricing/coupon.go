@@ -0,0 +1,115 @@
+package pricing
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"taverne/valueobject"
+)
+
+var (
+	// ErrCouponUnknown is returned when a coupon code is not in the coupon book
+	ErrCouponUnknown = errors.New("the coupon is unknown")
+	// ErrCouponExpired is returned when a coupon is handed in after it expired
+	ErrCouponExpired = errors.New("the coupon has expired")
+	// ErrCouponExhausted is returned when a coupon has been used as often as allowed
+	ErrCouponExhausted = errors.New("the coupon has been used up")
+	// ErrCouponRepeated is returned when the same coupon is handed in more than once with an order
+	ErrCouponRepeated = errors.New("a coupon can only be handed in once per order")
+)
+
+// Coupon is a code a customer can hand in for a discount.
+// Either Percent off the remaining total or a fixed Amount is granted
+type Coupon struct {
+	Code    string
+	Percent float64
+	Amount  float64
+	// ExpiresAt is the end of the validity, the zero time never expires
+	ExpiresAt time.Time
+	// MaxUses limits how often the coupon can be used, zero is unlimited
+	MaxUses int
+}
+
+// CouponBook is a Rule granting the discounts of all coupons handed in with an order.
+// It counts the uses of every coupon
+type CouponBook struct {
+	coupons map[string]Coupon
+	uses    map[string]int
+	sync.Mutex
+}
+
+// NewCouponBook is a factory to create a CouponBook of the given coupons
+func NewCouponBook(coupons ...Coupon) *CouponBook {
+	cb := &CouponBook{
+		coupons: make(map[string]Coupon, len(coupons)),
+		uses:    make(map[string]int),
+	}
+	for _, c := range coupons {
+		cb.coupons[c.Code] = c
+	}
+	return cb
+}
+
+// Uses returns how often a coupon has been used
+func (cb *CouponBook) Uses(code string) int {
+	cb.Lock()
+	defer cb.Unlock()
+
+	return cb.uses[code]
+}
+
+// Apply validates all coupons of the order and counts them as used.
+// Either all coupons are used or, on error, none of them
+func (cb *CouponBook) Apply(order Order) ([]valueobject.Discount, error) {
+	cb.Lock()
+	defer cb.Unlock()
+
+	uses := make(map[string]int)
+	for _, code := range order.Coupons {
+		c, ok := cb.coupons[code]
+		if !ok {
+			return nil, fmt.Errorf("%s: %w", code, ErrCouponUnknown)
+		}
+		if !c.ExpiresAt.IsZero() && order.Time.After(c.ExpiresAt) {
+			return nil, fmt.Errorf("%s: %w", code, ErrCouponExpired)
+		}
+		if uses[code] > 0 {
+			return nil, fmt.Errorf("%s: %w", code, ErrCouponRepeated)
+		}
+		uses[code]++
+		if c.MaxUses > 0 && cb.uses[code]+uses[code] > c.MaxUses {
+			return nil, fmt.Errorf("%s: %w", code, ErrCouponExhausted)
+		}
+	}
+
+	var discounts []valueobject.Discount
+	total := order.Total()
+	for _, code := range order.Coupons {
+		c := cb.coupons[code]
+		amount := c.Amount + total*c.Percent/100
+		discounts = append(discounts, valueobject.Discount{
+			Description: "Coupon " + code,
+			Amount:      amount,
+		})
+		total -= amount
+	}
+
+	for code, n := range uses {
+		cb.uses[code] += n
+	}
+	return discounts, nil
+}
+
+// Release gives back the uses of the coupons of an order which is not placed or cancelled
+func (cb *CouponBook) Release(order Order) {
+	cb.Lock()
+	defer cb.Unlock()
+
+	for _, code := range order.Coupons {
+		if cb.uses[code] > 0 {
+			cb.uses[code]--
+		}
+	}
+}
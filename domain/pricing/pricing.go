@@ -0,0 +1,132 @@
+// Package pricing holds the rules which turn the items of an order into discounts
+package pricing
+
+import (
+	"math"
+	"time"
+
+	"taverne/aggregate"
+	"taverne/valueobject"
+
+	"github.com/google/uuid"
+)
+
+// Line is an ordered item together with the product it was ordered from
+type Line struct {
+	Item    valueobject.OrderItem
+	Product aggregate.Product
+}
+
+// Order is everything a Rule can look at to decide on discounts
+type Order struct {
+	Customer uuid.UUID
+	Lines    []Line
+	// Coupons are the coupon codes handed in by the customer
+	Coupons []string
+	// Time is when the order is placed, taken from the clock of the order service
+	Time time.Time
+	// Discounts holds everything granted by the rules applied before
+	Discounts []valueobject.Discount
+}
+
+// Subtotal returns the sum of all lines before discounts
+func (o Order) Subtotal() float64 {
+	var subtotal float64
+	for _, l := range o.Lines {
+		subtotal += l.Item.Total()
+	}
+	return subtotal
+}
+
+// Total returns the subtotal minus all discounts granted so far
+func (o Order) Total() float64 {
+	total := o.Subtotal()
+	for _, d := range o.Discounts {
+		total -= d.Amount
+	}
+	return total
+}
+
+// Rule decides on discounts for an order
+type Rule interface {
+	Apply(order Order) ([]valueobject.Discount, error)
+}
+
+// RuleFunc is an adapter to use an ordinary function as Rule
+type RuleFunc func(order Order) ([]valueobject.Discount, error)
+
+func (f RuleFunc) Apply(order Order) ([]valueobject.Discount, error) {
+	return f(order)
+}
+
+// Releaser is implemented by rules which keep count of what they granted, such as coupon uses.
+// Release gives back what was counted for an order which is not placed or cancelled after all
+type Releaser interface {
+	Release(order Order)
+}
+
+// Pipeline applies its rules one after another.
+// Every rule sees the discounts of the rules before, so a percentage coupon
+// behind a happy hour rule only reduces what is left to pay
+type Pipeline []Rule
+
+// Apply runs all rules and returns every discount granted.
+// Discounts are rounded to cents and capped so the total never drops below zero
+func (p Pipeline) Apply(order Order) ([]valueobject.Discount, error) {
+	start := len(order.Discounts)
+	for i, rule := range p {
+		discounts, err := rule.Apply(order)
+		if err != nil {
+			// the order is not priced, so the rules before give back what they counted
+			p[:i].Release(order)
+			return nil, err
+		}
+		for _, d := range discounts {
+			d.Amount = math.Min(roundCents(d.Amount), roundCents(order.Total()))
+			if d.Amount <= 0 {
+				continue
+			}
+			order.Discounts = append(order.Discounts, d)
+		}
+	}
+	return order.Discounts[start:], nil
+}
+
+// Release gives back what the rules counted for the order, such as coupon uses
+func (p Pipeline) Release(order Order) {
+	for _, rule := range p {
+		if r, ok := rule.(Releaser); ok {
+			r.Release(order)
+		}
+	}
+}
+
+// Matcher selects the products a rule applies to
+type Matcher func(p aggregate.Product) bool
+
+// AllProducts matches every product
+func AllProducts(aggregate.Product) bool {
+	return true
+}
+
+// Products matches the products with the given IDs
+func Products(ids ...uuid.UUID) Matcher {
+	set := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return func(p aggregate.Product) bool {
+		return set[p.GetID()]
+	}
+}
+
+// InCategory matches the products listed in a menu category, such as drinks
+func InCategory(id uuid.UUID) Matcher {
+	return func(p aggregate.Product) bool {
+		return p.InCategory(id)
+	}
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
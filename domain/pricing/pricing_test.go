@@ -0,0 +1,257 @@
+package pricing_test
+
+import (
+	"errors"
+	"math"
+	"taverne/aggregate"
+	"taverne/domain/pricing"
+	"taverne/valueobject"
+	"testing"
+	"time"
+)
+
+func newLine(t *testing.T, name string, price float64, quantity int) pricing.Line {
+	p, err := aggregate.NewProduct(name, "Healthy Beverage", price)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pricing.Line{
+		Item: valueobject.OrderItem{
+			ProductID: p.GetID(),
+			Name:      name,
+			Quantity:  quantity,
+			UnitPrice: price,
+		},
+		Product: p,
+	}
+}
+
+func sum(discounts []valueobject.Discount) float64 {
+	var total float64
+	for _, d := range discounts {
+		total += d.Amount
+	}
+	return total
+}
+
+func TestPricing_HappyHour(t *testing.T) {
+	beer := newLine(t, "Beer", 5, 2)
+	peanuts := newLine(t, "Peanuts", 2, 1)
+	drinks := pricing.Products(beer.Product.GetID())
+
+	type testCase struct {
+		name     string
+		time     time.Time
+		expected float64
+	}
+
+	testCases := []testCase{
+		{
+			name:     "Before happy hour",
+			time:     time.Date(2026, 1, 1, 16, 59, 0, 0, time.UTC),
+			expected: 0,
+		},
+		{
+			name:     "During happy hour",
+			time:     time.Date(2026, 1, 1, 17, 0, 0, 0, time.UTC),
+			expected: 2,
+		},
+		{
+			name:     "After happy hour",
+			time:     time.Date(2026, 1, 1, 19, 0, 0, 0, time.UTC),
+			expected: 0,
+		},
+	}
+
+	rules := pricing.Pipeline{pricing.HappyHour(17, 19, 20, drinks)}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			discounts, err := rules.Apply(pricing.Order{
+				Lines: []pricing.Line{beer, peanuts},
+				Time:  tc.time,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := sum(discounts); math.Abs(got-tc.expected) > 0.001 {
+				t.Errorf("Expected discount %.2f, got %.2f", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestPricing_HappyHourPastMidnight(t *testing.T) {
+	beer := newLine(t, "Beer", 5, 2)
+	drinks := pricing.Products(beer.Product.GetID())
+
+	type testCase struct {
+		name     string
+		time     time.Time
+		expected float64
+	}
+
+	testCases := []testCase{
+		{
+			name:     "Before happy hour",
+			time:     time.Date(2026, 1, 1, 21, 59, 0, 0, time.UTC),
+			expected: 0,
+		},
+		{
+			name:     "Before midnight",
+			time:     time.Date(2026, 1, 1, 23, 30, 0, 0, time.UTC),
+			expected: 2,
+		},
+		{
+			name:     "After midnight",
+			time:     time.Date(2026, 1, 2, 1, 59, 0, 0, time.UTC),
+			expected: 2,
+		},
+		{
+			name:     "After happy hour",
+			time:     time.Date(2026, 1, 2, 2, 0, 0, 0, time.UTC),
+			expected: 0,
+		},
+	}
+
+	rules := pricing.Pipeline{pricing.HappyHour(22, 2, 20, drinks)}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			discounts, err := rules.Apply(pricing.Order{
+				Lines: []pricing.Line{beer},
+				Time:  tc.time,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := sum(discounts); math.Abs(got-tc.expected) > 0.001 {
+				t.Errorf("Expected discount %.2f, got %.2f", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestPricing_BuyNGetOneFree(t *testing.T) {
+	beer := newLine(t, "Beer", 5, 2)
+	moreBeer := beer
+	moreBeer.Item.Quantity = 3
+
+	rules := pricing.Pipeline{pricing.BuyNGetOneFree(2, pricing.AllProducts)}
+	discounts, err := rules.Apply(pricing.Order{Lines: []pricing.Line{beer, moreBeer}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 5 beers across two lines, every third is free
+	if len(discounts) != 1 || discounts[0].Amount != 5 {
+		t.Errorf("Expected one free beer, got %v", discounts)
+	}
+}
+
+func TestPricing_Coupons(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	book := pricing.NewCouponBook(
+		pricing.Coupon{Code: "TEN", Percent: 10},
+		pricing.Coupon{Code: "FIVER", Amount: 5, MaxUses: 1},
+		pricing.Coupon{Code: "OLD", Percent: 50, ExpiresAt: now.Add(-time.Hour)},
+	)
+	rules := pricing.Pipeline{book}
+
+	type testCase struct {
+		name        string
+		coupons     []string
+		expected    float64
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			name:     "Percentage then fixed",
+			coupons:  []string{"TEN", "FIVER"},
+			expected: 2 + 5,
+		},
+		{
+			name:        "Fixed coupon used up",
+			coupons:     []string{"FIVER"},
+			expectedErr: pricing.ErrCouponExhausted,
+		},
+		{
+			name:        "Expired coupon",
+			coupons:     []string{"OLD"},
+			expectedErr: pricing.ErrCouponExpired,
+		},
+		{
+			name:        "Unknown coupon",
+			coupons:     []string{"FREEBEER"},
+			expectedErr: pricing.ErrCouponUnknown,
+		},
+		{
+			name:        "Same coupon twice",
+			coupons:     []string{"TEN", "TEN"},
+			expectedErr: pricing.ErrCouponRepeated,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			discounts, err := rules.Apply(pricing.Order{
+				Lines:   []pricing.Line{newLine(t, "Beer", 5, 4)},
+				Coupons: tc.coupons,
+				Time:    now,
+			})
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+			if got := sum(discounts); math.Abs(got-tc.expected) > 0.001 {
+				t.Errorf("Expected discount %.2f, got %.2f", tc.expected, got)
+			}
+		})
+	}
+
+	if uses := book.Uses("TEN"); uses != 1 {
+		t.Errorf("Expected coupon to be used once, got %d", uses)
+	}
+
+	rules.Release(pricing.Order{Coupons: []string{"TEN", "FIVER"}})
+	if uses := book.Uses("FIVER"); uses != 0 {
+		t.Errorf("Expected released coupon to be unused, got %d", uses)
+	}
+	if _, err := rules.Apply(pricing.Order{
+		Lines:   []pricing.Line{newLine(t, "Beer", 5, 4)},
+		Coupons: []string{"FIVER"},
+		Time:    now,
+	}); err != nil {
+		t.Errorf("Expected released coupon to be usable again, got %v", err)
+	}
+}
+
+func TestPricing_FailingRuleReleasesCoupons(t *testing.T) {
+	book := pricing.NewCouponBook(pricing.Coupon{Code: "FIVER", Amount: 5, MaxUses: 1})
+	failing := errors.New("rule failed")
+	rules := pricing.Pipeline{book, pricing.RuleFunc(func(pricing.Order) ([]valueobject.Discount, error) {
+		return nil, failing
+	})}
+
+	_, err := rules.Apply(pricing.Order{
+		Lines:   []pricing.Line{newLine(t, "Beer", 5, 4)},
+		Coupons: []string{"FIVER"},
+	})
+	if !errors.Is(err, failing) {
+		t.Fatalf("Expected error %v, got %v", failing, err)
+	}
+	if uses := book.Uses("FIVER"); uses != 0 {
+		t.Errorf("Expected coupon to be unused, got %d", uses)
+	}
+}
+
+func TestPricing_DiscountsCappedAtTotal(t *testing.T) {
+	rules := pricing.Pipeline{pricing.NewCouponBook(pricing.Coupon{Code: "BIG", Amount: 100})}
+	discounts, err := rules.Apply(pricing.Order{
+		Lines:   []pricing.Line{newLine(t, "Beer", 5, 1)},
+		Coupons: []string{"BIG"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sum(discounts); got != 5 {
+		t.Errorf("Expected discount capped at 5.00, got %.2f", got)
+	}
+}
@@ -0,0 +1,85 @@
+package pricing
+
+import (
+	"fmt"
+
+	"taverne/valueobject"
+
+	"github.com/google/uuid"
+)
+
+// HappyHour grants percent off every matching line while the order is placed
+// between the hours from (inclusive) and to (exclusive), e.g. HappyHour(17, 19, 20, drinks).
+// A window ending before it starts runs past midnight, e.g. HappyHour(22, 2, 20, drinks)
+func HappyHour(from, to int, percent float64, match Matcher) Rule {
+	return RuleFunc(func(order Order) ([]valueobject.Discount, error) {
+		hour := order.Time.Hour()
+		inside := hour >= from && hour < to
+		if from > to {
+			inside = hour >= from || hour < to
+		}
+		if !inside {
+			return nil, nil
+		}
+		var discounts []valueobject.Discount
+		for _, l := range order.Lines {
+			if !match(l.Product) {
+				continue
+			}
+			discounts = append(discounts, valueobject.Discount{
+				Description: fmt.Sprintf("Happy hour %g%% %s", percent, l.Item.Name),
+				ProductID:   l.Item.ProductID,
+				Amount:      l.Item.Total() * percent / 100,
+			})
+		}
+		return discounts, nil
+	})
+}
+
+// BuyNGetOneFree makes every n+1th unit of a matching product free.
+// Units of the same product and variant are counted across all lines of the order
+func BuyNGetOneFree(n int, match Matcher) Rule {
+	return RuleFunc(func(order Order) ([]valueobject.Discount, error) {
+		if n < 1 {
+			return nil, nil
+		}
+		type key struct {
+			product uuid.UUID
+			variant uuid.UUID
+		}
+		quantities := make(map[key]int)
+		// first keeps the first item of every product and variant for its name and price
+		first := make(map[key]valueobject.OrderItem)
+		var keys []key
+		for _, l := range order.Lines {
+			if !match(l.Product) {
+				continue
+			}
+			k := key{product: l.Item.ProductID, variant: l.Item.VariantID}
+			if _, ok := quantities[k]; !ok {
+				keys = append(keys, k)
+				first[k] = l.Item
+			}
+			quantities[k] += l.Item.Quantity
+		}
+
+		var discounts []valueobject.Discount
+		for _, k := range keys {
+			free := quantities[k] / (n + 1)
+			if free == 0 {
+				continue
+			}
+			item := first[k]
+			name := item.Name
+			if item.Variant != "" {
+				name += " " + item.Variant
+			}
+			discounts = append(discounts, valueobject.Discount{
+				Description: fmt.Sprintf("Buy %d get one free %s", n, name),
+				ProductID:   k.product,
+				Amount:      float64(free) * item.UnitPrice,
+			})
+		}
+		return discounts, nil
+	})
+}
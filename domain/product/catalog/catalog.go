@@ -0,0 +1,201 @@
+// Package catalog imports and exports the product catalogue as CSV or JSON
+//
+// Every row describes one product. A row with the ID of a known product updates it,
+// any other row creates a new product. Rows are validated like NewProduct does,
+// so a row without name or description fails with aggregate.ErrMissingValues.
+// Imports change many products at once, OrderService.ImportCatalogCSV and ImportCatalogJSON
+// run them while no order takes stock and check the tax categories against the tax table.
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"taverne/aggregate"
+	"taverne/domain/product"
+	"taverne/domain/tax"
+	"taverne/valueobject"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidHeader is returned when a CSV file does not start with the expected header
+	ErrInvalidHeader = errors.New("the header of the catalogue is invalid")
+	// ErrInvalidValue is returned for a row with a value which can not be parsed
+	ErrInvalidValue = errors.New("invalid value")
+	// ErrUnknownTaxCategory is returned for a row with a tax category which is not in the tax table
+	ErrUnknownTaxCategory = errors.New("the tax category is not in the tax table")
+)
+
+// Row is a product as it is imported and exported
+type Row struct {
+	ID          uuid.UUID               `json:"id"`
+	Name        string                  `json:"name"`
+	Description string                  `json:"description"`
+	Price       float64                 `json:"price"`
+	TaxCategory valueobject.TaxCategory `json:"tax_category"`
+	Available   bool                    `json:"available"`
+}
+
+// Options change how a catalogue is imported
+type Options struct {
+	// DryRun validates all rows and reports what would change without touching the repository
+	DryRun bool
+	// Now is when changed prices take effect, time.Now if nil
+	Now func() time.Time
+	// Taxes is the tax table which has to know the tax category of every row, nil accepts any category
+	Taxes *tax.Table
+}
+
+// RowError is the error of a single row, rows are counted from 1 without the header
+type RowError struct {
+	Row int
+	Err error
+}
+
+func (e RowError) Error() string {
+	return fmt.Sprintf("row %d: %v", e.Row, e.Err)
+}
+
+func (e RowError) Unwrap() error {
+	return e.Err
+}
+
+// Report tells what an import did, or in a dry run would do
+type Report struct {
+	Created []uuid.UUID
+	Updated []uuid.UUID
+	Errors  []RowError
+}
+
+// importRows upserts all rows which parsed without error.
+// parseErrs holds the rows which failed parsing, they are skipped
+func importRows(rows map[int]Row, parseErrs []RowError, repo product.ProductRepository, opts Options) Report {
+	report := Report{Errors: parseErrs}
+	now := time.Now
+	if opts.Now != nil {
+		now = opts.Now
+	}
+
+	numbers := make([]int, 0, len(rows))
+	for n := range rows {
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+
+	// pending keeps the products of a dry run, so a later row updates what an earlier row created
+	pending := make(map[uuid.UUID]aggregate.Product)
+	for _, n := range numbers {
+		if opts.Taxes != nil && !opts.Taxes.Has(rows[n].TaxCategory) {
+			report.Errors = append(report.Errors, RowError{Row: n, Err: fmt.Errorf("%w: %q", ErrUnknownTaxCategory, rows[n].TaxCategory)})
+			continue
+		}
+		created, p, err := upsert(rows[n], repo, pending, now())
+		if err != nil {
+			report.Errors = append(report.Errors, RowError{Row: n, Err: err})
+			continue
+		}
+		if !opts.DryRun {
+			if created {
+				err = repo.Add(p)
+			} else {
+				err = repo.Update(p)
+			}
+			if err != nil {
+				report.Errors = append(report.Errors, RowError{Row: n, Err: err})
+				continue
+			}
+		}
+		pending[p.GetID()] = p
+		if created {
+			report.Created = append(report.Created, p.GetID())
+		} else {
+			report.Updated = append(report.Updated, p.GetID())
+		}
+	}
+
+	sort.SliceStable(report.Errors, func(i, j int) bool {
+		return report.Errors[i].Row < report.Errors[j].Row
+	})
+	return report
+}
+
+// upsert applies a row to the product with its ID, or to a new product
+func upsert(row Row, repo product.ProductRepository, pending map[uuid.UUID]aggregate.Product, now time.Time) (bool, aggregate.Product, error) {
+	if row.Price < 0 {
+		return false, aggregate.Product{}, aggregate.ErrInvalidPrice
+	}
+
+	existing, ok := pending[row.ID]
+	if !ok && row.ID != uuid.Nil {
+		var err error
+		existing, err = repo.GetByID(row.ID)
+		if err != nil && !errors.Is(err, product.ErrProductNotFound) {
+			return false, aggregate.Product{}, err
+		}
+		ok = err == nil
+	}
+
+	if !ok {
+		p, err := aggregate.NewProduct(row.Name, row.Description, row.Price)
+		if err != nil {
+			return false, aggregate.Product{}, err
+		}
+		if row.ID != uuid.Nil {
+			p.SetID(row.ID)
+		}
+		apply(&p, row)
+		return true, p, nil
+	}
+
+	if err := existing.SetName(row.Name); err != nil {
+		return false, aggregate.Product{}, err
+	}
+	if err := existing.SetDescription(row.Description); err != nil {
+		return false, aggregate.Product{}, err
+	}
+	if existing.PriceAt(now) != row.Price {
+		if err := existing.ChangePrice(row.Price, now); err != nil {
+			return false, aggregate.Product{}, err
+		}
+	}
+	apply(&existing, row)
+	return false, existing, nil
+}
+
+// apply sets the values of a row which need no validation
+func apply(p *aggregate.Product, row Row) {
+	p.SetTaxCategory(row.TaxCategory)
+	if row.Available {
+		p.MarkAvailable()
+	} else {
+		p.MarkUnavailable()
+	}
+}
+
+// exportRows returns all products which are not archived as rows sorted by name
+func exportRows(repo product.ProductRepository, now time.Time) ([]Row, error) {
+	products, err := repo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(products, func(i, j int) bool {
+		return products[i].GetItem().Name < products[j].GetItem().Name
+	})
+
+	rows := make([]Row, 0, len(products))
+	for _, p := range products {
+		rows = append(rows, Row{
+			ID:          p.GetID(),
+			Name:        p.GetItem().Name,
+			Description: p.GetItem().Description,
+			Price:       p.PriceAt(now),
+			TaxCategory: p.GetTaxCategory(),
+			Available:   p.IsAvailable(),
+		})
+	}
+	return rows, nil
+}
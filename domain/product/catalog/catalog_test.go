@@ -0,0 +1,161 @@
+package catalog
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"taverne/aggregate"
+	"taverne/domain/product/memory"
+	"taverne/valueobject"
+	"testing"
+)
+
+func TestCatalog_ImportCSV(t *testing.T) {
+	beer, err := aggregate.NewProduct("Beer", "Healthy Beverage", 1.99)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	csv := "id,name,description,price,tax_category,available\n" +
+		beer.GetID().String() + ",Beer,Cold and fresh,2.49,beverage,\n" +
+		",Pretzel,Warm and salty,1.50,food,true\n" +
+		",,No name,1.00,food,\n" +
+		",Wine,Red,cheap,beverage,\n" +
+		",Cider,From the barrel,3.20,beverage,false\n"
+
+	type testCase struct {
+		name   string
+		dryRun bool
+	}
+
+	testCases := []testCase{
+		{name: "Dry run", dryRun: true},
+		{name: "Import", dryRun: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := memory.New()
+			if err := repo.Add(beer); err != nil {
+				t.Fatal(err)
+			}
+
+			report, err := ImportCSV(strings.NewReader(csv), repo, Options{DryRun: tc.dryRun})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(report.Created) != 2 || len(report.Updated) != 1 {
+				t.Errorf("Expected 2 created and 1 updated, got %v", report)
+			}
+			if len(report.Errors) != 2 {
+				t.Fatalf("Expected 2 row errors, got %v", report.Errors)
+			}
+			if report.Errors[0].Row != 3 || !errors.Is(report.Errors[0], aggregate.ErrMissingValues) {
+				t.Errorf("Expected row 3 to miss values, got %v", report.Errors[0])
+			}
+			if report.Errors[1].Row != 4 || !errors.Is(report.Errors[1], ErrInvalidValue) {
+				t.Errorf("Expected row 4 to have an invalid value, got %v", report.Errors[1])
+			}
+
+			all, _ := repo.GetAll()
+			stored, _ := repo.GetByID(beer.GetID())
+			if tc.dryRun {
+				if len(all) != 1 || stored.GetPrice() != 1.99 {
+					t.Errorf("Expected dry run to leave the repository untouched")
+				}
+				return
+			}
+			if len(all) != 3 {
+				t.Errorf("Expected 3 products, got %d", len(all))
+			}
+			if stored.GetPrice() != 2.49 || stored.GetItem().Description != "Cold and fresh" {
+				t.Errorf("Expected beer to be updated, got %.2f %s", stored.GetPrice(), stored.GetItem().Description)
+			}
+			if stored.GetTaxCategory() != valueobject.TaxBeverage {
+				t.Errorf("Expected beer to be a beverage, got %q", stored.GetTaxCategory())
+			}
+			if len(stored.GetPriceHistory()) != 2 {
+				t.Errorf("Expected the price change to be kept in the history, got %v", stored.GetPriceHistory())
+			}
+		})
+	}
+}
+
+func TestCatalog_ImportCSVHeader(t *testing.T) {
+	_, err := ImportCSV(strings.NewReader("name,price\nBeer,1.99\n"), memory.New(), Options{})
+	if !errors.Is(err, ErrInvalidHeader) {
+		t.Errorf("Expected error %v, got %v", ErrInvalidHeader, err)
+	}
+}
+
+func TestCatalog_RoundTrip(t *testing.T) {
+	repo := memory.New()
+	for _, p := range []struct {
+		name  string
+		price float64
+	}{{"Beer", 1.99}, {"Wine", 3.5}} {
+		prod, err := aggregate.NewProduct(p.name, "Healthy Beverage", p.price)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prod.SetTaxCategory(valueobject.TaxBeverage)
+		if err := repo.Add(prod); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	type testCase struct {
+		name     string
+		export   func(*bytes.Buffer) error
+		importer func(*bytes.Buffer, *memory.MemoryProductRepository) (Report, error)
+	}
+
+	testCases := []testCase{
+		{
+			name:   "CSV",
+			export: func(b *bytes.Buffer) error { return ExportCSV(b, repo) },
+			importer: func(b *bytes.Buffer, r *memory.MemoryProductRepository) (Report, error) {
+				return ImportCSV(b, r, Options{})
+			},
+		},
+		{
+			name:   "JSON",
+			export: func(b *bytes.Buffer) error { return ExportJSON(b, repo) },
+			importer: func(b *bytes.Buffer, r *memory.MemoryProductRepository) (Report, error) {
+				return ImportJSON(b, r, Options{})
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tc.export(&buf); err != nil {
+				t.Fatal(err)
+			}
+
+			target := memory.New()
+			report, err := tc.importer(&buf, target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(report.Created) != 2 || len(report.Errors) != 0 {
+				t.Fatalf("Expected 2 products created without errors, got %v", report)
+			}
+			for _, id := range report.Created {
+				original, err := repo.GetByID(id)
+				if err != nil {
+					t.Fatal(err)
+				}
+				copied, err := target.GetByID(id)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if copied.GetItem().Name != original.GetItem().Name || copied.GetPrice() != original.GetPrice() ||
+					copied.GetTaxCategory() != original.GetTaxCategory() || !copied.IsAvailable() {
+					t.Errorf("Expected %v, got %v", original.GetItem(), copied.GetItem())
+				}
+			}
+		})
+	}
+}
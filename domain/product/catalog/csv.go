@@ -0,0 +1,121 @@
+package catalog
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"taverne/domain/product"
+	"taverne/valueobject"
+
+	"github.com/google/uuid"
+)
+
+// header are the columns of a CSV catalogue
+var header = []string{"id", "name", "description", "price", "tax_category", "available"}
+
+// ImportCSV upserts all products of a CSV catalogue.
+// The first line has to be the header, an empty id creates a new product
+// and an empty available column means the product is available.
+// Rows which fail are reported and skipped, the error is only set if the file can not be read
+func ImportCSV(r io.Reader, repo product.ProductRepository, opts Options) (Report, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = len(header)
+	cr.TrimLeadingSpace = true
+
+	first, err := cr.Read()
+	if err != nil {
+		return Report{}, fmt.Errorf("%w: %v", ErrInvalidHeader, err)
+	}
+	for i, col := range header {
+		if strings.TrimSpace(strings.ToLower(first[i])) != col {
+			return Report{}, fmt.Errorf("%w: expected %s", ErrInvalidHeader, strings.Join(header, ","))
+		}
+	}
+
+	rows := make(map[int]Row)
+	var errs []RowError
+	for n := 1; ; n++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if _, ok := err.(*csv.ParseError); ok {
+				errs = append(errs, RowError{Row: n, Err: err})
+				continue
+			}
+			return Report{}, err
+		}
+		row, err := parseRecord(record)
+		if err != nil {
+			errs = append(errs, RowError{Row: n, Err: err})
+			continue
+		}
+		rows[n] = row
+	}
+	return importRows(rows, errs, repo, opts), nil
+}
+
+// ExportCSV writes all products which are not archived as CSV with header
+func ExportCSV(w io.Writer, repo product.ProductRepository) error {
+	rows, err := exportRows(repo, time.Now())
+	if err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		err := cw.Write([]string{
+			row.ID.String(),
+			row.Name,
+			row.Description,
+			strconv.FormatFloat(row.Price, 'f', 2, 64),
+			string(row.TaxCategory),
+			strconv.FormatBool(row.Available),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func parseRecord(record []string) (Row, error) {
+	row := Row{
+		Name:        strings.TrimSpace(record[1]),
+		Description: strings.TrimSpace(record[2]),
+		TaxCategory: valueobject.TaxCategory(strings.TrimSpace(record[4])),
+		Available:   true,
+	}
+
+	if id := strings.TrimSpace(record[0]); id != "" {
+		parsed, err := uuid.Parse(id)
+		if err != nil {
+			return Row{}, fmt.Errorf("%w: id %q", ErrInvalidValue, id)
+		}
+		row.ID = parsed
+	}
+
+	price, err := strconv.ParseFloat(strings.TrimSpace(record[3]), 64)
+	if err != nil {
+		return Row{}, fmt.Errorf("%w: price %q", ErrInvalidValue, record[3])
+	}
+	row.Price = price
+
+	if available := strings.TrimSpace(record[5]); available != "" {
+		parsed, err := strconv.ParseBool(available)
+		if err != nil {
+			return Row{}, fmt.Errorf("%w: available %q", ErrInvalidValue, available)
+		}
+		row.Available = parsed
+	}
+	return row, nil
+}
@@ -0,0 +1,50 @@
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"taverne/domain/product"
+)
+
+// jsonRow is a Row as read from JSON, a missing available field means the product is available
+type jsonRow struct {
+	Row
+	Available *bool `json:"available"`
+}
+
+// ImportJSON upserts all products of a JSON catalogue, which is an array of rows.
+// Rows which fail are reported and skipped, the error is only set if the document can not be read
+func ImportJSON(r io.Reader, repo product.ProductRepository, opts Options) (Report, error) {
+	var raw []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return Report{}, err
+	}
+
+	rows := make(map[int]Row)
+	var errs []RowError
+	for i, msg := range raw {
+		var jr jsonRow
+		if err := json.Unmarshal(msg, &jr); err != nil {
+			errs = append(errs, RowError{Row: i + 1, Err: fmt.Errorf("%w: %v", ErrInvalidValue, err)})
+			continue
+		}
+		row := jr.Row
+		row.Available = jr.Available == nil || *jr.Available
+		rows[i+1] = row
+	}
+	return importRows(rows, errs, repo, opts), nil
+}
+
+// ExportJSON writes all products which are not archived as an indented JSON array
+func ExportJSON(w io.Writer, repo product.ProductRepository) error {
+	rows, err := exportRows(repo, time.Now())
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rows)
+}
@@ -21,15 +21,30 @@ func New() *MemoryProductRepository {
 	}
 }
 
-// GetAll returns all products as a slice
+// GetAll returns all products which are not archived as a slice
 // A database implementation could return an error
 func (mpr *MemoryProductRepository) GetAll() ([]aggregate.Product, error) {
+	return mpr.collect(func(p aggregate.Product) bool { return !p.IsArchived() }), nil
+}
+
+// GetArchived returns all archived products as a slice
+func (mpr *MemoryProductRepository) GetArchived() ([]aggregate.Product, error) {
+	return mpr.collect(aggregate.Product.IsArchived), nil
+}
+
+// collect returns all products matching keep
+func (mpr *MemoryProductRepository) collect(keep func(aggregate.Product) bool) []aggregate.Product {
+	mpr.Lock()
+	defer mpr.Unlock()
+
 	// Collect all Products from map
 	var products []aggregate.Product
 	for _, product := range mpr.products {
-		products = append(products, product)
+		if keep(product) {
+			products = append(products, product)
+		}
 	}
-	return products, nil
+	return products
 }
 
 // GetByID searches for a product based on it's ID
@@ -40,6 +55,33 @@ func (mpr *MemoryProductRepository) GetByID(id uuid.UUID) (aggregate.Product, er
 	return aggregate.Product{}, product.ErrProductNotFound
 }
 
+// GetByIDs searches for all products with a single lock on the repository
+// Every unknown id is collected into a product.MissingProductsError
+func (mpr *MemoryProductRepository) GetByIDs(ids []uuid.UUID) ([]aggregate.Product, error) {
+	mpr.Lock()
+	defer mpr.Unlock()
+
+	products := make([]aggregate.Product, 0, len(ids))
+	var missing []uuid.UUID
+	seen := make(map[uuid.UUID]bool)
+	for _, id := range ids {
+		p, ok := mpr.products[id]
+		if !ok {
+			if !seen[id] {
+				missing = append(missing, id)
+				seen[id] = true
+			}
+			continue
+		}
+		products = append(products, p)
+	}
+
+	if len(missing) > 0 {
+		return nil, &product.MissingProductsError{IDs: missing}
+	}
+	return products, nil
+}
+
 // Add will add a new product to the repository
 func (mpr *MemoryProductRepository) Add(newprod aggregate.Product) error {
 	mpr.Lock()
@@ -67,14 +109,26 @@ func (mpr *MemoryProductRepository) Update(upprod aggregate.Product) error {
 	return nil
 }
 
-// Delete remove an product from the repository
+// Delete archives a product, it stays in the repository for historic orders
 func (mpr *MemoryProductRepository) Delete(id uuid.UUID) error {
+	return mpr.change(id, (*aggregate.Product).Archive)
+}
+
+// Restore puts an archived product back
+func (mpr *MemoryProductRepository) Restore(id uuid.UUID) error {
+	return mpr.change(id, (*aggregate.Product).Restore)
+}
+
+// change applies change to the product with the given id
+func (mpr *MemoryProductRepository) change(id uuid.UUID, change func(*aggregate.Product)) error {
 	mpr.Lock()
 	defer mpr.Unlock()
 
-	if _, ok := mpr.products[id]; !ok {
+	p, ok := mpr.products[id]
+	if !ok {
 		return product.ErrProductNotFound
 	}
-	delete(mpr.products, id)
+	change(&p)
+	mpr.products[id] = p
 	return nil
 }
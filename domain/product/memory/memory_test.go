@@ -1,6 +1,7 @@
 package memory
 
 import (
+	"errors"
 	"taverne/aggregate"
 	"taverne/domain/product"
 	"testing"
@@ -76,7 +77,109 @@ func TestMemoryProductRepository_Delete(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
-	if len(repo.products) != 0 {
-		t.Errorf("Expected 0 products, got %d", len(repo.products))
+	// The product is archived, not removed
+	if len(repo.products) != 1 {
+		t.Errorf("Expected 1 product, got %d", len(repo.products))
+	}
+	all, _ := repo.GetAll()
+	if len(all) != 0 {
+		t.Errorf("Expected 0 products, got %d", len(all))
+	}
+	archived, _ := repo.GetArchived()
+	if len(archived) != 1 {
+		t.Errorf("Expected 1 archived product, got %d", len(archived))
+	}
+	if _, err := repo.GetByID(existingProd.GetID()); err != nil {
+		t.Errorf("Expected archived product to be found by id, got %v", err)
+	}
+
+	if err := repo.Delete(uuid.New()); err != product.ErrProductNotFound {
+		t.Errorf("Expected error %v, got %v", product.ErrProductNotFound, err)
+	}
+}
+
+func TestMemoryProductRepository_Restore(t *testing.T) {
+	repo := New()
+	existingProd, err := aggregate.NewProduct("Beer", "Good for you're health", 1.99)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.Add(existingProd)
+
+	if err := repo.Delete(existingProd.GetID()); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Restore(existingProd.GetID()); err != nil {
+		t.Fatal(err)
+	}
+	all, _ := repo.GetAll()
+	if len(all) != 1 {
+		t.Errorf("Expected 1 product, got %d", len(all))
+	}
+}
+
+func TestMemoryProductRepository_GetByIDs(t *testing.T) {
+	repo := New()
+	beer, err := aggregate.NewProduct("Beer", "Good for your health", 1.99)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wine, err := aggregate.NewProduct("Wine", "Good for your health", 2.99)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.Add(beer)
+	repo.Add(wine)
+
+	missingA, missingB := uuid.New(), uuid.New()
+
+	type testCase struct {
+		name        string
+		ids         []uuid.UUID
+		expectedLen int
+		expectedErr error
+		missing     []uuid.UUID
+	}
+
+	testCases := []testCase{
+		{
+			name:        "All products found",
+			ids:         []uuid.UUID{beer.GetID(), wine.GetID(), beer.GetID()},
+			expectedLen: 3,
+			expectedErr: nil,
+		},
+		{
+			name:        "Missing products are all reported",
+			ids:         []uuid.UUID{missingA, beer.GetID(), missingB, missingA},
+			expectedErr: product.ErrProductNotFound,
+			missing:     []uuid.UUID{missingA, missingB},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			products, err := repo.GetByIDs(tc.ids)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+			if err != nil {
+				var merr *product.MissingProductsError
+				if !errors.As(err, &merr) {
+					t.Fatalf("Expected MissingProductsError, got %T", err)
+				}
+				if len(merr.IDs) != len(tc.missing) {
+					t.Fatalf("Expected %d missing ids, got %v", len(tc.missing), merr.IDs)
+				}
+				for i, id := range tc.missing {
+					if merr.IDs[i] != id {
+						t.Errorf("Expected missing id %v, got %v", id, merr.IDs[i])
+					}
+				}
+				return
+			}
+			if len(products) != tc.expectedLen {
+				t.Errorf("Expected %d products, got %d", tc.expectedLen, len(products))
+			}
+		})
 	}
 }
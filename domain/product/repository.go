@@ -3,6 +3,8 @@ package product
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 	"taverne/aggregate"
 
 	"github.com/google/uuid"
@@ -16,10 +18,39 @@ var (
 )
 
 // ProductRepository is the repository interface to fulfill the use the product aggregate
+// Products are never removed, so historic orders can still look them up by ID
 type ProductRepository interface {
+	// GetAll returns all products which are not archived
 	GetAll() ([]aggregate.Product, error)
+	// GetArchived returns all archived products
+	GetArchived() ([]aggregate.Product, error)
+	// GetByID returns a product even if it is archived
 	GetByID(id uuid.UUID) (aggregate.Product, error)
+	// GetByIDs returns the products in the order of the given ids.
+	// If any id is unknown a *MissingProductsError listing all of them is returned
+	GetByIDs(ids []uuid.UUID) ([]aggregate.Product, error)
 	Add(product aggregate.Product) error
 	Update(product aggregate.Product) error
+	// Delete archives a product, it can be brought back with Restore
 	Delete(id uuid.UUID) error
+	// Restore puts an archived product back
+	Restore(id uuid.UUID) error
+}
+
+// MissingProductsError is returned by GetByIDs when one or more products are not found.
+// It matches ErrProductNotFound with errors.Is
+type MissingProductsError struct {
+	IDs []uuid.UUID
+}
+
+func (e *MissingProductsError) Error() string {
+	ids := make([]string, 0, len(e.IDs))
+	for _, id := range e.IDs {
+		ids = append(ids, id.String())
+	}
+	return fmt.Sprintf("%v: %s", ErrProductNotFound, strings.Join(ids, ", "))
+}
+
+func (e *MissingProductsError) Unwrap() error {
+	return ErrProductNotFound
 }
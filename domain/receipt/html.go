@@ -0,0 +1,66 @@
+package receipt
+
+import (
+	"html/template"
+	"io"
+)
+
+// DefaultHTMLTemplate is the HTML receipt, it can use the functions money, date and neg
+const DefaultHTMLTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{if .CreditNote}}Credit note{{else}}Receipt{{end}} {{.Number}}</title>
+</head>
+<body>
+{{if .CreditNote}}<h1>Credit note</h1>
+<p>Credit note {{.Number}} for invoice {{.InvoiceNumber}}<br>{{date .Issued}}{{if .Reason}}<br>{{.Reason}}{{end}}</p>
+{{else}}<h1>Receipt</h1>
+<p>Invoice {{.Number}}<br>{{date .Issued}}</p>
+{{end}}
+<table>
+<thead><tr><th>Qty</th><th>Item</th><th>Price</th><th>Total</th></tr></thead>
+<tbody>
+{{range .Lines}}<tr><td>{{if .Quantity}}{{.Quantity}}{{end}}</td><td>{{.Name}}{{if .Details}}<br><small>{{.Details}}</small>{{end}}</td><td>{{if .Quantity}}{{money .UnitPrice}}{{end}}</td><td>{{money .Total}}</td></tr>
+{{end}}</tbody>
+<tfoot>
+<tr><td colspan="3">Subtotal</td><td>{{money .Subtotal}}</td></tr>
+{{range .Discounts}}<tr><td colspan="3">{{.Description}}</td><td>{{money (neg .Amount)}}</td></tr>
+{{end}}<tr><th colspan="3">Total</th><th>{{money .Total}}</th></tr>
+</tfoot>
+</table>
+<table>
+<thead><tr><th>VAT</th><th>Net</th><th>Tax</th><th>Gross</th></tr></thead>
+<tbody>
+{{range .Tax.Rates}}<tr><td>{{.Rate}}%</td><td>{{money .Net}}</td><td>{{money .Tax}}</td><td>{{money .Gross}}</td></tr>
+{{end}}</tbody>
+</table>
+</body>
+</html>
+`
+
+// HTMLRenderer renders receipts as an HTML page
+type HTMLRenderer struct {
+	tmpl *template.Template
+}
+
+// NewHTMLRenderer parses an HTML template for receipts, an empty template uses DefaultHTMLTemplate
+func NewHTMLRenderer(tmpl string) (*HTMLRenderer, error) {
+	if tmpl == "" {
+		tmpl = DefaultHTMLTemplate
+	}
+	t, err := template.New("receipt").Funcs(template.FuncMap{
+		"money": money,
+		"date":  formatDate,
+		"neg":   func(amount float64) float64 { return -amount },
+	}).Parse(tmpl)
+	if err != nil {
+		return nil, err
+	}
+	return &HTMLRenderer{tmpl: t}, nil
+}
+
+// Render writes the receipt as HTML, all texts of the receipt are escaped
+func (hr *HTMLRenderer) Render(w io.Writer, r Receipt) error {
+	return hr.tmpl.Execute(w, r)
+}
@@ -0,0 +1,115 @@
+package receipt
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// pdfFontSize is the size of the monospaced font in points
+	pdfFontSize = 9.0
+	// pdfCharWidth is the width of a Courier character in points
+	pdfCharWidth = pdfFontSize * 0.6
+	// pdfLeading is the distance between two lines in points
+	pdfLeading = pdfFontSize * 1.25
+	// pdfMargin is the white space around the receipt in points
+	pdfMargin = 18.0
+)
+
+// PDFRenderer renders receipts as a single page PDF which looks like the printed text receipt
+// The PDF is written by hand with the standard Courier font, so no font has to be embedded
+type PDFRenderer struct {
+	text *TextRenderer
+}
+
+// NewPDFRenderer creates a PDFRenderer which lays out the receipt with the text renderer
+func NewPDFRenderer(text *TextRenderer) *PDFRenderer {
+	return &PDFRenderer{text: text}
+}
+
+// Render writes the receipt as PDF
+func (pr *PDFRenderer) Render(w io.Writer, r Receipt) error {
+	var text bytes.Buffer
+	if err := pr.text.Render(&text, r); err != nil {
+		return err
+	}
+	lines := strings.Split(strings.TrimRight(text.String(), "\n"), "\n")
+
+	// the page is as long as the receipt, just like the paper of a thermal printer
+	width := 2*pdfMargin + float64(pr.text.width)*pdfCharWidth
+	height := 2*pdfMargin + float64(len(lines))*pdfLeading
+
+	var content bytes.Buffer
+	fmt.Fprintf(&content, "BT\n/F1 %.1f Tf\n%.2f TL\n%.2f %.2f Td\n", pdfFontSize, pdfLeading, pdfMargin, height-pdfMargin-pdfFontSize)
+	for _, l := range lines {
+		fmt.Fprintf(&content, "(%s) Tj T*\n", pdfString(l))
+	}
+	content.WriteString("ET\n")
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 4 0 R >> >> /Contents 5 0 R >>", width, height),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+	}
+
+	bw := bufio.NewWriter(w)
+	cw := &countingWriter{w: bw}
+	fmt.Fprint(cw, "%PDF-1.4\n")
+	offsets := make([]int, 0, len(objects))
+	for i, obj := range objects {
+		offsets = append(offsets, cw.n)
+		fmt.Fprintf(cw, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := cw.n
+	fmt.Fprintf(cw, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(cw, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(cw, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	if cw.err != nil {
+		return cw.err
+	}
+	return bw.Flush()
+}
+
+// pdfString escapes a line for a PDF string in WinAnsi encoding,
+// characters outside of Latin-1 are replaced by a question mark
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// countingWriter counts the written bytes for the cross reference table of the PDF
+type countingWriter struct {
+	w   io.Writer
+	n   int
+	err error
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	n, err := cw.w.Write(p)
+	cw.n += n
+	cw.err = err
+	return n, err
+}
@@ -0,0 +1,147 @@
+// Package receipt renders the receipt of a billed order as plain text, HTML or PDF
+package receipt
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"taverne/aggregate"
+	"taverne/valueobject"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrNotInvoiced is returned when a receipt is requested for an order without an invoice number
+	ErrNotInvoiced = errors.New("the order has no invoice number yet")
+)
+
+// Receipt is everything printed on the receipt of an order
+type Receipt struct {
+	// Number is the invoice number of the order
+	Number     string
+	Issued     time.Time
+	OrderID    uuid.UUID
+	CustomerID uuid.UUID
+	Lines      []Line
+	Discounts  []valueobject.Discount
+	// Subtotal is the sum of all lines before discounts, Total what the customer paid
+	Subtotal float64
+	Total    float64
+	Tax      valueobject.TaxBreakdown
+	// CreditNote is true for a credit note, all amounts of a credit note are negative
+	CreditNote bool
+	// InvoiceNumber is the number of the invoice a credit note corrects
+	InvoiceNumber string
+	Reason        string
+}
+
+// Line is an ordered item on the receipt
+type Line struct {
+	Name string
+	// Details are the variant and modifiers, such as "0.5l, extra lime"
+	Details string
+	// Quantity is zero for a price reduction of an item which was not handed back,
+	// the line is rendered without quantity and unit price
+	Quantity  int
+	UnitPrice float64
+	Total     float64
+}
+
+// Renderer writes a receipt in one format
+type Renderer interface {
+	Render(w io.Writer, r Receipt) error
+}
+
+// New builds the receipt of a billed order
+// will return ErrNotInvoiced if the order has no invoice number
+func New(ord aggregate.Order) (Receipt, error) {
+	if ord.GetInvoiceNumber() == "" {
+		return Receipt{}, ErrNotInvoiced
+	}
+
+	r := Receipt{
+		Number:     ord.GetInvoiceNumber(),
+		Issued:     ord.GetPlacedAt(),
+		OrderID:    ord.GetID(),
+		CustomerID: ord.GetCustomerID(),
+		Discounts:  ord.GetDiscounts(),
+		Subtotal:   ord.Subtotal(),
+		Total:      ord.Total(),
+		Tax:        ord.GetTaxBreakdown(),
+	}
+	for _, item := range ord.GetItems() {
+		details := item.Modifiers
+		if item.Variant != "" {
+			details = append([]string{item.Variant}, details...)
+		}
+		r.Lines = append(r.Lines, Line{
+			Name:      item.Name,
+			Details:   strings.Join(details, ", "),
+			Quantity:  item.Quantity,
+			UnitPrice: item.UnitPrice,
+			Total:     item.Total(),
+		})
+	}
+	return r, nil
+}
+
+// NewCreditNote builds the credit note of a refund of a billed order
+// The tax is the breakdown of the refunded amount, it is negated like all other amounts
+func NewCreditNote(ord aggregate.Order, refund valueobject.Refund, number string, tax valueobject.TaxBreakdown) (Receipt, error) {
+	if ord.GetInvoiceNumber() == "" {
+		return Receipt{}, ErrNotInvoiced
+	}
+
+	r := Receipt{
+		Number:        number,
+		Issued:        refund.CreatedAt,
+		OrderID:       ord.GetID(),
+		CustomerID:    ord.GetCustomerID(),
+		Subtotal:      -refund.Amount,
+		Total:         -refund.Amount,
+		Tax:           negate(tax),
+		CreditNote:    true,
+		InvoiceNumber: ord.GetInvoiceNumber(),
+		Reason:        refund.Reason,
+	}
+	returned := make(map[int]int)
+	for _, ri := range refund.Returned {
+		returned[ri.Item] += ri.Quantity
+	}
+	items := ord.GetItems()
+	for i, amount := range refund.Amounts {
+		if amount == 0 {
+			continue
+		}
+		line := Line{
+			Name:      items[i].Name,
+			Quantity:  returned[i],
+			UnitPrice: items[i].UnitPrice,
+			Total:     -amount,
+		}
+		if returned[i] == 0 {
+			// only part of the price is paid back, nothing was handed back
+			line.Details = "price reduction"
+		}
+		r.Lines = append(r.Lines, line)
+	}
+	return r, nil
+}
+
+// negate turns the tax breakdown of a refund into the one of a credit note
+func negate(b valueobject.TaxBreakdown) valueobject.TaxBreakdown {
+	n := valueobject.TaxBreakdown{Net: -b.Net, Tax: -b.Tax, Gross: -b.Gross}
+	for _, r := range b.Rates {
+		n.Rates = append(n.Rates, valueobject.TaxRate{Rate: r.Rate, Net: -r.Net, Tax: -r.Tax, Gross: -r.Gross})
+	}
+	return n
+}
+
+// money formats an amount with two decimals, so no cent is lost on the receipt
+func money(amount float64) string {
+	return fmt.Sprintf("%.2f", amount)
+}
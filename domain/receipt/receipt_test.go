@@ -0,0 +1,201 @@
+package receipt_test
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+	"taverne/aggregate"
+	"taverne/domain/receipt"
+	"taverne/valueobject"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func newReceipt(t *testing.T) receipt.Receipt {
+	ord, err := aggregate.NewOrder(uuid.New(), []valueobject.OrderItem{
+		{Name: "Beer", Variant: "0.5l", Modifiers: []string{"lime"}, Quantity: 2, UnitPrice: 3.5},
+		{Name: "Fish & Chips", Quantity: 1, UnitPrice: 9.9, TaxCategory: valueobject.TaxFood},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ord.ApplyDiscounts(valueobject.Discount{Description: "Happy hour", Amount: 0.9}); err != nil {
+		t.Fatal(err)
+	}
+	ord.SetTaxBreakdown(valueobject.TaxBreakdown{
+		Net: 13.89, Tax: 2.11, Gross: 16.0,
+		Rates: []valueobject.TaxRate{
+			{Rate: 7, Net: 8.41, Tax: 0.59, Gross: 9.0},
+			{Rate: 19, Net: 5.48, Tax: 1.04, Gross: 6.52},
+		},
+	})
+
+	if _, err := receipt.New(ord); err != receipt.ErrNotInvoiced {
+		t.Errorf("Expected error %v, got %v", receipt.ErrNotInvoiced, err)
+	}
+	if err := ord.SetInvoiceNumber("000042"); err != nil {
+		t.Fatal(err)
+	}
+	r, err := receipt.New(ord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestReceipt_Text(t *testing.T) {
+	tr, err := receipt.NewTextRenderer(32, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := tr.Render(&buf, newReceipt(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(buf.String(), "\n")
+	for _, l := range lines {
+		if len([]rune(l)) > 32 {
+			t.Errorf("Expected lines of at most 32 characters, got %q", l)
+		}
+	}
+	for _, expected := range []string{
+		"Invoice                   000042",
+		"2 x Beer                    7.00",
+		"    0.5l, lime",
+		"    @ 3.50",
+		"Happy hour                 -0.90",
+		"TOTAL                      16.00",
+		"VAT 7% on 8.41              0.59",
+	} {
+		if !strings.Contains(buf.String(), expected+"\n") {
+			t.Errorf("Expected line %q in\n%s", expected, buf.String())
+		}
+	}
+}
+
+func TestReceipt_CustomTemplate(t *testing.T) {
+	tr, err := receipt.NewTextRenderer(0, `{{.Number}} {{money .Total}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := tr.Render(&buf, newReceipt(t)); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "000042 16.00" {
+		t.Errorf("Expected 000042 16.00, got %q", buf.String())
+	}
+
+	if _, err := receipt.NewHTMLRenderer(`{{.Number`); err == nil {
+		t.Error("Expected an error for a broken template")
+	}
+}
+
+func TestReceipt_HTML(t *testing.T) {
+	hr, err := receipt.NewHTMLRenderer("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := hr.Render(&buf, newReceipt(t)); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "Fish &amp; Chips") {
+		t.Errorf("Expected the item name to be escaped, got\n%s", buf.String())
+	}
+	if !strings.Contains(buf.String(), "<th>16.00</th>") {
+		t.Errorf("Expected the total 16.00, got\n%s", buf.String())
+	}
+}
+
+func TestReceipt_PDF(t *testing.T) {
+	tr, err := receipt.NewTextRenderer(0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := receipt.NewPDFRenderer(tr).Render(&buf, newReceipt(t)); err != nil {
+		t.Fatal(err)
+	}
+	pdf := buf.String()
+
+	if !strings.HasPrefix(pdf, "%PDF-1.4\n") || !strings.HasSuffix(pdf, "%%EOF\n") {
+		t.Fatalf("Expected a PDF document, got\n%s", pdf)
+	}
+	if !strings.Contains(pdf, "(1 x Fish & Chips") {
+		t.Errorf("Expected the items in the content stream, got\n%s", pdf)
+	}
+
+	// startxref has to point at the cross reference table
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindStringSubmatch(pdf)
+	if m == nil {
+		t.Fatal("Expected startxref")
+	}
+	offset, _ := strconv.Atoi(m[1])
+	if !strings.HasPrefix(pdf[offset:], "xref\n") {
+		t.Errorf("Expected xref at offset %d, got %q", offset, pdf[offset:offset+10])
+	}
+}
+
+func TestReceipt_CreditNote(t *testing.T) {
+	ord, err := aggregate.NewOrder(uuid.New(), []valueobject.OrderItem{
+		{Name: "Beer", Quantity: 2, UnitPrice: 3.5},
+		{Name: "Fish & Chips", Quantity: 1, UnitPrice: 9.9, TaxCategory: valueobject.TaxFood},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ord.SetInvoiceNumber("000042"); err != nil {
+		t.Fatal(err)
+	}
+	// one beer is handed back and the cold fish is reduced in price
+	refund := valueobject.Refund{
+		ID:       uuid.New(),
+		Amount:   6.5,
+		Amounts:  []float64{3.5, 3},
+		Returned: []valueobject.ReturnedItem{{Item: 0, Quantity: 1}},
+		Reason:   "Cold fish",
+	}
+	r, err := receipt.NewCreditNote(ord, refund, "000043", valueobject.TaxBreakdown{Gross: 6.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr, err := receipt.NewTextRenderer(32, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := tr.Render(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"For invoice               000042",
+		"1 x Beer                   -3.50",
+		"Fish & Chips               -3.00",
+		"    price reduction",
+		"TOTAL                      -6.50",
+	} {
+		if !strings.Contains(buf.String(), expected+"\n") {
+			t.Errorf("Expected line %q in\n%s", expected, buf.String())
+		}
+	}
+	if strings.Contains(buf.String(), "0 x") {
+		t.Errorf("Expected the price reduction without quantity, got\n%s", buf.String())
+	}
+
+	hr, err := receipt.NewHTMLRenderer("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := hr.Render(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "<tr><td></td><td>Fish &amp; Chips<br><small>price reduction</small></td><td></td><td>-3.00</td></tr>") {
+		t.Errorf("Expected the price reduction without quantity and unit price, got\n%s", buf.String())
+	}
+}
@@ -0,0 +1,107 @@
+package receipt
+
+import (
+	"io"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultWidth is the number of characters of a line on an 80mm thermal printer
+const DefaultWidth = 42
+
+// DefaultTextTemplate is the plain text receipt for thermal printers.
+// Besides the functions of text/template it can use
+//
+//	money   formats an amount with two decimals
+//	date    formats a time as date and time of day
+//	neg     negates an amount
+//	center  centers a text on the line
+//	pair    prints a text on the left and one on the right of the line
+//	rule    prints a line of dashes
+const DefaultTextTemplate = `{{if .CreditNote}}{{center "CREDIT NOTE"}}
+{{pair "Credit note" .Number}}
+{{pair "For invoice" .InvoiceNumber}}
+{{if .Reason}}{{.Reason}}
+{{end}}{{else}}{{center "RECEIPT"}}
+{{pair "Invoice" .Number}}
+{{end}}{{pair "Date" (date .Issued)}}
+{{rule}}
+{{range .Lines}}{{if .Quantity}}{{pair (printf "%d x %s" .Quantity .Name) (money .Total)}}{{else}}{{pair .Name (money .Total)}}{{end}}
+{{if .Details}}    {{.Details}}
+{{end}}{{if gt .Quantity 1}}    @ {{money .UnitPrice}}
+{{end}}{{end}}{{rule}}
+{{pair "Subtotal" (money .Subtotal)}}
+{{range .Discounts}}{{pair .Description (money (neg .Amount))}}
+{{end}}{{pair "TOTAL" (money .Total)}}
+{{rule}}
+{{range .Tax.Rates}}{{pair (printf "VAT %g%% on %s" .Rate (money .Net)) (money .Tax)}}
+{{end}}{{pair "Net" (money .Tax.Net)}}
+{{center "Thank you for your visit"}}
+`
+
+// TextRenderer renders receipts as plain text of a fixed width
+type TextRenderer struct {
+	width int
+	tmpl  *template.Template
+}
+
+// NewTextRenderer parses a text template for receipts of the given width,
+// an empty template uses DefaultTextTemplate and a width below one DefaultWidth
+func NewTextRenderer(width int, tmpl string) (*TextRenderer, error) {
+	if width < 1 {
+		width = DefaultWidth
+	}
+	if tmpl == "" {
+		tmpl = DefaultTextTemplate
+	}
+	tr := &TextRenderer{width: width}
+	t, err := template.New("receipt").Funcs(tr.funcs()).Parse(tmpl)
+	if err != nil {
+		return nil, err
+	}
+	tr.tmpl = t
+	return tr, nil
+}
+
+// Render writes the receipt as plain text
+func (tr *TextRenderer) Render(w io.Writer, r Receipt) error {
+	return tr.tmpl.Execute(w, r)
+}
+
+// funcs are the layout functions of the template, they know the width of the receipt
+func (tr *TextRenderer) funcs() template.FuncMap {
+	return template.FuncMap{
+		"money": money,
+		"date":  formatDate,
+		"neg":   func(amount float64) float64 { return -amount },
+		"rule":  func() string { return strings.Repeat("-", tr.width) },
+		"center": func(s string) string {
+			s = truncate(s, tr.width)
+			return strings.Repeat(" ", (tr.width-utf8.RuneCountInString(s))/2) + s
+		},
+		"pair": func(left, right string) string {
+			// the right side, usually an amount, is never cut
+			left = truncate(left, tr.width-utf8.RuneCountInString(right)-1)
+			gap := tr.width - utf8.RuneCountInString(left) - utf8.RuneCountInString(right)
+			return left + strings.Repeat(" ", max(gap, 1)) + right
+		},
+	}
+}
+
+// formatDate formats the issue date of a receipt
+func formatDate(t time.Time) string {
+	return t.Format("2006-01-02 15:04")
+}
+
+// truncate cuts s to at most n characters
+func truncate(s string, n int) string {
+	if n < 0 {
+		return ""
+	}
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
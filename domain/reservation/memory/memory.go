@@ -0,0 +1,76 @@
+// Package memory is a in memory implementation of the ReservationRepository interface
+package memory
+
+import (
+	"sort"
+	"sync"
+	"taverne/aggregate"
+	"taverne/domain/reservation"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type MemoryReservationRepository struct {
+	reservations map[uuid.UUID]aggregate.Reservation
+	sync.Mutex
+}
+
+// New is a factory function to generate a new repository of reservations
+func New() *MemoryReservationRepository {
+	return &MemoryReservationRepository{
+		reservations: make(map[uuid.UUID]aggregate.Reservation),
+	}
+}
+
+// Get finds a reservation by ID
+func (mrr *MemoryReservationRepository) Get(id uuid.UUID) (aggregate.Reservation, error) {
+	mrr.Lock()
+	defer mrr.Unlock()
+
+	if r, ok := mrr.reservations[id]; ok {
+		return r, nil
+	}
+	return aggregate.Reservation{}, reservation.ErrReservationNotFound
+}
+
+// GetBetween finds the reservations overlapping from to to
+func (mrr *MemoryReservationRepository) GetBetween(from, to time.Time) ([]aggregate.Reservation, error) {
+	mrr.Lock()
+	defer mrr.Unlock()
+
+	var reservations []aggregate.Reservation
+	for _, r := range mrr.reservations {
+		if r.Overlaps(from, to) {
+			reservations = append(reservations, r)
+		}
+	}
+	sort.Slice(reservations, func(i, j int) bool {
+		return reservations[i].GetStart().Before(reservations[j].GetStart())
+	})
+	return reservations, nil
+}
+
+// Add will add a new reservation to the repository
+func (mrr *MemoryReservationRepository) Add(r aggregate.Reservation) error {
+	mrr.Lock()
+	defer mrr.Unlock()
+
+	if _, ok := mrr.reservations[r.GetID()]; ok {
+		return reservation.ErrReservationAlreadyExist
+	}
+	mrr.reservations[r.GetID()] = r
+	return nil
+}
+
+// Update will replace an existing reservation
+func (mrr *MemoryReservationRepository) Update(r aggregate.Reservation) error {
+	mrr.Lock()
+	defer mrr.Unlock()
+
+	if _, ok := mrr.reservations[r.GetID()]; !ok {
+		return reservation.ErrReservationNotFound
+	}
+	mrr.reservations[r.GetID()] = r
+	return nil
+}
@@ -0,0 +1,72 @@
+package memory
+
+import (
+	"taverne/aggregate"
+	"taverne/domain/reservation"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestMemoryReservationRepository_GetBetween(t *testing.T) {
+	repo := New()
+	evening := time.Date(2024, time.June, 7, 18, 0, 0, 0, time.UTC)
+	for _, start := range []time.Time{evening.Add(2 * time.Hour), evening} {
+		r, err := aggregate.NewReservation(uuid.New(), 2, start, start.Add(2*time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.Add(r); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.Add(r); err != reservation.ErrReservationAlreadyExist {
+			t.Errorf("Expected error %v, got %v", reservation.ErrReservationAlreadyExist, err)
+		}
+	}
+
+	type testCase struct {
+		name     string
+		from     time.Time
+		to       time.Time
+		expected int
+	}
+
+	testCases := []testCase{
+		{
+			name:     "Whole evening",
+			from:     evening,
+			to:       evening.Add(6 * time.Hour),
+			expected: 2,
+		},
+		{
+			name:     "Slot ending when the later one starts",
+			from:     evening.Add(time.Hour),
+			to:       evening.Add(2 * time.Hour),
+			expected: 1,
+		},
+		{
+			name:     "Afternoon",
+			from:     evening.Add(-4 * time.Hour),
+			to:       evening,
+			expected: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			found, err := repo.GetBetween(tc.from, tc.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(found) != tc.expected {
+				t.Fatalf("Expected %d reservations, got %d", tc.expected, len(found))
+			}
+			for i := 1; i < len(found); i++ {
+				if found[i].GetStart().Before(found[i-1].GetStart()) {
+					t.Errorf("Expected the earliest reservation first, got %v", found)
+				}
+			}
+		})
+	}
+}
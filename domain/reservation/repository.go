@@ -0,0 +1,26 @@
+// Package reservation holds the repository and the implementations for a ReservationRepository
+package reservation
+
+import (
+	"errors"
+	"taverne/aggregate"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrReservationNotFound is returned when a reservation is not found
+	ErrReservationNotFound = errors.New("the reservation was not found")
+	// ErrReservationAlreadyExist is returned when trying to add a reservation that already exists
+	ErrReservationAlreadyExist = errors.New("the reservation already exists")
+)
+
+// ReservationRepository is the repository interface to fulfill to persist the reservation aggregate
+type ReservationRepository interface {
+	Get(id uuid.UUID) (aggregate.Reservation, error)
+	// GetBetween returns the reservations of every status whose slot overlaps from to to, the earliest first
+	GetBetween(from, to time.Time) ([]aggregate.Reservation, error)
+	Add(reservation aggregate.Reservation) error
+	Update(reservation aggregate.Reservation) error
+}
@@ -0,0 +1,152 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"taverne/aggregate"
+	"taverne/domain/reservation"
+	"time"
+
+	"github.com/google/uuid"
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+type SqliteRepository struct {
+	db *sql.DB
+}
+
+// Create a new sqlite repository
+func New(ctx context.Context, connectionString string) (*SqliteRepository, error) {
+	db, err := sql.Open("sqlite3", connectionString)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.ExecContext(ctx,
+		`CREATE TABLE IF NOT EXISTS reservation (
+			id TEXT PRIMARY KEY,
+			customer_id TEXT NOT NULL,
+			party_size INT NOT NULL,
+			table_id TEXT NOT NULL,
+			start_at TIMESTAMP NOT NULL,
+			end_at TIMESTAMP NOT NULL,
+			status TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL
+		)`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error creating table reservation, got %v", err)
+	}
+
+	return &SqliteRepository{
+		db: db,
+	}, nil
+}
+
+// sqliteReservation is an internal type that is used to scan a stored reservation
+type sqliteReservation struct {
+	ID        uuid.UUID
+	Customer  uuid.UUID
+	PartySize int
+	Table     uuid.UUID
+	Start     time.Time
+	End       time.Time
+	Status    string
+	CreatedAt time.Time
+}
+
+// scan reads a row in the order of the columns of columns
+func (s *sqliteReservation) scan(row interface{ Scan(...any) error }) error {
+	return row.Scan(&s.ID, &s.Customer, &s.PartySize, &s.Table, &s.Start, &s.End, &s.Status, &s.CreatedAt)
+}
+
+const columns = `id, customer_id, party_size, table_id, start_at, end_at, status, created_at`
+
+// ToAggregate converts into a aggregate.Reservation in the local time zone
+func (s sqliteReservation) ToAggregate() aggregate.Reservation {
+	return aggregate.RestoreReservation(
+		s.ID, s.Customer, s.Table, s.PartySize, s.Start.Local(), s.End.Local(),
+		aggregate.ReservationStatus(s.Status), s.CreatedAt.Local(),
+	)
+}
+
+func (sr *SqliteRepository) Get(id uuid.UUID) (aggregate.Reservation, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var s sqliteReservation
+	err := s.scan(sr.db.QueryRowContext(ctx, `SELECT `+columns+` FROM reservation WHERE id = ?`, id.String()))
+	if errors.Is(err, sql.ErrNoRows) {
+		return aggregate.Reservation{}, reservation.ErrReservationNotFound
+	}
+	if err != nil {
+		return aggregate.Reservation{}, err
+	}
+	return s.ToAggregate(), nil
+}
+
+// GetBetween finds the reservations overlapping from to to
+// All times are stored in UTC, so they compare in the order they happened
+func (sr *SqliteRepository) GetBetween(from, to time.Time) ([]aggregate.Reservation, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	rows, err := sr.db.QueryContext(ctx,
+		`SELECT `+columns+` FROM reservation WHERE start_at < ? AND end_at > ? ORDER BY start_at`,
+		to.UTC(), from.UTC(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reservations []aggregate.Reservation
+	for rows.Next() {
+		var s sqliteReservation
+		if err := s.scan(rows); err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, s.ToAggregate())
+	}
+	return reservations, rows.Err()
+}
+
+func (sr *SqliteRepository) Add(r aggregate.Reservation) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := sr.db.ExecContext(ctx,
+		`INSERT INTO reservation (`+columns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.GetID().String(), r.GetCustomerID().String(), r.GetPartySize(), r.GetTableID().String(),
+		r.GetStart().UTC(), r.GetEnd().UTC(), string(r.GetStatus()), r.GetCreatedAt().UTC(),
+	)
+	var serr sqlite3.Error
+	if errors.As(err, &serr) && serr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey {
+		return reservation.ErrReservationAlreadyExist
+	}
+	return err
+}
+
+func (sr *SqliteRepository) Update(r aggregate.Reservation) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	res, err := sr.db.ExecContext(ctx,
+		`UPDATE reservation SET party_size = ?, table_id = ?, start_at = ?, end_at = ?, status = ? WHERE id = ?`,
+		r.GetPartySize(), r.GetTableID().String(), r.GetStart().UTC(), r.GetEnd().UTC(), string(r.GetStatus()),
+		r.GetID().String(),
+	)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return reservation.ErrReservationNotFound
+	}
+	return nil
+}
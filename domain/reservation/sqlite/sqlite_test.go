@@ -0,0 +1,71 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"taverne/aggregate"
+	"taverne/domain/reservation"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestSqliteRepository_Reservations(t *testing.T) {
+	repo, err := New(context.Background(), filepath.Join(t.TempDir(), "reservation.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, time.June, 7, 19, 0, 0, 0, time.UTC)
+
+	r, err := aggregate.NewReservation(uuid.New(), 4, start, start.Add(2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.AssignTable(uuid.New()); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Add(r); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Add(r); err != reservation.ErrReservationAlreadyExist {
+		t.Errorf("Expected error %v, got %v", reservation.ErrReservationAlreadyExist, err)
+	}
+
+	if err := r.Cancel(); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Update(r); err != nil {
+		t.Fatal(err)
+	}
+	found, err := repo.Get(r.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.GetStatus() != aggregate.ReservationCancelled || found.GetTableID() != r.GetTableID() ||
+		found.GetPartySize() != 4 || !found.GetStart().Equal(start) {
+		t.Errorf("Expected %v, got %v", r, found)
+	}
+
+	between, err := repo.GetBetween(start.Add(time.Hour), start.Add(3*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(between) != 1 {
+		t.Errorf("Expected 1 reservation, got %d", len(between))
+	}
+	between, err = repo.GetBetween(start.Add(2*time.Hour), start.Add(3*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(between) != 0 {
+		t.Errorf("Expected no reservation after the slot, got %d", len(between))
+	}
+
+	if _, err := repo.Get(uuid.New()); err != reservation.ErrReservationNotFound {
+		t.Errorf("Expected error %v, got %v", reservation.ErrReservationNotFound, err)
+	}
+	if err := repo.Update(aggregate.Reservation{}); err != reservation.ErrReservationNotFound {
+		t.Errorf("Expected error %v, got %v", reservation.ErrReservationNotFound, err)
+	}
+}
@@ -0,0 +1,78 @@
+// Package memory is a in memory implementation of the TabRepository interface
+package memory
+
+import (
+	"sync"
+	"taverne/aggregate"
+	"taverne/domain/tab"
+
+	"github.com/google/uuid"
+)
+
+type MemoryTabRepository struct {
+	tabs map[uuid.UUID]aggregate.Tab
+	sync.Mutex
+}
+
+// New is a factory function to generate a new repository of tabs
+func New() *MemoryTabRepository {
+	return &MemoryTabRepository{
+		tabs: make(map[uuid.UUID]aggregate.Tab),
+	}
+}
+
+// Get finds a tab by ID
+func (mtr *MemoryTabRepository) Get(id uuid.UUID) (aggregate.Tab, error) {
+	mtr.Lock()
+	defer mtr.Unlock()
+
+	if t, ok := mtr.tabs[id]; ok {
+		return t, nil
+	}
+	return aggregate.Tab{}, tab.ErrTabNotFound
+}
+
+// GetOpen finds the open tab of a customer
+func (mtr *MemoryTabRepository) GetOpen(customer uuid.UUID) (aggregate.Tab, error) {
+	mtr.Lock()
+	defer mtr.Unlock()
+
+	return mtr.open(customer)
+}
+
+// Add will add a new tab to the repository
+func (mtr *MemoryTabRepository) Add(t aggregate.Tab) error {
+	mtr.Lock()
+	defer mtr.Unlock()
+
+	if _, ok := mtr.tabs[t.GetID()]; ok {
+		return tab.ErrTabAlreadyExist
+	}
+	if _, err := mtr.open(t.GetCustomerID()); err == nil && t.IsOpen() {
+		return tab.ErrTabAlreadyOpen
+	}
+	mtr.tabs[t.GetID()] = t
+	return nil
+}
+
+// Update will replace an existing tab
+func (mtr *MemoryTabRepository) Update(t aggregate.Tab) error {
+	mtr.Lock()
+	defer mtr.Unlock()
+
+	if _, ok := mtr.tabs[t.GetID()]; !ok {
+		return tab.ErrTabNotFound
+	}
+	mtr.tabs[t.GetID()] = t
+	return nil
+}
+
+// open expects the lock to be held
+func (mtr *MemoryTabRepository) open(customer uuid.UUID) (aggregate.Tab, error) {
+	for _, t := range mtr.tabs {
+		if t.GetCustomerID() == customer && t.IsOpen() {
+			return t, nil
+		}
+	}
+	return aggregate.Tab{}, tab.ErrTabNotFound
+}
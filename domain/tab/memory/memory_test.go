@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"taverne/aggregate"
+	"taverne/domain/tab"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestMemoryTabRepository_Add(t *testing.T) {
+	repo := New()
+	customer := uuid.New()
+
+	first := aggregate.NewTab(customer)
+	if err := repo.Add(first); err != nil {
+		t.Fatal(err)
+	}
+
+	// A second open tab for the same customer is not allowed
+	if err := repo.Add(aggregate.NewTab(customer)); err != tab.ErrTabAlreadyOpen {
+		t.Errorf("Expected error %v, got %v", tab.ErrTabAlreadyOpen, err)
+	}
+
+	// After closing the first one a new tab can be opened
+	if err := first.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Update(first); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Add(aggregate.NewTab(customer)); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
+
+func TestMemoryTabRepository_GetOpen(t *testing.T) {
+	repo := New()
+	customer := uuid.New()
+	existing := aggregate.NewTab(customer)
+	if err := repo.Add(existing); err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		name        string
+		customer    uuid.UUID
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			name:        "Open tab of customer",
+			customer:    customer,
+			expectedErr: nil,
+		},
+		{
+			name:        "Customer without tab",
+			customer:    uuid.New(),
+			expectedErr: tab.ErrTabNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			found, err := repo.GetOpen(tc.customer)
+			if err != tc.expectedErr {
+				t.Fatalf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+			if err == nil && found.GetID() != existing.GetID() {
+				t.Errorf("Expected tab %v, got %v", existing.GetID(), found.GetID())
+			}
+		})
+	}
+}
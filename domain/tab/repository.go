@@ -0,0 +1,28 @@
+// Package tab holds the repository and the implementations for a TabRepository
+package tab
+
+import (
+	"errors"
+	"taverne/aggregate"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrTabNotFound is returned when a tab is not found
+	ErrTabNotFound = errors.New("the tab was not found")
+	// ErrTabAlreadyExist is returned when trying to add a tab that already exists
+	ErrTabAlreadyExist = errors.New("the tab already exists")
+	// ErrTabAlreadyOpen is returned when a customer already has an open tab
+	ErrTabAlreadyOpen = errors.New("the customer already has an open tab")
+)
+
+// TabRepository is the repository interface to fulfill to persist the tab aggregate
+type TabRepository interface {
+	Get(id uuid.UUID) (aggregate.Tab, error)
+	// GetOpen returns the open tab of a customer or ErrTabNotFound
+	GetOpen(customer uuid.UUID) (aggregate.Tab, error)
+	// Add stores a new tab, a customer can only have one open tab at a time
+	Add(tab aggregate.Tab) error
+	Update(tab aggregate.Tab) error
+}
@@ -0,0 +1,78 @@
+// Package memory is a in memory implementation of the TableRepository interface
+package memory
+
+import (
+	"sort"
+	"sync"
+	"taverne/aggregate"
+	"taverne/domain/table"
+
+	"github.com/google/uuid"
+)
+
+type MemoryTableRepository struct {
+	tables map[uuid.UUID]aggregate.Table
+	sync.Mutex
+}
+
+// New is a factory function to generate a new repository of tables
+func New() *MemoryTableRepository {
+	return &MemoryTableRepository{
+		tables: make(map[uuid.UUID]aggregate.Table),
+	}
+}
+
+// GetAll returns all tables ordered by number
+func (mtr *MemoryTableRepository) GetAll() ([]aggregate.Table, error) {
+	mtr.Lock()
+	defer mtr.Unlock()
+
+	tables := make([]aggregate.Table, 0, len(mtr.tables))
+	for _, t := range mtr.tables {
+		tables = append(tables, t)
+	}
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].GetNumber() < tables[j].GetNumber()
+	})
+	return tables, nil
+}
+
+// Get finds a table by ID
+func (mtr *MemoryTableRepository) Get(id uuid.UUID) (aggregate.Table, error) {
+	mtr.Lock()
+	defer mtr.Unlock()
+
+	if t, ok := mtr.tables[id]; ok {
+		return t, nil
+	}
+	return aggregate.Table{}, table.ErrTableNotFound
+}
+
+// Add will add a new table to the repository
+func (mtr *MemoryTableRepository) Add(t aggregate.Table) error {
+	mtr.Lock()
+	defer mtr.Unlock()
+
+	if _, ok := mtr.tables[t.GetID()]; ok {
+		return table.ErrTableAlreadyExist
+	}
+	for _, existing := range mtr.tables {
+		if existing.GetNumber() == t.GetNumber() {
+			return table.ErrTableNumberTaken
+		}
+	}
+	mtr.tables[t.GetID()] = t
+	return nil
+}
+
+// Update will replace an existing table
+func (mtr *MemoryTableRepository) Update(t aggregate.Table) error {
+	mtr.Lock()
+	defer mtr.Unlock()
+
+	if _, ok := mtr.tables[t.GetID()]; !ok {
+		return table.ErrTableNotFound
+	}
+	mtr.tables[t.GetID()] = t
+	return nil
+}
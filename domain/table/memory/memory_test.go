@@ -0,0 +1,81 @@
+package memory
+
+import (
+	"taverne/aggregate"
+	"taverne/domain/table"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func newTestTable(t *testing.T, number int) aggregate.Table {
+	tb, err := aggregate.NewTable(number, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tb
+}
+
+func TestMemoryTableRepository_Add(t *testing.T) {
+	repo := New()
+	existing := newTestTable(t, 1)
+	if err := repo.Add(existing); err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		name        string
+		table       aggregate.Table
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			name:        "Add a new table",
+			table:       newTestTable(t, 2),
+			expectedErr: nil,
+		},
+		{
+			name:        "Add an existing table",
+			table:       existing,
+			expectedErr: table.ErrTableAlreadyExist,
+		},
+		{
+			name:        "Add a table with a taken number",
+			table:       newTestTable(t, 1),
+			expectedErr: table.ErrTableNumberTaken,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := repo.Add(tc.table)
+			if err != tc.expectedErr {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestMemoryTableRepository_GetAll(t *testing.T) {
+	repo := New()
+	for _, n := range []int{3, 1, 2} {
+		if err := repo.Add(newTestTable(t, n)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tables, err := repo.GetAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, tb := range tables {
+		if tb.GetNumber() != i+1 {
+			t.Errorf("Expected table %d at position %d, got %d", i+1, i, tb.GetNumber())
+		}
+	}
+
+	if _, err := repo.Get(uuid.New()); err != table.ErrTableNotFound {
+		t.Errorf("Expected error %v, got %v", table.ErrTableNotFound, err)
+	}
+}
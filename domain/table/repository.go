@@ -0,0 +1,28 @@
+// Package table holds the repository and the implementations for a TableRepository
+package table
+
+import (
+	"errors"
+	"taverne/aggregate"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrTableNotFound is returned when a table is not found
+	ErrTableNotFound = errors.New("the table was not found")
+	// ErrTableAlreadyExist is returned when trying to add a table that already exists
+	ErrTableAlreadyExist = errors.New("the table already exists")
+	// ErrTableNumberTaken is returned when trying to add a table with the number of another table
+	ErrTableNumberTaken = errors.New("another table has the same number")
+)
+
+// TableRepository is the repository interface to fulfill to persist the table aggregate
+type TableRepository interface {
+	// GetAll returns every table ordered by number
+	GetAll() ([]aggregate.Table, error)
+	Get(id uuid.UUID) (aggregate.Table, error)
+	// Add stores a new table, every table has its own number
+	Add(table aggregate.Table) error
+	Update(table aggregate.Table) error
+}
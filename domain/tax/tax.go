@@ -0,0 +1,104 @@
+// Package tax calculates the value added tax included in gross prices
+package tax
+
+import (
+	"math"
+	"sort"
+
+	"taverne/valueobject"
+)
+
+// Rounding decides where tax amounts are rounded to cents
+type Rounding int
+
+const (
+	// RoundPerLine rounds the tax of every line and sums up the rounded amounts
+	RoundPerLine Rounding = iota
+	// RoundPerInvoice sums up the gross per rate and rounds the tax once per rate
+	RoundPerInvoice
+)
+
+// Table maps tax categories to their rate in percent
+type Table struct {
+	// Standard is the rate of every category which has no rate of its own
+	Standard float64
+	Rates    map[valueobject.TaxCategory]float64
+}
+
+// DefaultTable returns the german rates, 7% on food and 19% on everything else
+func DefaultTable() Table {
+	return Table{
+		Standard: 19,
+		Rates: map[valueobject.TaxCategory]float64{
+			valueobject.TaxFood:     7,
+			valueobject.TaxBeverage: 19,
+		},
+	}
+}
+
+// Rate returns the rate in percent of a category
+func (t Table) Rate(c valueobject.TaxCategory) float64 {
+	if rate, ok := t.Rates[c]; ok {
+		return rate
+	}
+	return t.Standard
+}
+
+// Has reports whether the table knows a category, the standard category is always known
+func (t Table) Has(c valueobject.TaxCategory) bool {
+	_, ok := t.Rates[c]
+	return ok || c == valueobject.TaxStandard
+}
+
+// Line is a gross amount of one tax category
+type Line struct {
+	Category valueobject.TaxCategory
+	Gross    float64
+}
+
+// Calculate breaks the gross amounts of all lines down into net and tax per rate
+func (t Table) Calculate(lines []Line, rounding Rounding) valueobject.TaxBreakdown {
+	byRate := make(map[float64]*valueobject.TaxRate)
+	for _, l := range lines {
+		rate := t.Rate(l.Category)
+		r, ok := byRate[rate]
+		if !ok {
+			r = &valueobject.TaxRate{Rate: rate}
+			byRate[rate] = r
+		}
+		gross := roundCents(l.Gross)
+		r.Gross += gross
+		if rounding == RoundPerLine {
+			r.Tax += included(gross, rate)
+		}
+	}
+
+	var b valueobject.TaxBreakdown
+	for _, r := range byRate {
+		r.Gross = roundCents(r.Gross)
+		if rounding == RoundPerInvoice {
+			r.Tax = included(r.Gross, r.Rate)
+		}
+		r.Tax = roundCents(r.Tax)
+		r.Net = roundCents(r.Gross - r.Tax)
+
+		b.Rates = append(b.Rates, *r)
+		b.Net += r.Net
+		b.Tax += r.Tax
+		b.Gross += r.Gross
+	}
+	sort.Slice(b.Rates, func(i, j int) bool {
+		return b.Rates[i].Rate < b.Rates[j].Rate
+	})
+	b.Net, b.Tax, b.Gross = roundCents(b.Net), roundCents(b.Tax), roundCents(b.Gross)
+	return b
+}
+
+// included returns the tax rounded to cents which is included in a gross amount
+func included(gross, rate float64) float64 {
+	return roundCents(gross - gross/(1+rate/100))
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,64 @@
+package tax_test
+
+import (
+	"taverne/domain/tax"
+	"taverne/valueobject"
+	"testing"
+)
+
+func TestTax_Calculate(t *testing.T) {
+	lines := []tax.Line{
+		{Category: valueobject.TaxBeverage, Gross: 0.99},
+		{Category: valueobject.TaxBeverage, Gross: 0.99},
+		{Category: valueobject.TaxBeverage, Gross: 0.99},
+		{Category: valueobject.TaxFood, Gross: 10.70},
+	}
+
+	type testCase struct {
+		name        string
+		rounding    tax.Rounding
+		expectedTax []float64
+	}
+
+	testCases := []testCase{
+		{
+			// 0.16 per beverage line
+			name:        "Round per line",
+			rounding:    tax.RoundPerLine,
+			expectedTax: []float64{0.70, 0.48},
+		},
+		{
+			// 19% included in 2.97
+			name:        "Round per invoice",
+			rounding:    tax.RoundPerInvoice,
+			expectedTax: []float64{0.70, 0.47},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := tax.DefaultTable().Calculate(lines, tc.rounding)
+			if len(b.Rates) != len(tc.expectedTax) {
+				t.Fatalf("Expected %d rates, got %v", len(tc.expectedTax), b.Rates)
+			}
+			for i, r := range b.Rates {
+				if r.Tax != tc.expectedTax[i] {
+					t.Errorf("Expected tax %.2f at %g%%, got %.2f", tc.expectedTax[i], r.Rate, r.Tax)
+				}
+			}
+			if b.Gross != 13.67 {
+				t.Errorf("Expected gross 13.67, got %.2f", b.Gross)
+			}
+			if b.Net+b.Tax != b.Gross {
+				t.Errorf("Expected net %.2f + tax %.2f to be gross %.2f", b.Net, b.Tax, b.Gross)
+			}
+		})
+	}
+}
+
+func TestTax_StandardRate(t *testing.T) {
+	table := tax.Table{Standard: 20}
+	if rate := table.Rate(valueobject.TaxFood); rate != 20 {
+		t.Errorf("Expected standard rate 20, got %g", rate)
+	}
+}
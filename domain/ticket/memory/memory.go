@@ -0,0 +1,93 @@
+// Package memory is a in memory implementation of the TicketRepository interface
+package memory
+
+import (
+	"sort"
+	"sync"
+	"taverne/aggregate"
+	"taverne/domain/ticket"
+
+	"github.com/google/uuid"
+)
+
+type MemoryTicketRepository struct {
+	tickets map[uuid.UUID]aggregate.Ticket
+	sync.Mutex
+}
+
+// New is a factory function to generate a new repository of tickets
+func New() *MemoryTicketRepository {
+	return &MemoryTicketRepository{
+		tickets: make(map[uuid.UUID]aggregate.Ticket),
+	}
+}
+
+// Get finds a ticket by ID
+func (mtr *MemoryTicketRepository) Get(id uuid.UUID) (aggregate.Ticket, error) {
+	mtr.Lock()
+	defer mtr.Unlock()
+
+	if t, ok := mtr.tickets[id]; ok {
+		return t, nil
+	}
+	return aggregate.Ticket{}, ticket.ErrTicketNotFound
+}
+
+// GetOpen finds the open tickets of a station
+func (mtr *MemoryTicketRepository) GetOpen(station aggregate.Station) ([]aggregate.Ticket, error) {
+	return mtr.find(func(t aggregate.Ticket) bool {
+		return (station == "" || t.GetStation() == station) && t.IsOpen()
+	}), nil
+}
+
+// GetByOrder finds the tickets of an order
+func (mtr *MemoryTicketRepository) GetByOrder(order uuid.UUID) ([]aggregate.Ticket, error) {
+	return mtr.find(func(t aggregate.Ticket) bool {
+		return t.GetOrderID() == order
+	}), nil
+}
+
+// Add will add a new ticket to the repository
+func (mtr *MemoryTicketRepository) Add(t aggregate.Ticket) error {
+	mtr.Lock()
+	defer mtr.Unlock()
+
+	if _, ok := mtr.tickets[t.GetID()]; ok {
+		return ticket.ErrTicketAlreadyExist
+	}
+	mtr.tickets[t.GetID()] = t
+	return nil
+}
+
+// Update will replace an existing ticket
+func (mtr *MemoryTicketRepository) Update(t aggregate.Ticket) error {
+	mtr.Lock()
+	defer mtr.Unlock()
+
+	if _, ok := mtr.tickets[t.GetID()]; !ok {
+		return ticket.ErrTicketNotFound
+	}
+	mtr.tickets[t.GetID()] = t
+	return nil
+}
+
+// find returns the matching tickets, the oldest first
+func (mtr *MemoryTicketRepository) find(match func(aggregate.Ticket) bool) []aggregate.Ticket {
+	mtr.Lock()
+	defer mtr.Unlock()
+
+	var tickets []aggregate.Ticket
+	for _, t := range mtr.tickets {
+		if match(t) {
+			tickets = append(tickets, t)
+		}
+	}
+	// tickets of the same time are ordered by ID, so the order does not change between calls
+	sort.SliceStable(tickets, func(i, j int) bool {
+		if !tickets[i].GetCreatedAt().Equal(tickets[j].GetCreatedAt()) {
+			return tickets[i].GetCreatedAt().Before(tickets[j].GetCreatedAt())
+		}
+		return tickets[i].GetID().String() < tickets[j].GetID().String()
+	})
+	return tickets
+}
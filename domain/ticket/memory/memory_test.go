@@ -0,0 +1,105 @@
+package memory
+
+import (
+	"taverne/aggregate"
+	"taverne/domain/ticket"
+	"taverne/valueobject"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestMemoryTicketRepository_GetOpen(t *testing.T) {
+	repo := New()
+	now := time.Date(2024, time.June, 7, 19, 0, 0, 0, time.UTC)
+	items := []valueobject.OrderItem{{ProductID: uuid.New(), Name: "Beer", Quantity: 1}}
+
+	var tickets []aggregate.Ticket
+	for i, station := range []aggregate.Station{aggregate.StationBar, aggregate.StationKitchen, aggregate.StationBar} {
+		tk, err := aggregate.NewTicket(uuid.New(), uuid.Nil, station, items, now.Add(time.Duration(i)*time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.Add(tk); err != nil {
+			t.Fatal(err)
+		}
+		tickets = append(tickets, tk)
+	}
+	if err := repo.Add(tickets[0]); err != ticket.ErrTicketAlreadyExist {
+		t.Errorf("Expected error %v, got %v", ticket.ErrTicketAlreadyExist, err)
+	}
+
+	// the latest bar ticket is ready and leaves the queue
+	for i := 0; i < 2; i++ {
+		if err := tickets[2].Bump(now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.Update(tickets[2]); err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		name     string
+		station  aggregate.Station
+		expected []aggregate.Ticket
+	}
+
+	testCases := []testCase{
+		{
+			name:     "Open tickets of the bar",
+			station:  aggregate.StationBar,
+			expected: tickets[:1],
+		},
+		{
+			name:     "Open tickets of all stations, the oldest first",
+			station:  "",
+			expected: tickets[:2],
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			open, err := repo.GetOpen(tc.station)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(open) != len(tc.expected) {
+				t.Fatalf("Expected %d tickets, got %d", len(tc.expected), len(open))
+			}
+			for i := range open {
+				if open[i].GetID() != tc.expected[i].GetID() {
+					t.Errorf("Expected ticket %s at position %d, got %s", tc.expected[i].GetID(), i, open[i].GetID())
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryTicketRepository_GetOpenSameTime(t *testing.T) {
+	repo := New()
+	now := time.Date(2024, time.June, 7, 19, 0, 0, 0, time.UTC)
+	items := []valueobject.OrderItem{{ProductID: uuid.New(), Name: "Beer", Quantity: 1}}
+
+	// tickets dispatched at the same time are ordered by their ID
+	for i := 0; i < 5; i++ {
+		tk, err := aggregate.NewTicket(uuid.New(), uuid.Nil, aggregate.StationBar, items, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.Add(tk); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	first, err := repo.GetOpen(aggregate.StationBar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i < len(first); i++ {
+		if first[i-1].GetID().String() >= first[i].GetID().String() {
+			t.Errorf("Expected tickets of the same time ordered by ID, got %s before %s", first[i-1].GetID(), first[i].GetID())
+		}
+	}
+}
@@ -0,0 +1,28 @@
+// Package ticket holds the repository and the implementations for a TicketRepository
+package ticket
+
+import (
+	"errors"
+	"taverne/aggregate"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrTicketNotFound is returned when a ticket is not found
+	ErrTicketNotFound = errors.New("the ticket was not found")
+	// ErrTicketAlreadyExist is returned when trying to add a ticket that already exists
+	ErrTicketAlreadyExist = errors.New("the ticket already exists")
+)
+
+// TicketRepository is the repository interface to fulfill to persist the ticket aggregate
+type TicketRepository interface {
+	Get(id uuid.UUID) (aggregate.Ticket, error)
+	// GetOpen returns the tickets a station still has to prepare, the oldest first.
+	// An empty station returns the open tickets of all stations
+	GetOpen(station aggregate.Station) ([]aggregate.Ticket, error)
+	// GetByOrder returns the tickets of an order
+	GetByOrder(order uuid.UUID) ([]aggregate.Ticket, error)
+	Add(ticket aggregate.Ticket) error
+	Update(ticket aggregate.Ticket) error
+}
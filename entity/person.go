@@ -1,9 +1,16 @@
 package entity
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type Person struct {
 	ID   uuid.UUID
 	Name string
-	Age  int
+	// BirthDate is the verified date of birth, the zero time means the age was never verified
+	BirthDate time.Time
+	Email     string
+	Phone     string
 }
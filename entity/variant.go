@@ -0,0 +1,25 @@
+package entity
+
+import (
+	"taverne/valueobject"
+
+	"github.com/google/uuid"
+)
+
+// Variant is a size or kind of a product with its own price and stock, such as an ale of 0.3l
+type Variant struct {
+	ID   uuid.UUID
+	Name string
+	// Price is the price the variant was added with, it is in effect until the first price change
+	Price float64
+	// PriceChanges are the scheduled prices of the variant sorted by the time they are in effect from
+	PriceChanges []valueobject.PriceChange
+	Quantity     int
+}
+
+// Modifier is an optional extra of a product which is charged on top, such as extra cheese
+type Modifier struct {
+	ID        uuid.UUID
+	Name      string
+	Surcharge float64
+}
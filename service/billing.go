@@ -0,0 +1,42 @@
+package service
+
+import (
+	"log"
+	"taverne/domain/payment"
+	"taverne/valueobject"
+
+	"github.com/google/uuid"
+)
+
+// BillingService charges customers for what they ordered
+type BillingService interface {
+	// Bill charges the customer amount for the given reference, such as an order or a tab
+	Bill(customer uuid.UUID, reference uuid.UUID, amount float64) error
+}
+
+// MethodBiller is implemented by a BillingService which takes the money by the payment method
+// the customer chose, such as cash or card
+type MethodBiller interface {
+	BillWith(method payment.Method, customer uuid.UUID, reference uuid.UUID, amount float64) error
+}
+
+// Refunder is implemented by a BillingService which can pay money back to customers
+type Refunder interface {
+	// Refund pays the customer amount back for the given reference, which was billed before,
+	// and returns the refund transaction. The refund ID identifies the refund, so a refund which
+	// is retried is paid back only once
+	Refund(customer uuid.UUID, reference uuid.UUID, refundID uuid.UUID, amount float64) (valueobject.Transaction, error)
+}
+
+// logBilling is used as long as no BillingService is configured, it only logs the bill
+type logBilling struct{}
+
+func (logBilling) Bill(customer uuid.UUID, reference uuid.UUID, amount float64) error {
+	log.Printf("Bill the Customer: %s %.2f", customer, amount)
+	return nil
+}
+
+func (logBilling) Refund(customer uuid.UUID, reference uuid.UUID, refundID uuid.UUID, amount float64) (valueobject.Transaction, error) {
+	log.Printf("Refund the Customer: %s %.2f", customer, amount)
+	return valueobject.NewTransaction(valueobject.TransactionRefund, -toCents(amount), customer, reference), nil
+}
@@ -0,0 +1,30 @@
+package service
+
+import (
+	"io"
+	"taverne/domain/product/catalog"
+)
+
+// ImportCatalogCSV upserts all products of a CSV catalogue, see catalog.ImportCSV.
+// The import runs while no other change of the products does, changed prices take effect now
+// and every row with a tax category the tax table does not know is reported with catalog.ErrUnknownTaxCategory
+func (o *OrderService) ImportCatalogCSV(r io.Reader, dryRun bool) (catalog.Report, error) {
+	o.productMu.Lock()
+	defer o.productMu.Unlock()
+
+	return catalog.ImportCSV(r, o.products, o.catalogOptions(dryRun))
+}
+
+// ImportCatalogJSON upserts all products of a JSON catalogue like ImportCatalogCSV, see catalog.ImportJSON
+func (o *OrderService) ImportCatalogJSON(r io.Reader, dryRun bool) (catalog.Report, error) {
+	o.productMu.Lock()
+	defer o.productMu.Unlock()
+
+	return catalog.ImportJSON(r, o.products, o.catalogOptions(dryRun))
+}
+
+// catalogOptions imports with the clock and the tax table of the service
+func (o *OrderService) catalogOptions(dryRun bool) catalog.Options {
+	taxes := o.taxes
+	return catalog.Options{DryRun: dryRun, Now: o.now, Taxes: &taxes}
+}
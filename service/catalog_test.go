@@ -0,0 +1,52 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"taverne/domain/product/catalog"
+	"taverne/valueobject"
+	"testing"
+)
+
+func TestCatalog_ImportCatalog(t *testing.T) {
+	products := init_products(t)
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	csv := "id,name,description,price,tax_category,available\n" +
+		products[0].GetID().String() + ",Beer,Cold and fresh,2.49,beverage,\n" +
+		",Cigar,Cuban,12.00,tobacco,\n"
+	report, err := os.ImportCatalogCSV(strings.NewReader(csv), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Updated) != 1 || len(report.Created) != 0 {
+		t.Errorf("Expected the beer to be updated, got %v", report)
+	}
+	if len(report.Errors) != 1 || report.Errors[0].Row != 2 || !errors.Is(report.Errors[0], catalog.ErrUnknownTaxCategory) {
+		t.Fatalf("Expected error %v in row 2, got %v", catalog.ErrUnknownTaxCategory, report.Errors)
+	}
+
+	json := `[{"name": "Pretzel", "description": "Warm and salty", "price": 1.5, "tax_category": "food"},
+		{"name": "Snuff", "description": "Fresh", "price": 4, "tax_category": "tobacco"}]`
+	report, err = os.ImportCatalogJSON(strings.NewReader(json), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Created) != 1 || len(report.Errors) != 1 || !errors.Is(report.Errors[0], catalog.ErrUnknownTaxCategory) {
+		t.Fatalf("Expected the pretzel to be created and the snuff to fail, got %v", report)
+	}
+	pretzel, err := os.products.GetByID(report.Created[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pretzel.GetTaxCategory() != valueobject.TaxFood {
+		t.Errorf("Expected the pretzel to be food, got %q", pretzel.GetTaxCategory())
+	}
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"taverne/aggregate"
+	"taverne/domain/customer"
+	"taverne/valueobject"
+
+	"github.com/google/uuid"
+)
+
+// CustomerService registers customers and keeps their profile up to date
+// Profile changes go through CustomerRepository.Change, so two updates of a customer do not overwrite each other
+type CustomerService struct {
+	customers customer.CustomerRepository
+}
+
+// NewCustomerService creates a CustomerService on top of the customer repository
+func NewCustomerService(cr customer.CustomerRepository) *CustomerService {
+	return &CustomerService{
+		customers: cr,
+	}
+}
+
+// Register creates a customer with the given profile and stores it
+// will return aggregate.ValidationErrors holding the error of every invalid field
+func (cs *CustomerService) Register(name string, opts ...aggregate.CustomerOption) (aggregate.Customer, error) {
+	c, err := aggregate.NewCustomer(name, opts...)
+	if err != nil {
+		return aggregate.Customer{}, err
+	}
+	if err := cs.customers.Add(c); err != nil {
+		return aggregate.Customer{}, err
+	}
+	return c, nil
+}
+
+// GetCustomer returns the customer with the given ID
+func (cs *CustomerService) GetCustomer(customerID uuid.UUID) (aggregate.Customer, error) {
+	return cs.customers.Get(customerID)
+}
+
+// UpdateProfile changes the profile of the customer with all options
+// Nothing is stored unless every option is valid, otherwise aggregate.ValidationErrors is returned
+func (cs *CustomerService) UpdateProfile(customerID uuid.UUID, opts ...aggregate.CustomerOption) (aggregate.Customer, error) {
+	return cs.customers.Change(customerID, func(c *aggregate.Customer) error {
+		return c.Apply(opts...)
+	})
+}
+
+// UpdateContact changes the email address and phone number of the customer, empty values remove them
+func (cs *CustomerService) UpdateContact(customerID uuid.UUID, email, phone string) (aggregate.Customer, error) {
+	return cs.UpdateProfile(customerID, aggregate.WithEmail(email), aggregate.WithPhone(phone))
+}
+
+// UpdateDietaryPreferences replaces the allergies and diets of the customer
+func (cs *CustomerService) UpdateDietaryPreferences(customerID uuid.UUID, allergies []valueobject.Allergen, diets []valueobject.Diet) (aggregate.Customer, error) {
+	return cs.UpdateProfile(customerID, aggregate.WithAllergies(allergies...), aggregate.WithDiets(diets...))
+}
+
+// SetMarketingConsent records if the customer agreed to receive marketing
+func (cs *CustomerService) SetMarketingConsent(customerID uuid.UUID, consent bool) (aggregate.Customer, error) {
+	return cs.UpdateProfile(customerID, aggregate.WithMarketingConsent(consent))
+}
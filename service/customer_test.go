@@ -0,0 +1,52 @@
+package service
+
+import (
+	"errors"
+	"taverne/aggregate"
+	"taverne/domain/customer/memory"
+	"taverne/valueobject"
+	"testing"
+)
+
+func TestCustomer_UpdateProfile(t *testing.T) {
+	cs := NewCustomerService(memory.New())
+
+	cust, err := cs.Register("Daisy", aggregate.WithEmail("daisy@duckburg.org"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = cs.UpdateContact(cust.GetID(), "daisy(at)duckburg.org", "12")
+	var verrs aggregate.ValidationErrors
+	if !errors.As(err, &verrs) {
+		t.Fatalf("Expected validation errors, got %v", err)
+	}
+	if !errors.Is(verrs.Field("email"), aggregate.ErrInvalidEmail) || !errors.Is(verrs.Field("phone"), aggregate.ErrInvalidPhone) {
+		t.Errorf("Expected errors of email and phone, got %v", err)
+	}
+
+	_, err = cs.UpdateDietaryPreferences(cust.GetID(), []valueobject.Allergen{valueobject.AllergenNuts}, []valueobject.Diet{valueobject.DietVegan})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cs.SetMarketingConsent(cust.GetID(), true); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := cs.GetCustomer(cust.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.GetEmail() != "daisy@duckburg.org" {
+		t.Errorf("Expected the invalid update to be dropped, got %s", found.GetEmail())
+	}
+	if allergies := found.GetAllergies(); len(allergies) != 1 || allergies[0] != valueobject.AllergenNuts {
+		t.Errorf("Expected allergy to nuts, got %v", allergies)
+	}
+	if diets := found.GetDiets(); len(diets) != 1 || diets[0] != valueobject.DietVegan {
+		t.Errorf("Expected vegan diet, got %v", diets)
+	}
+	if !found.HasMarketingConsent() {
+		t.Error("Expected marketing consent")
+	}
+}
@@ -0,0 +1,292 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"taverne/aggregate"
+	"taverne/domain/ticket"
+	ticketmemory "taverne/domain/ticket/memory"
+	"taverne/valueobject"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidStation is returned when categories are routed to a station without a name
+	ErrInvalidStation = errors.New("a station has to have a name")
+)
+
+// subscriptionBuffer is how many updates a subscriber may fall behind before updates to it are dropped
+const subscriptionBuffer = 64
+
+// KitchenConfiguration is an alias that takes a pointer and modifies the KitchenService
+type KitchenConfiguration func(ks *KitchenService) error
+
+// KitchenService keeps the ticket queue of every station, such as the bar and the kitchen,
+// and tells the screens of the stations about every change
+type KitchenService struct {
+	tickets ticket.TicketRepository
+	// stations routes the products of a category to a station, everything else goes to the kitchen
+	stations map[uuid.UUID]aggregate.Station
+	now      func() time.Time
+	// ticketMu serializes bumps, so two screens can not bump the same ticket at once
+	ticketMu sync.Mutex
+	// subscribers are guarded by subMu, an update is sent while it is held so no channel is closed mid-send
+	subscribers map[*subscription]struct{}
+	subMu       sync.Mutex
+}
+
+// KitchenTicket is a ticket as the screen of a station shows it
+type KitchenTicket struct {
+	ID        uuid.UUID              `json:"id"`
+	OrderID   uuid.UUID              `json:"order_id"`
+	TableID   uuid.UUID              `json:"table_id"`
+	Station   aggregate.Station      `json:"station"`
+	Status    aggregate.TicketStatus `json:"status"`
+	Items     []KitchenItem          `json:"items"`
+	CreatedAt time.Time              `json:"created_at"`
+	UpdatedAt time.Time              `json:"updated_at"`
+}
+
+// KitchenItem is an item to prepare, without prices
+type KitchenItem struct {
+	Name      string   `json:"name"`
+	Variant   string   `json:"variant,omitempty"`
+	Modifiers []string `json:"modifiers,omitempty"`
+	Quantity  int      `json:"quantity"`
+	Notes     string   `json:"notes,omitempty"`
+}
+
+// subscription is a screen listening to the updates of some stations, no stations listens to all
+type subscription struct {
+	stations []aggregate.Station
+	updates  chan KitchenTicket
+}
+
+// wants reports if the subscriber listens to the station
+func (s *subscription) wants(station aggregate.Station) bool {
+	if len(s.stations) == 0 {
+		return true
+	}
+	for _, st := range s.stations {
+		if st == station {
+			return true
+		}
+	}
+	return false
+}
+
+// NewKitchenService takes a variable amount of KitchenConfigurations and builds a KitchenService
+func NewKitchenService(cfgs ...KitchenConfiguration) (*KitchenService, error) {
+	ks := &KitchenService{
+		stations:    make(map[uuid.UUID]aggregate.Station),
+		now:         time.Now,
+		subscribers: make(map[*subscription]struct{}),
+	}
+	for _, cfg := range cfgs {
+		if err := cfg(ks); err != nil {
+			return nil, err
+		}
+	}
+	if ks.tickets == nil {
+		ks.tickets = ticketmemory.New()
+	}
+	return ks, nil
+}
+
+// WithTicketRepository applies a given ticket repository to the KitchenService
+func WithTicketRepository(tr ticket.TicketRepository) KitchenConfiguration {
+	return func(ks *KitchenService) error {
+		ks.tickets = tr
+		return nil
+	}
+}
+
+// WithStation routes the products of the categories to a station, such as the drinks to the bar
+func WithStation(station aggregate.Station, categories ...uuid.UUID) KitchenConfiguration {
+	return func(ks *KitchenService) error {
+		if station == "" {
+			return ErrInvalidStation
+		}
+		for _, c := range categories {
+			ks.stations[c] = station
+		}
+		return nil
+	}
+}
+
+// WithKitchenClock replaces the clock of the KitchenService, which is handy for testing
+func WithKitchenClock(now func() time.Time) KitchenConfiguration {
+	return func(ks *KitchenService) error {
+		ks.now = now
+		return nil
+	}
+}
+
+// Dispatch puts a placed order on the queues, one ticket per station which prepares any of its items
+// products are the ordered products, they decide the station by their categories
+func (ks *KitchenService) Dispatch(order aggregate.Order, products []aggregate.Product) ([]aggregate.Ticket, error) {
+	byProduct := make(map[uuid.UUID]aggregate.Station, len(products))
+	for _, p := range products {
+		byProduct[p.GetID()] = ks.station(p)
+	}
+
+	// the stations keep the order in which the items were ordered
+	var stations []aggregate.Station
+	items := make(map[aggregate.Station][]valueobject.OrderItem)
+	for _, item := range order.GetItems() {
+		station, ok := byProduct[item.ProductID]
+		if !ok {
+			station = aggregate.StationKitchen
+		}
+		if _, ok := items[station]; !ok {
+			stations = append(stations, station)
+		}
+		items[station] = append(items[station], item)
+	}
+
+	tickets := make([]aggregate.Ticket, 0, len(stations))
+	for _, station := range stations {
+		t, err := aggregate.NewTicket(order.GetID(), order.GetTableID(), station, items[station], ks.now())
+		if err != nil {
+			return tickets, err
+		}
+		if err := ks.tickets.Add(t); err != nil {
+			return tickets, err
+		}
+		ks.publish(t)
+		tickets = append(tickets, t)
+	}
+	return tickets, nil
+}
+
+// station returns the station of the first category of the product which is routed to one
+func (ks *KitchenService) station(p aggregate.Product) aggregate.Station {
+	for _, c := range p.GetCategories() {
+		if s, ok := ks.stations[c]; ok {
+			return s
+		}
+	}
+	return aggregate.StationKitchen
+}
+
+// Bump moves a ticket one step on, from new to in progress and from in progress to ready
+func (ks *KitchenService) Bump(ticketID uuid.UUID) (aggregate.Ticket, error) {
+	ks.ticketMu.Lock()
+	defer ks.ticketMu.Unlock()
+
+	t, err := ks.tickets.Get(ticketID)
+	if err != nil {
+		return aggregate.Ticket{}, err
+	}
+	if err := t.Bump(ks.now()); err != nil {
+		return aggregate.Ticket{}, err
+	}
+	if err := ks.tickets.Update(t); err != nil {
+		return aggregate.Ticket{}, err
+	}
+	ks.publish(t)
+	return t, nil
+}
+
+// Withdraw takes the open tickets of a cancelled order off the queues
+func (ks *KitchenService) Withdraw(orderID uuid.UUID) error {
+	ks.ticketMu.Lock()
+	defer ks.ticketMu.Unlock()
+
+	tickets, err := ks.tickets.GetByOrder(orderID)
+	if err != nil {
+		return err
+	}
+	for _, t := range tickets {
+		if !t.IsOpen() {
+			continue
+		}
+		if err := t.Cancel(ks.now()); err != nil {
+			return err
+		}
+		if err := ks.tickets.Update(t); err != nil {
+			return err
+		}
+		ks.publish(t)
+	}
+	return nil
+}
+
+// Queue returns the tickets a station still has to prepare, the oldest first.
+// An empty station returns the queue of the whole kitchen and bar together
+func (ks *KitchenService) Queue(station aggregate.Station) ([]KitchenTicket, error) {
+	tickets, err := ks.tickets.GetOpen(station)
+	if err != nil {
+		return nil, err
+	}
+	queue := make([]KitchenTicket, 0, len(tickets))
+	for _, t := range tickets {
+		queue = append(queue, newKitchenTicket(t))
+	}
+	return queue, nil
+}
+
+// Subscribe returns a channel which receives every change to a ticket of the stations,
+// no stations subscribes to all of them. The returned function ends the subscription and closes the channel.
+// A subscriber which falls behind misses updates, it catches up with Queue
+func (ks *KitchenService) Subscribe(stations ...aggregate.Station) (<-chan KitchenTicket, func()) {
+	sub := &subscription{
+		stations: append([]aggregate.Station(nil), stations...),
+		updates:  make(chan KitchenTicket, subscriptionBuffer),
+	}
+	ks.subMu.Lock()
+	ks.subscribers[sub] = struct{}{}
+	ks.subMu.Unlock()
+
+	var once sync.Once
+	return sub.updates, func() {
+		once.Do(func() {
+			ks.subMu.Lock()
+			defer ks.subMu.Unlock()
+			delete(ks.subscribers, sub)
+			close(sub.updates)
+		})
+	}
+}
+
+// publish sends the ticket to every subscriber of its station without waiting for slow ones
+func (ks *KitchenService) publish(t aggregate.Ticket) {
+	update := newKitchenTicket(t)
+
+	ks.subMu.Lock()
+	defer ks.subMu.Unlock()
+	for sub := range ks.subscribers {
+		if !sub.wants(t.GetStation()) {
+			continue
+		}
+		select {
+		case sub.updates <- update:
+		default:
+		}
+	}
+}
+
+// newKitchenTicket converts a ticket into what the screens show
+func newKitchenTicket(t aggregate.Ticket) KitchenTicket {
+	kt := KitchenTicket{
+		ID:        t.GetID(),
+		OrderID:   t.GetOrderID(),
+		TableID:   t.GetTableID(),
+		Station:   t.GetStation(),
+		Status:    t.GetStatus(),
+		CreatedAt: t.GetCreatedAt(),
+		UpdatedAt: t.GetUpdatedAt(),
+	}
+	for _, item := range t.GetItems() {
+		kt.Items = append(kt.Items, KitchenItem{
+			Name:      item.Name,
+			Variant:   item.Variant,
+			Modifiers: item.Modifiers,
+			Quantity:  item.Quantity,
+			Notes:     item.Notes,
+		})
+	}
+	return kt
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"taverne/aggregate"
+	"time"
+)
+
+// heartbeat is how often an idle stream sends a comment, so proxies do not close it
+const heartbeat = 15 * time.Second
+
+// StreamHandler streams the tickets of stations as Server-Sent Events, such as GET /kitchen?station=bar.
+// Every station parameter subscribes to one station, without any the stream carries all of them.
+// A new stream starts with the open tickets, after that every change is sent as a ticket event
+func (ks *KitchenService) StreamHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+			return
+		}
+		var stations []aggregate.Station
+		for _, s := range r.URL.Query()["station"] {
+			stations = append(stations, aggregate.Station(s))
+		}
+
+		// subscribing before reading the queues makes sure no change in between is missed
+		updates, unsubscribe := ks.Subscribe(stations...)
+		defer unsubscribe()
+
+		var open []KitchenTicket
+		queues := stations
+		if len(queues) == 0 {
+			queues = []aggregate.Station{""}
+		}
+		for _, s := range queues {
+			queue, err := ks.Queue(s)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			open = append(open, queue...)
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		for _, t := range open {
+			if err := writeTicketEvent(w, t); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-ticker.C:
+				if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+					return
+				}
+			case t, ok := <-updates:
+				if !ok {
+					return
+				}
+				if err := writeTicketEvent(w, t); err != nil {
+					return
+				}
+			}
+			flusher.Flush()
+		}
+	})
+}
+
+// writeTicketEvent writes a ticket as one event, a screen replaces the ticket with the same id
+func writeTicketEvent(w http.ResponseWriter, t KitchenTicket) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: ticket\ndata: %s\n\n", data)
+	return err
+}
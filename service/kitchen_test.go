@@ -0,0 +1,231 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"taverne/aggregate"
+	"taverne/valueobject"
+	"testing"
+)
+
+// init_kitchen returns a Tavern which sends its billed orders to the kitchen, beer and wine are drinks made at the bar
+func init_kitchen(t *testing.T) (*Tavern, *KitchenService, []aggregate.Product, aggregate.Customer) {
+	products := init_products(t)
+	drinks, err := aggregate.NewCategory("Drinks", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	products[0].AddToCategory(drinks.GetID())
+	products[2].AddToCategory(drinks.GetID())
+
+	ks, err := NewKitchenService(WithStation(aggregate.StationBar, drinks.GetID()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+		WithKitchen(ks),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tavern, err := NewTavern(WithOrderService(os))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cust, err := aggregate.NewCustomer("Percy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customers.Add(cust); err != nil {
+		t.Fatal(err)
+	}
+	return tavern, ks, products, cust
+}
+
+func TestKitchen_Queue(t *testing.T) {
+	tavern, ks, products, cust := init_kitchen(t)
+	if _, err := NewKitchenService(WithStation("")); err != ErrInvalidStation {
+		t.Errorf("Expected error %v, got %v", ErrInvalidStation, err)
+	}
+
+	updates, unsubscribe := ks.Subscribe(aggregate.StationBar)
+	order, err := tavern.Order(OrderRequest{
+		CustomerID: cust.GetID(),
+		Lines: []valueobject.OrderLine{
+			{ProductID: products[0].GetID(), Quantity: 2},
+			{ProductID: products[1].GetID(), Quantity: 1},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		station       aggregate.Station
+		expectedItems []string
+	}
+
+	testCases := []testCase{
+		{station: aggregate.StationBar, expectedItems: []string{"Beer"}},
+		{station: aggregate.StationKitchen, expectedItems: []string{"Peenuts"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.station), func(t *testing.T) {
+			queue, err := ks.Queue(tc.station)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(queue) != 1 || queue[0].OrderID != order.GetID() || queue[0].Status != aggregate.TicketNew {
+				t.Fatalf("Expected one new ticket of order %s, got %v", order.GetID(), queue)
+			}
+			if len(queue[0].Items) != len(tc.expectedItems) || queue[0].Items[0].Name != tc.expectedItems[0] {
+				t.Errorf("Expected items %v, got %v", tc.expectedItems, queue[0].Items)
+			}
+		})
+	}
+
+	// the bar screen hears about its ticket and every bump of it
+	bar := <-updates
+	if bar.Station != aggregate.StationBar || bar.Status != aggregate.TicketNew {
+		t.Fatalf("Expected a new bar ticket, got %v", bar)
+	}
+	for _, expected := range []aggregate.TicketStatus{aggregate.TicketInProgress, aggregate.TicketReady} {
+		if _, err := ks.Bump(bar.ID); err != nil {
+			t.Fatal(err)
+		}
+		if update := <-updates; update.ID != bar.ID || update.Status != expected {
+			t.Errorf("Expected ticket %s to be %s, got %v", bar.ID, expected, update)
+		}
+	}
+	if _, err := ks.Bump(bar.ID); err != aggregate.ErrTicketDone {
+		t.Errorf("Expected error %v, got %v", aggregate.ErrTicketDone, err)
+	}
+
+	// withdrawing the order takes the open kitchen ticket off its queue, the ready drinks stay ready
+	if err := ks.Withdraw(order.GetID()); err != nil {
+		t.Fatal(err)
+	}
+	if queue, err := ks.Queue(""); err != nil || len(queue) != 0 {
+		t.Errorf("Expected empty queues, got %v %v", queue, err)
+	}
+	if len(updates) != 0 {
+		t.Errorf("Expected the bar screen not to hear about kitchen tickets, got %d updates", len(updates))
+	}
+
+	unsubscribe()
+	if _, ok := <-updates; ok {
+		t.Errorf("Expected the channel to be closed")
+	}
+}
+
+func TestKitchen_StreamHandler(t *testing.T) {
+	tavern, ks, products, cust := init_kitchen(t)
+	order, err := tavern.Order(OrderRequest{
+		CustomerID: cust.GetID(),
+		Lines:      []valueobject.OrderLine{{ProductID: products[1].GetID(), Quantity: 3}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(ks.StreamHandler())
+	defer server.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"?station=kitchen", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %s", ct)
+	}
+
+	lines := bufio.NewScanner(resp.Body)
+	next := func() KitchenTicket {
+		for lines.Scan() {
+			data, ok := strings.CutPrefix(lines.Text(), "data: ")
+			if !ok {
+				continue
+			}
+			var ticket KitchenTicket
+			if err := json.Unmarshal([]byte(data), &ticket); err != nil {
+				t.Fatal(err)
+			}
+			return ticket
+		}
+		t.Fatalf("Expected another event, got %v", lines.Err())
+		return KitchenTicket{}
+	}
+
+	// the stream starts with the open tickets and goes on with every change
+	open := next()
+	if open.OrderID != order.GetID() || open.Status != aggregate.TicketNew || open.Items[0].Quantity != 3 {
+		t.Fatalf("Expected the new ticket of order %s, got %v", order.GetID(), open)
+	}
+	if _, err := ks.Bump(open.ID); err != nil {
+		t.Fatal(err)
+	}
+	if bumped := next(); bumped.ID != open.ID || bumped.Status != aggregate.TicketInProgress {
+		t.Errorf("Expected ticket %s in progress, got %v", open.ID, bumped)
+	}
+}
+
+func TestKitchen_DispatchBilled(t *testing.T) {
+	tavern, ks, products, cust := init_kitchen(t)
+	queued := func() int {
+		queue, err := ks.Queue("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(queue)
+	}
+
+	// an order which is placed but not billed does not reach the kitchen
+	if _, err := tavern.OrderService.CreateOrder(OrderRequest{
+		CustomerID: cust.GetID(),
+		Lines:      []valueobject.OrderLine{{ProductID: products[1].GetID(), Quantity: 1}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if n := queued(); n != 0 {
+		t.Errorf("Expected no tickets before billing, got %d", n)
+	}
+
+	// the orders on a tab reach the kitchen once the tab is settled
+	tabID, err := tavern.OpenTab(cust.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	order, err := tavern.OrderOnTab(tabID, OrderRequest{
+		CustomerID: cust.GetID(),
+		Lines:      []valueobject.OrderLine{{ProductID: products[0].GetID(), Quantity: 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := queued(); n != 0 {
+		t.Errorf("Expected no tickets of an open tab, got %d", n)
+	}
+	if err := tavern.CloseTab(tabID); err != nil {
+		t.Fatal(err)
+	}
+	queue, err := ks.Queue(aggregate.StationBar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queue) != 1 || queue[0].OrderID != order.GetID() {
+		t.Errorf("Expected the ticket of order %s at the bar, got %v", order.GetID(), queue)
+	}
+}
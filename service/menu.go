@@ -0,0 +1,125 @@
+package service
+
+import (
+	"sort"
+	"taverne/aggregate"
+	"taverne/valueobject"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MenuItem is a product as it is shown on the menu
+type MenuItem struct {
+	ProductID   uuid.UUID
+	Name        string
+	Description string
+	Price       float64
+	// Variants are the variants in stock, a product with variants is ordered as one of them
+	Variants []MenuOption
+	// Modifiers are the extras with their surcharge as price
+	Modifiers []MenuOption
+	// Allergens are the declared allergens the product contains
+	Allergens []valueobject.Allergen
+}
+
+// MenuOption is a variant or modifier of a product on the menu
+type MenuOption struct {
+	ID    uuid.UUID
+	Name  string
+	Price float64
+}
+
+// MenuSection is a category of the menu with all its available products
+type MenuSection struct {
+	CategoryID uuid.UUID
+	Name       string
+	Items      []MenuItem
+}
+
+// Menu returns all categories in menu order with the products which can be ordered right now,
+// archived, 86'd and sold out products are left out.
+// Products are sorted by name, a product listed in several categories shows up in each of them
+func (t *Tavern) Menu() ([]MenuSection, error) {
+	categories, err := t.categories.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	products, err := t.OrderService.products.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(products, func(i, j int) bool {
+		return products[i].GetItem().Name < products[j].GetItem().Name
+	})
+
+	now := t.OrderService.now()
+	menu := make([]MenuSection, 0, len(categories))
+	for _, c := range categories {
+		section := MenuSection{
+			CategoryID: c.GetID(),
+			Name:       c.GetName(),
+			Items:      make([]MenuItem, 0),
+		}
+		for _, p := range products {
+			if !p.InCategory(c.GetID()) || !p.IsAvailable() || !p.InStock() {
+				continue
+			}
+			section.Items = append(section.Items, newMenuItem(p, now))
+		}
+		menu = append(menu, section)
+	}
+	return menu, nil
+}
+
+// AddProductToCategory lists a product in a category of the menu
+// will return category.ErrCategoryNotFound if the category does not exist
+func (t *Tavern) AddProductToCategory(productID, categoryID uuid.UUID) error {
+	if _, err := t.categories.Get(categoryID); err != nil {
+		return err
+	}
+
+	t.OrderService.productMu.Lock()
+	defer t.OrderService.productMu.Unlock()
+
+	p, err := t.OrderService.products.GetByID(productID)
+	if err != nil {
+		return err
+	}
+	p.AddToCategory(categoryID)
+	return t.OrderService.products.Update(p)
+}
+
+// RemoveProductFromCategory takes a product off a category of the menu
+func (t *Tavern) RemoveProductFromCategory(productID, categoryID uuid.UUID) error {
+	t.OrderService.productMu.Lock()
+	defer t.OrderService.productMu.Unlock()
+
+	p, err := t.OrderService.products.GetByID(productID)
+	if err != nil {
+		return err
+	}
+	p.RemoveFromCategory(categoryID)
+	return t.OrderService.products.Update(p)
+}
+
+// newMenuItem shows a product with the price in effect at the given time
+func newMenuItem(p aggregate.Product, at time.Time) MenuItem {
+	item := MenuItem{
+		ProductID:   p.GetID(),
+		Name:        p.GetItem().Name,
+		Description: p.GetItem().Description,
+		Price:       p.PriceAt(at),
+		Allergens:   p.GetAllergens(),
+	}
+	for _, v := range p.GetVariants() {
+		if v.Quantity > 0 {
+			price, _ := p.VariantPriceAt(v.ID, at)
+			item.Variants = append(item.Variants, MenuOption{ID: v.ID, Name: v.Name, Price: price})
+		}
+	}
+	for _, m := range p.GetModifiers() {
+		item.Modifiers = append(item.Modifiers, MenuOption{ID: m.ID, Name: m.Name, Price: m.Surcharge})
+	}
+	return item
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"taverne/aggregate"
+	"taverne/domain/category"
+	"taverne/valueobject"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestMenu_Menu(t *testing.T) {
+	drinks, err := aggregate.NewCategory("Drinks", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snacks, err := aggregate.NewCategory("Snacks", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	empty, err := aggregate.NewCategory("Desserts", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	products := init_products(t)
+	// Beer and Wine are drinks, Peenuts are a snack but also go with drinks
+	products[0].AddToCategory(drinks.GetID())
+	products[1].AddToCategory(snacks.GetID())
+	products[1].AddToCategory(drinks.GetID())
+	products[2].AddToCategory(drinks.GetID())
+	if err := products[1].SetAllergens(valueobject.AllergenPeanuts); err != nil {
+		t.Fatal(err)
+	}
+	// Wine is sold out
+	if err := products[2].RemoveStock(products[2].GetQuantity()); err != nil {
+		t.Fatal(err)
+	}
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tavern, err := NewTavern(
+		WithOrderService(os),
+		WithMemoryCategoryRepository([]aggregate.Category{drinks, snacks, empty}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	menu, err := tavern.Menu()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string][]string{
+		"Snacks":   {"Peenuts"},
+		"Drinks":   {"Beer", "Peenuts"},
+		"Desserts": {},
+	}
+	order := []string{"Snacks", "Drinks", "Desserts"}
+	if len(menu) != len(order) {
+		t.Fatalf("Expected %d sections, got %d", len(order), len(menu))
+	}
+	for i, section := range menu {
+		if section.Name != order[i] {
+			t.Errorf("Expected section %s at %d, got %s", order[i], i, section.Name)
+		}
+		items := expected[section.Name]
+		if len(section.Items) != len(items) {
+			t.Fatalf("Expected %d items in %s, got %v", len(items), section.Name, section.Items)
+		}
+		for j, item := range section.Items {
+			if item.Name != items[j] {
+				t.Errorf("Expected %s in %s, got %s", items[j], section.Name, item.Name)
+			}
+			if item.Name == "Peenuts" && (len(item.Allergens) != 1 || item.Allergens[0] != valueobject.AllergenPeanuts) {
+				t.Errorf("Expected Peenuts to declare peanuts, got %v", item.Allergens)
+			}
+		}
+	}
+}
+
+func TestMenu_AddProductToCategory(t *testing.T) {
+	drinks, err := aggregate.NewCategory("Drinks", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	products := init_products(t)
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tavern, err := NewTavern(
+		WithOrderService(os),
+		WithMemoryCategoryRepository([]aggregate.Category{drinks}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	beer := products[0].GetID()
+
+	listed := func() int {
+		menu, err := tavern.Menu()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(menu[0].Items)
+	}
+
+	if err := tavern.AddProductToCategory(beer, uuid.New()); err != category.ErrCategoryNotFound {
+		t.Errorf("Expected error %v, got %v", category.ErrCategoryNotFound, err)
+	}
+	if err := tavern.AddProductToCategory(beer, drinks.GetID()); err != nil {
+		t.Fatal(err)
+	}
+	if n := listed(); n != 1 {
+		t.Errorf("Expected the beer on the menu, got %d items", n)
+	}
+	if err := tavern.RemoveProductFromCategory(beer, drinks.GetID()); err != nil {
+		t.Fatal(err)
+	}
+	if n := listed(); n != 0 {
+		t.Errorf("Expected the beer off the menu, got %d items", n)
+	}
+}
@@ -3,17 +3,103 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"strings"
+	"sync"
 	"taverne/aggregate"
 	"taverne/domain/customer"
 	"taverne/domain/customer/memory"
 	"taverne/domain/customer/sqlite"
+	"taverne/domain/loyalty"
+	"taverne/domain/order"
+	ordermemory "taverne/domain/order/memory"
+	"taverne/domain/payment"
+	"taverne/domain/pricing"
 	"taverne/domain/product"
 	prodmemory "taverne/domain/product/memory"
+	"taverne/domain/tax"
+	"taverne/valueobject"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+var (
+	// ErrEmptyOrder is returned when an order request contains no lines
+	ErrEmptyOrder = errors.New("an order has to contain at least one line")
+	// ErrInvalidQuantity is returned when an order line has a quantity below one
+	ErrInvalidQuantity = errors.New("the quantity of an order line has to be positive")
+	// ErrUnderAge is returned when a customer orders a product restricted to an age the customer has not reached
+	ErrUnderAge = errors.New("the customer is too young for the product")
+	// ErrAllergenConflict is returned when an order contains allergens the customer is allergic to
+	// and the request does not acknowledge them
+	ErrAllergenConflict = errors.New("the order contains allergens the customer is allergic to")
+)
+
+// AllergenConflict is a product which contains allergens the customer is allergic to
+type AllergenConflict struct {
+	ProductID uuid.UUID
+	Name      string
+	Allergens []valueobject.Allergen
+}
+
+// AllergenConflictError lists every product of an order the customer is allergic to
+// so the staff can warn the customer before the order is placed with AcknowledgeAllergens
+type AllergenConflictError struct {
+	Conflicts []AllergenConflict
+}
+
+func (e *AllergenConflictError) Error() string {
+	msgs := make([]string, 0, len(e.Conflicts))
+	for _, c := range e.Conflicts {
+		msgs = append(msgs, fmt.Sprintf("%s contains %v", c.Name, c.Allergens))
+	}
+	return fmt.Sprintf("%v: %s", ErrAllergenConflict, strings.Join(msgs, ", "))
+}
+
+func (e *AllergenConflictError) Unwrap() error {
+	return ErrAllergenConflict
+}
+
+// OrderRequest is everything a customer asks for in one order
+type OrderRequest struct {
+	CustomerID uuid.UUID
+	Lines      []valueobject.OrderLine
+	// Coupons are coupon codes the customer hands in
+	Coupons []string
+	// RedeemPoints are loyalty points the customer wants to pay with,
+	// no more of them are redeemed than needed to pay the order
+	RedeemPoints int
+	// AcknowledgeAllergens confirms the customer was told the order contains allergens
+	// the customer is allergic to, without it such an order is refused
+	AcknowledgeAllergens bool
+	// PaymentMethod is how the customer pays, empty uses the default of the BillingService
+	PaymentMethod payment.Method
+	// TableID is the table the order is served at, the customer has to be seated there.
+	// uuid.Nil orders at the bar
+	TableID uuid.UUID
+	// IdempotencyKey identifies the request when it is retried, Tavern.Order places the order of a key only once
+	IdempotencyKey string
+}
+
+// Validate checks that the request has lines and every line a positive quantity
+func (r OrderRequest) Validate() error {
+	if len(r.Lines) == 0 {
+		return ErrEmptyOrder
+	}
+	for i, l := range r.Lines {
+		if l.Quantity < 1 {
+			return fmt.Errorf("line %d has quantity %d: %w", i+1, l.Quantity, ErrInvalidQuantity)
+		}
+	}
+	if r.RedeemPoints < 0 {
+		return aggregate.ErrInvalidPoints
+	}
+	return nil
+}
+
 // OrderConfiguration is an alias for a function that will take in a pointer to an OrderService and modify it
 type OrderConfiguration func(os *OrderService) error
 
@@ -21,6 +107,19 @@ type OrderConfiguration func(os *OrderService) error
 type OrderService struct {
 	customers customer.CustomerRepository
 	products  product.ProductRepository
+	orders    order.OrderRepository
+	pricing   pricing.Pipeline
+	taxes     tax.Table
+	rounding  tax.Rounding
+	loyalty   *loyalty.Program
+	// kitchen gets a ticket for every billed order, nil without a kitchen display
+	kitchen *KitchenService
+	// productMu serializes changes to products, so two orders can not both take the last unit in stock
+	productMu sync.Mutex
+	// orderMu serializes changes to orders, so concurrent transitions do not overwrite each other
+	orderMu sync.Mutex
+	// now is the clock used to place and price orders
+	now func() time.Time
 }
 
 // NewOrderService takes a variable amount of OrderConfiguration functions and returns a new OrderService
@@ -28,7 +127,10 @@ type OrderService struct {
 // It is a variadic function (aka unknown number of parameters)
 func NewOrderService(cfgs ...OrderConfiguration) (*OrderService, error) {
 	// create the order service
-	os := &OrderService{}
+	os := &OrderService{
+		now:   time.Now,
+		taxes: tax.DefaultTable(),
+	}
 	// apply all configuration passed in
 	for _, cfg := range cfgs {
 		// Pass the service into the configuration function
@@ -37,6 +139,10 @@ func NewOrderService(cfgs ...OrderConfiguration) (*OrderService, error) {
 			return nil, err
 		}
 	}
+	// placed orders have to be kept somewhere to advance them later on
+	if os.orders == nil {
+		os.orders = ordermemory.New()
+	}
 	return os, nil
 
 }
@@ -76,6 +182,60 @@ func WithMemoryProductRepository(products []aggregate.Product) OrderConfiguratio
 	}
 }
 
+// WithOrderRepository applies a given order repository to the OrderService
+func WithOrderRepository(or order.OrderRepository) OrderConfiguration {
+	return func(os *OrderService) error {
+		os.orders = or
+		return nil
+	}
+}
+
+// WithMemoryOrderRepository applies a memory order repository to the OrderService
+func WithMemoryOrderRepository() OrderConfiguration {
+	return WithOrderRepository(ordermemory.New())
+}
+
+// WithPricingRules appends pricing rules to the pipeline every order runs through
+func WithPricingRules(rules ...pricing.Rule) OrderConfiguration {
+	return func(os *OrderService) error {
+		os.pricing = append(os.pricing, rules...)
+		return nil
+	}
+}
+
+// WithTaxTable replaces the default tax table and decides where tax is rounded
+func WithTaxTable(table tax.Table, rounding tax.Rounding) OrderConfiguration {
+	return func(os *OrderService) error {
+		os.taxes = table
+		os.rounding = rounding
+		return nil
+	}
+}
+
+// WithLoyaltyProgram lets customers earn and redeem loyalty points
+func WithLoyaltyProgram(p loyalty.Program) OrderConfiguration {
+	return func(os *OrderService) error {
+		os.loyalty = &p
+		return nil
+	}
+}
+
+// WithKitchen sends every billed order to the queues of the kitchen and the bar
+func WithKitchen(ks *KitchenService) OrderConfiguration {
+	return func(os *OrderService) error {
+		os.kitchen = ks
+		return nil
+	}
+}
+
+// WithClock replaces the clock of the OrderService, which is handy for testing time based rules
+func WithClock(now func() time.Time) OrderConfiguration {
+	return func(os *OrderService) error {
+		os.now = now
+		return nil
+	}
+}
+
 func WithSQLiteCustomerRepository(connectionString string) OrderConfiguration {
 	return func(os *OrderService) error {
 		// Create the sqlite repo, if we needed parameters, such as connection strings they could be inputted here
@@ -89,28 +249,547 @@ func WithSQLiteCustomerRepository(connectionString string) OrderConfiguration {
 }
 
 // CreateOrder will chaintogether all repositories to create a order for a customer
-// will return the collected price of all Products
-func (o *OrderService) CreateOrder(customerID uuid.UUID, productIDs []uuid.UUID) (float64, error) {
+// Each line is priced as unit price × quantity and the ordered quantity is taken out of stock.
+// will return the placed order
+func (o *OrderService) CreateOrder(req OrderRequest) (aggregate.Order, error) {
+	if err := req.Validate(); err != nil {
+		return aggregate.Order{}, err
+	}
+
 	// Get the customer
-	c, err := o.customers.Get(customerID)
+	c, err := o.customers.Get(req.CustomerID)
+	if err != nil {
+		return aggregate.Order{}, err
+	}
+	if req.RedeemPoints > 0 {
+		if o.loyalty == nil {
+			return aggregate.Order{}, loyalty.ErrNoProgram
+		}
+		if c.GetPoints() < req.RedeemPoints {
+			return aggregate.Order{}, aggregate.ErrInsufficientPoints
+		}
+	}
+
+	// Get all products at once, so every unknown product is reported together
+	productIDs := make([]uuid.UUID, 0, len(req.Lines))
+	for _, l := range req.Lines {
+		productIDs = append(productIDs, l.ProductID)
+	}
+	products, err := o.products.GetByIDs(productIDs)
 	if err != nil {
-		return 0, err
+		return aggregate.Order{}, err
 	}
 
-	// Get each product
-	var products []aggregate.Product
-	var price float64
-	for _, id := range productIDs {
-		p, err := o.products.GetByID(id)
+	// GetByIDs keeps the order of the ids, so products[i] belongs to req.Lines[i]
+	items := make([]valueobject.OrderItem, 0, len(req.Lines))
+	for i, p := range products {
+		if err := checkAge(c, p, o.now()); err != nil {
+			return aggregate.Order{}, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		item, err := newOrderItem(p, req.Lines[i], o.now())
 		if err != nil {
-			return 0, err
+			return aggregate.Order{}, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		items = append(items, item)
+	}
+
+	if !req.AcknowledgeAllergens {
+		if err := checkAllergens(c, products); err != nil {
+			return aggregate.Order{}, err
+		}
+	}
+
+	ord, err := aggregate.NewOrder(c.GetID(), items)
+	if err != nil {
+		return aggregate.Order{}, err
+	}
+	ord.SetTableID(req.TableID)
+
+	if err := o.takeStock(items); err != nil {
+		return aggregate.Order{}, err
+	}
+
+	if err := o.placeOrder(&ord, req, products); err != nil {
+		// the order was never placed, so hand the stock back
+		if rerr := o.releaseStock(items); rerr != nil {
+			return aggregate.Order{}, errors.Join(err, rerr)
 		}
-		products = append(products, p)
-		price += p.GetPrice()
+		return aggregate.Order{}, err
 	}
 
 	// All Products exists in store, now we can create the order
-	log.Printf("Customer: %s has ordered %d products", c.GetID(), len(products))
+	log.Printf("Customer: %s has ordered %d products", c.GetID(), len(items))
+
+	return ord, nil
+}
+
+// dispatch sends a billed order to the queues of the kitchen and the bar
+// The order is billed already, so failing to show it in the kitchen must not fail the order
+func (o *OrderService) dispatch(ord aggregate.Order) {
+	if o.kitchen == nil {
+		return
+	}
+	items := ord.GetItems()
+	productIDs := make([]uuid.UUID, 0, len(items))
+	for _, item := range items {
+		productIDs = append(productIDs, item.ProductID)
+	}
+	products, err := o.products.GetByIDs(productIDs)
+	if err == nil {
+		_, err = o.kitchen.Dispatch(ord, products)
+	}
+	if err != nil {
+		log.Printf("sending order %s to the kitchen failed: %v", ord.GetID(), err)
+	}
+}
+
+// checkAge makes sure the customer may order an age-restricted product
+// A customer without a verified birth date can not order any restricted product
+func checkAge(c aggregate.Customer, p aggregate.Product, at time.Time) error {
+	if p.GetMinimumAge() == 0 {
+		return nil
+	}
+	age, err := c.AgeAt(at)
+	if err != nil {
+		return fmt.Errorf("product %s: %w", p.GetItem().Name, err)
+	}
+	if age < p.GetMinimumAge() {
+		return fmt.Errorf("product %s requires age %d: %w", p.GetItem().Name, p.GetMinimumAge(), ErrUnderAge)
+	}
+	return nil
+}
+
+// checkAllergens returns an AllergenConflictError if any product contains allergens the customer is allergic to
+func checkAllergens(c aggregate.Customer, products []aggregate.Product) error {
+	allergies := c.GetAllergies()
+	if len(allergies) == 0 {
+		return nil
+	}
+	var conflicts []AllergenConflict
+	seen := make(map[uuid.UUID]bool)
+	for _, p := range products {
+		if seen[p.GetID()] {
+			continue
+		}
+		seen[p.GetID()] = true
+		if found := p.Contains(allergies); len(found) > 0 {
+			conflicts = append(conflicts, AllergenConflict{
+				ProductID: p.GetID(),
+				Name:      p.GetItem().Name,
+				Allergens: found,
+			})
+		}
+	}
+	if len(conflicts) > 0 {
+		return &AllergenConflictError{Conflicts: conflicts}
+	}
+	return nil
+}
+
+// newOrderItem prices a line with the chosen variant and modifiers of the product
+// The item snapshots the price in effect at the given time
+func newOrderItem(p aggregate.Product, line valueobject.OrderLine, at time.Time) (valueobject.OrderItem, error) {
+	if !p.IsAvailable() {
+		return valueobject.OrderItem{}, fmt.Errorf("product %s: %w", p.GetItem().Name, aggregate.ErrProductUnavailable)
+	}
+	price, err := p.PriceOf(at, line.VariantID, line.ModifierIDs)
+	if err != nil {
+		return valueobject.OrderItem{}, fmt.Errorf("product %s: %w", p.GetItem().Name, err)
+	}
+
+	item := valueobject.OrderItem{
+		ProductID:   p.GetID(),
+		Name:        p.GetItem().Name,
+		VariantID:   line.VariantID,
+		Quantity:    line.Quantity,
+		Notes:       line.Notes,
+		UnitPrice:   price,
+		TaxCategory: p.GetTaxCategory(),
+	}
+	if line.VariantID != uuid.Nil {
+		// PriceOf already made sure the variant and modifiers exist
+		v, _ := p.GetVariant(line.VariantID)
+		item.Variant = v.Name
+	}
+	for _, id := range line.ModifierIDs {
+		m, _ := p.GetModifier(id)
+		item.Modifiers = append(item.Modifiers, m.Name)
+	}
+	return item, nil
+}
+
+// placeOrder runs the order through the pricing pipeline, redeems loyalty points and stores it
+// products[i] is the product of the i-th item of the order
+func (o *OrderService) placeOrder(ord *aggregate.Order, req OrderRequest, products []aggregate.Product) (err error) {
+	items := ord.GetItems()
+	lines := make([]pricing.Line, 0, len(items))
+	for i, item := range items {
+		lines = append(lines, pricing.Line{Item: item, Product: products[i]})
+	}
 
-	return price, nil
+	priced := pricing.Order{
+		Customer: ord.GetCustomerID(),
+		Lines:    lines,
+		Coupons:  req.Coupons,
+		Time:     o.now(),
+	}
+	discounts, err := o.pricing.Apply(priced)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// the order is not placed, so its coupons can be used again and the points are given back
+		if err != nil {
+			o.pricing.Release(priced)
+			if rerr := o.restorePoints(*ord); rerr != nil {
+				err = errors.Join(err, rerr)
+			}
+		}
+	}()
+	ord.SetCoupons(req.Coupons)
+	if err := ord.ApplyDiscounts(discounts...); err != nil {
+		return err
+	}
+	if err := o.redeemPoints(ord, req.RedeemPoints); err != nil {
+		return err
+	}
+
+	// prices are gross, so the tax is taken out of what is left to pay of every item
+	totals := ord.DiscountedItemTotals()
+	taxLines := make([]tax.Line, 0, len(items))
+	for i, item := range items {
+		taxLines = append(taxLines, tax.Line{Category: item.TaxCategory, Gross: totals[i]})
+	}
+	ord.SetTaxBreakdown(o.taxes.Calculate(taxLines, o.rounding))
+
+	return o.orders.Add(*ord)
+}
+
+// redeemPoints pays what is left of the order with loyalty points of the customer
+func (o *OrderService) redeemPoints(ord *aggregate.Order, offered int) error {
+	if offered == 0 {
+		return nil
+	}
+	points, value := o.loyalty.Redeem(offered, ord.Total())
+	if points == 0 {
+		return nil
+	}
+
+	err := ord.ApplyDiscounts(valueobject.Discount{
+		Description: fmt.Sprintf("Loyalty %d points", points),
+		Amount:      fromCents(toCents(value)),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = o.customers.Change(ord.GetCustomerID(), func(c *aggregate.Customer) error {
+		if err := c.RedeemPoints(points); err != nil {
+			return err
+		}
+		c.AddTransaction(valueobject.NewTransaction(valueobject.TransactionLoyaltyRedemption, points, c.GetID(), ord.GetID()))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	ord.SetRedeemedPoints(points)
+	return nil
+}
+
+// restorePoints gives the customer back the loyalty points redeemed for an order which is never paid
+func (o *OrderService) restorePoints(ord aggregate.Order) error {
+	points := ord.GetRedeemedPoints()
+	if points == 0 {
+		return nil
+	}
+	_, err := o.customers.Change(ord.GetCustomerID(), func(c *aggregate.Customer) error {
+		if err := c.AddPoints(points); err != nil {
+			return err
+		}
+		c.AddTransaction(valueobject.NewTransaction(valueobject.TransactionLoyaltyAccrual, points, ord.GetID(), c.GetID()))
+		return nil
+	})
+	return err
+}
+
+// accruePoints credits the customer with the loyalty points earned by paying amount for the reference,
+// such as an order or a tab. Without a loyalty program nobody earns points
+func (o *OrderService) accruePoints(customerID uuid.UUID, reference uuid.UUID, amount float64) error {
+	if o.loyalty == nil {
+		return nil
+	}
+	points := o.loyalty.Accrue(amount)
+	if points == 0 {
+		return nil
+	}
+
+	_, err := o.customers.Change(customerID, func(c *aggregate.Customer) error {
+		if err := c.AddPoints(points); err != nil {
+			return err
+		}
+		c.AddTransaction(valueobject.NewTransaction(valueobject.TransactionLoyaltyAccrual, points, reference, c.GetID()))
+		return nil
+	})
+	return err
+}
+
+// reversePoints takes back the loyalty points the customer earned for the reference, such as an order
+// or a tab, in proportion to the part of paid which is refunded. The refund is the reference of the
+// reversal, so every refund takes points back once. Points the customer spent already are not taken back
+func (o *OrderService) reversePoints(customerID, reference uuid.UUID, paid float64, refundID uuid.UUID, refunded float64) error {
+	if toCents(paid) <= 0 {
+		return nil
+	}
+	_, err := o.customers.Change(customerID, func(c *aggregate.Customer) error {
+		var earned int
+		for _, t := range c.GetTransactions() {
+			switch {
+			case t.GetKind() == valueobject.TransactionLoyaltyRedemption && t.GetTo() == refundID:
+				return nil
+			case t.GetKind() == valueobject.TransactionLoyaltyAccrual && t.GetFrom() == reference:
+				earned += t.GetAmount()
+			}
+		}
+		points := min(earned*toCents(refunded)/toCents(paid), c.GetPoints())
+		if points <= 0 {
+			return nil
+		}
+		if err := c.RedeemPoints(points); err != nil {
+			return err
+		}
+		c.AddTransaction(valueobject.NewTransaction(valueobject.TransactionLoyaltyRedemption, points, c.GetID(), refundID))
+		return nil
+	})
+	return err
+}
+
+// ChangePrice schedules a new price of a product from effectiveFrom on
+// Orders already placed keep the price they were placed with
+func (o *OrderService) ChangePrice(productID uuid.UUID, price float64, effectiveFrom time.Time) error {
+	o.productMu.Lock()
+	defer o.productMu.Unlock()
+
+	p, err := o.products.GetByID(productID)
+	if err != nil {
+		return err
+	}
+	if err := p.ChangePrice(price, effectiveFrom); err != nil {
+		return err
+	}
+	return o.products.Update(p)
+}
+
+// ChangeVariantPrice schedules a new price of a variant of a product from effectiveFrom on
+// Orders already placed keep the price they were placed with
+func (o *OrderService) ChangeVariantPrice(productID, variantID uuid.UUID, price float64, effectiveFrom time.Time) error {
+	o.productMu.Lock()
+	defer o.productMu.Unlock()
+
+	p, err := o.products.GetByID(productID)
+	if err != nil {
+		return err
+	}
+	if err := p.ChangeVariantPrice(variantID, price, effectiveFrom); err != nil {
+		return err
+	}
+	return o.products.Update(p)
+}
+
+// SetProductAvailability 86es a product or makes it orderable again
+func (o *OrderService) SetProductAvailability(productID uuid.UUID, available bool) error {
+	o.productMu.Lock()
+	defer o.productMu.Unlock()
+
+	p, err := o.products.GetByID(productID)
+	if err != nil {
+		return err
+	}
+	if available {
+		p.MarkAvailable()
+	} else {
+		p.MarkUnavailable()
+	}
+	return o.products.Update(p)
+}
+
+// PrepareOrder moves a placed order into the kitchen
+func (o *OrderService) PrepareOrder(orderID uuid.UUID) error {
+	return o.advance(orderID, (*aggregate.Order).Prepare)
+}
+
+// ServeOrder marks an order as served
+func (o *OrderService) ServeOrder(orderID uuid.UUID) error {
+	return o.advance(orderID, (*aggregate.Order).Serve)
+}
+
+// PayOrder marks a billed order as paid
+func (o *OrderService) PayOrder(orderID uuid.UUID) error {
+	return o.advance(orderID, (*aggregate.Order).Pay)
+}
+
+// RefundOrder marks a paid order as refunded
+func (o *OrderService) RefundOrder(orderID uuid.UUID) error {
+	return o.advance(orderID, (*aggregate.Order).Refund)
+}
+
+// CancelOrder cancels an order which has not been served, paid or invoiced yet
+// If the kitchen has not started preparing it, the stock is released back to the products
+func (o *OrderService) CancelOrder(orderID uuid.UUID) error {
+	o.orderMu.Lock()
+	defer o.orderMu.Unlock()
+
+	ord, err := o.orders.Get(orderID)
+	if err != nil {
+		return err
+	}
+	placed := ord.GetStatus() == aggregate.OrderPlaced
+	if err := ord.Cancel(); err != nil {
+		return err
+	}
+	if placed {
+		if err := o.releaseStock(ord.GetItems()); err != nil {
+			return err
+		}
+	}
+	if err := o.orders.Update(ord); err != nil {
+		return err
+	}
+	// the cancelled order is never paid, so its coupons can be used again
+	o.pricing.Release(pricing.Order{Customer: ord.GetCustomerID(), Coupons: ord.GetCoupons()})
+	if o.kitchen != nil {
+		if err := o.kitchen.Withdraw(orderID); err != nil {
+			log.Printf("taking order %s off the kitchen queues failed: %v", orderID, err)
+		}
+	}
+	// the cancelled order is never paid, so the customer gets the redeemed points back
+	return o.restorePoints(ord)
+}
+
+// SplitOrder divides the total of a billed order between several customers
+// and returns one transaction per payer. Line items are addressed by their position in the order
+func (o *OrderService) SplitOrder(orderID uuid.UUID, split Split) ([]valueobject.Transaction, error) {
+	ord, err := o.orders.Get(orderID)
+	if err != nil {
+		return nil, err
+	}
+	// a cancelled order is never paid, so only paid and refunded orders were billed
+	if st := ord.GetStatus(); st != aggregate.OrderPaid && st != aggregate.OrderRefunded {
+		return nil, fmt.Errorf("order %s is %s: %w", orderID, st, ErrSplitNotBilled)
+	}
+	return o.splitBill(split, ord.GetID(), ord.Total(), ord.GetItems())
+}
+
+// splitBill splits a bill and makes sure every payer is a known customer
+func (o *OrderService) splitBill(split Split, reference uuid.UUID, total float64, items []valueobject.OrderItem) ([]valueobject.Transaction, error) {
+	transactions, err := splitTransactions(split, reference, total, items)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range transactions {
+		if _, err := o.customers.Get(t.GetFrom()); err != nil {
+			return nil, err
+		}
+	}
+	return transactions, nil
+}
+
+// advance loads an order, applies the transition and stores the order again
+// Changes to orders are serialized, so a concurrent change is never overwritten
+func (o *OrderService) advance(orderID uuid.UUID, transition func(*aggregate.Order) error) error {
+	o.orderMu.Lock()
+	defer o.orderMu.Unlock()
+
+	ord, err := o.orders.Get(orderID)
+	if err != nil {
+		return err
+	}
+	if err := transition(&ord); err != nil {
+		return err
+	}
+	return o.orders.Update(ord)
+}
+
+// takeStock removes the ordered quantities from the products or their variants.
+// All products are checked before any of them is updated, while no other stock change runs
+func (o *OrderService) takeStock(items []valueobject.OrderItem) error {
+	o.productMu.Lock()
+	defer o.productMu.Unlock()
+
+	products, err := o.stockChanges(items, func(p *aggregate.Product, variant uuid.UUID, n int) error {
+		if variant == uuid.Nil {
+			return p.RemoveStock(n)
+		}
+		return p.RemoveVariantStock(variant, n)
+	})
+	if err != nil {
+		return err
+	}
+	return o.updateProducts(products)
+}
+
+// releaseStock puts the ordered quantities back into stock
+func (o *OrderService) releaseStock(items []valueobject.OrderItem) error {
+	o.productMu.Lock()
+	defer o.productMu.Unlock()
+
+	products, err := o.stockChanges(items, func(p *aggregate.Product, variant uuid.UUID, n int) error {
+		if variant == uuid.Nil {
+			return p.AddStock(n)
+		}
+		return p.AddVariantStock(variant, n)
+	})
+	if err != nil {
+		return err
+	}
+	return o.updateProducts(products)
+}
+
+// stockKey identifies the stock of a product or one of its variants
+type stockKey struct {
+	product uuid.UUID
+	variant uuid.UUID
+}
+
+// stockChanges sums up the quantity per product and variant and applies change to a fresh copy of every product
+func (o *OrderService) stockChanges(items []valueobject.OrderItem, change func(p *aggregate.Product, variant uuid.UUID, n int) error) ([]aggregate.Product, error) {
+	quantities := make(map[stockKey]int)
+	var keys []stockKey
+	var ids []uuid.UUID
+	seen := make(map[uuid.UUID]bool)
+	for _, i := range items {
+		key := stockKey{product: i.ProductID, variant: i.VariantID}
+		if _, ok := quantities[key]; !ok {
+			keys = append(keys, key)
+		}
+		quantities[key] += i.Quantity
+		if !seen[i.ProductID] {
+			ids = append(ids, i.ProductID)
+			seen[i.ProductID] = true
+		}
+	}
+
+	products, err := o.products.GetByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[uuid.UUID]int, len(products))
+	for i, p := range products {
+		index[p.GetID()] = i
+	}
+	for _, key := range keys {
+		p := &products[index[key.product]]
+		if err := change(p, key.variant, quantities[key]); err != nil {
+			return nil, fmt.Errorf("product %s: %w", p.GetItem().Name, err)
+		}
+	}
+	return products, nil
+}
+
+func (o *OrderService) updateProducts(products []aggregate.Product) error {
+	for _, p := range products {
+		if err := o.products.Update(p); err != nil {
+			return err
+		}
+	}
+	return nil
 }
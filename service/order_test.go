@@ -1,8 +1,16 @@
 package service
 
 import (
+	"errors"
+	"math"
+	"sync"
 	"taverne/aggregate"
+	"taverne/domain/pricing"
+	"taverne/domain/product"
+	"taverne/domain/tax"
+	"taverne/valueobject"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -25,6 +33,11 @@ func init_products(t *testing.T) []aggregate.Product {
 	products := []aggregate.Product{
 		beer, peenuts, wine,
 	}
+	for i := range products {
+		if err := products[i].AddStock(10); err != nil {
+			t.Error(err)
+		}
+	}
 	return products
 }
 
@@ -51,14 +64,882 @@ func TestOrder_NewOrderService(t *testing.T) {
 	}
 
 	// Perform order
-	order := []uuid.UUID{
-		products[0].GetID(),
+	order := OrderRequest{
+		CustomerID: cust.GetID(),
+		Lines: []valueobject.OrderLine{
+			{ProductID: products[0].GetID(), Quantity: 1},
+		},
 	}
 
-	_, err = os.CreateOrder(cust.GetID(), order)
+	_, err = os.CreateOrder(order)
 
 	if err != nil {
 		t.Error(err)
 	}
 
 }
+
+func TestOrder_CreateOrderMissingProducts(t *testing.T) {
+	products := init_products(t)
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cust, err := aggregate.NewCustomer("Donald")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customers.Add(cust); err != nil {
+		t.Fatal(err)
+	}
+
+	missingA, missingB := uuid.New(), uuid.New()
+	order := OrderRequest{
+		CustomerID: cust.GetID(),
+		Lines: []valueobject.OrderLine{
+			{ProductID: missingA, Quantity: 1},
+			{ProductID: products[0].GetID(), Quantity: 1},
+			{ProductID: missingB, Quantity: 1},
+		},
+	}
+
+	_, err = os.CreateOrder(order)
+	if !errors.Is(err, product.ErrProductNotFound) {
+		t.Fatalf("Expected error %v, got %v", product.ErrProductNotFound, err)
+	}
+	var merr *product.MissingProductsError
+	if !errors.As(err, &merr) || len(merr.IDs) != 2 {
+		t.Errorf("Expected both missing products to be reported, got %v", err)
+	}
+}
+
+func TestOrder_CreateOrderLines(t *testing.T) {
+	products := init_products(t)
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cust, err := aggregate.NewCustomer("Donald")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customers.Add(cust); err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		name          string
+		lines         []valueobject.OrderLine
+		expectedPrice float64
+		expectedErr   error
+	}
+
+	testCases := []testCase{
+		{
+			name:        "Empty order",
+			lines:       nil,
+			expectedErr: ErrEmptyOrder,
+		},
+		{
+			name: "Zero quantity",
+			lines: []valueobject.OrderLine{
+				{ProductID: products[0].GetID(), Quantity: 0},
+			},
+			expectedErr: ErrInvalidQuantity,
+		},
+		{
+			name: "Negative quantity",
+			lines: []valueobject.OrderLine{
+				{ProductID: products[0].GetID(), Quantity: 1},
+				{ProductID: products[1].GetID(), Quantity: -2},
+			},
+			expectedErr: ErrInvalidQuantity,
+		},
+		{
+			name: "Two ales and peanuts without salt",
+			lines: []valueobject.OrderLine{
+				{ProductID: products[0].GetID(), Quantity: 2},
+				{ProductID: products[1].GetID(), Quantity: 1, Notes: "no salt"},
+			},
+			expectedPrice: 2*1.99 + 0.99,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			order, err := os.CreateOrder(OrderRequest{CustomerID: cust.GetID(), Lines: tc.lines})
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+			if price := order.Total(); math.Abs(price-tc.expectedPrice) > 0.0001 {
+				t.Errorf("Expected price %.2f, got %.2f", tc.expectedPrice, price)
+			}
+		})
+	}
+}
+
+func TestOrder_Lifecycle(t *testing.T) {
+	products := init_products(t)
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+		WithMemoryOrderRepository(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cust, err := aggregate.NewCustomer("Donald")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customers.Add(cust); err != nil {
+		t.Fatal(err)
+	}
+
+	beer := products[0].GetID()
+	stock := func() int {
+		p, err := os.products.GetByID(beer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p.GetQuantity()
+	}
+	place := func(quantity int) uuid.UUID {
+		order, err := os.CreateOrder(OrderRequest{
+			CustomerID: cust.GetID(),
+			Lines:      []valueobject.OrderLine{{ProductID: beer, Quantity: quantity}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return order.GetID()
+	}
+
+	t.Run("Order takes stock", func(t *testing.T) {
+		place(3)
+		if got := stock(); got != 7 {
+			t.Errorf("Expected 7 in stock, got %d", got)
+		}
+	})
+
+	t.Run("Insufficient stock", func(t *testing.T) {
+		_, err := os.CreateOrder(OrderRequest{
+			CustomerID: cust.GetID(),
+			Lines: []valueobject.OrderLine{
+				{ProductID: beer, Quantity: 5},
+				{ProductID: beer, Quantity: 5},
+			},
+		})
+		if !errors.Is(err, aggregate.ErrInsufficientStock) {
+			t.Errorf("Expected error %v, got %v", aggregate.ErrInsufficientStock, err)
+		}
+		if got := stock(); got != 7 {
+			t.Errorf("Expected 7 in stock, got %d", got)
+		}
+	})
+
+	t.Run("Cancel before preparation releases stock", func(t *testing.T) {
+		id := place(2)
+		if err := os.CancelOrder(id); err != nil {
+			t.Fatal(err)
+		}
+		if got := stock(); got != 7 {
+			t.Errorf("Expected 7 in stock, got %d", got)
+		}
+	})
+
+	t.Run("Cancel during preparation keeps stock", func(t *testing.T) {
+		id := place(2)
+		if err := os.PrepareOrder(id); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.CancelOrder(id); err != nil {
+			t.Fatal(err)
+		}
+		if got := stock(); got != 5 {
+			t.Errorf("Expected 5 in stock, got %d", got)
+		}
+	})
+
+	t.Run("Full lifecycle", func(t *testing.T) {
+		id := place(1)
+		for _, step := range []func(uuid.UUID) error{os.PrepareOrder, os.ServeOrder, os.PayOrder, os.RefundOrder} {
+			if err := step(id); err != nil {
+				t.Fatal(err)
+			}
+		}
+		order, err := os.orders.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if order.GetStatus() != aggregate.OrderRefunded {
+			t.Errorf("Expected status %s, got %s", aggregate.OrderRefunded, order.GetStatus())
+		}
+		if err := os.CancelOrder(id); !errors.Is(err, aggregate.ErrIllegalTransition) {
+			t.Errorf("Expected error %v, got %v", aggregate.ErrIllegalTransition, err)
+		}
+	})
+}
+
+func TestOrder_CreateOrderPricing(t *testing.T) {
+	products := init_products(t)
+	beer := products[0]
+
+	happyHour := time.Date(2026, 10, 19, 18, 0, 0, 0, time.UTC)
+	coupons := pricing.NewCouponBook(pricing.Coupon{Code: "WELCOME", Amount: 1, MaxUses: 1})
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+		WithClock(func() time.Time { return happyHour }),
+		WithPricingRules(
+			pricing.HappyHour(17, 19, 20, pricing.Products(beer.GetID())),
+			coupons,
+		),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cust, err := aggregate.NewCustomer("Donald")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customers.Add(cust); err != nil {
+		t.Fatal(err)
+	}
+
+	order, err := os.CreateOrder(OrderRequest{
+		CustomerID: cust.GetID(),
+		Lines: []valueobject.OrderLine{
+			{ProductID: beer.GetID(), Quantity: 5},
+			{ProductID: products[1].GetID(), Quantity: 1},
+		},
+		Coupons: []string{"WELCOME"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 5 × 1.99 = 9.95, 20% off = 1.99, coupon 1.00
+	discounts := order.GetDiscounts()
+	if len(discounts) != 2 {
+		t.Fatalf("Expected 2 itemised discounts, got %v", discounts)
+	}
+	if discounts[0].Amount != 1.99 || discounts[0].ProductID != beer.GetID() {
+		t.Errorf("Expected happy hour discount of 1.99 on beer, got %v", discounts[0])
+	}
+	expected := 9.95 + 0.99 - 1.99 - 1
+	if math.Abs(order.Total()-expected) > 0.0001 {
+		t.Errorf("Expected total %.2f, got %.2f", expected, order.Total())
+	}
+
+	// An unknown coupon fails the order and hands the stock back
+	_, err = os.CreateOrder(OrderRequest{
+		CustomerID: cust.GetID(),
+		Lines:      []valueobject.OrderLine{{ProductID: beer.GetID(), Quantity: 1}},
+		Coupons:    []string{"NOPE"},
+	})
+	if !errors.Is(err, pricing.ErrCouponUnknown) {
+		t.Fatalf("Expected error %v, got %v", pricing.ErrCouponUnknown, err)
+	}
+	p, err := os.products.GetByID(beer.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.GetQuantity() != 5 {
+		t.Errorf("Expected 5 beers in stock, got %d", p.GetQuantity())
+	}
+
+	// A cancelled order gives its coupon back
+	if uses := coupons.Uses("WELCOME"); uses != 1 {
+		t.Errorf("Expected coupon to be used once, got %d", uses)
+	}
+	if err := os.CancelOrder(order.GetID()); err != nil {
+		t.Fatal(err)
+	}
+	if uses := coupons.Uses("WELCOME"); uses != 0 {
+		t.Errorf("Expected coupon to be released, got %d uses", uses)
+	}
+	if _, err := os.CreateOrder(OrderRequest{
+		CustomerID: cust.GetID(),
+		Lines:      []valueobject.OrderLine{{ProductID: beer.GetID(), Quantity: 1}},
+		Coupons:    []string{"WELCOME"},
+	}); err != nil {
+		t.Errorf("Expected released coupon to be usable again, got %v", err)
+	}
+}
+
+func TestOrder_CreateOrderTax(t *testing.T) {
+	products := init_products(t)
+	products[0].SetTaxCategory(valueobject.TaxBeverage)
+	products[1].SetTaxCategory(valueobject.TaxFood)
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+		WithTaxTable(tax.DefaultTable(), tax.RoundPerInvoice),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cust, err := aggregate.NewCustomer("Donald")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customers.Add(cust); err != nil {
+		t.Fatal(err)
+	}
+
+	order, err := os.CreateOrder(OrderRequest{
+		CustomerID: cust.GetID(),
+		Lines: []valueobject.OrderLine{
+			{ProductID: products[0].GetID(), Quantity: 2},
+			{ProductID: products[1].GetID(), Quantity: 1},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := order.GetTaxBreakdown()
+	if len(b.Rates) != 2 {
+		t.Fatalf("Expected 2 tax rates, got %v", b.Rates)
+	}
+	// 0.99 food at 7%, 3.98 beverages at 19%
+	if b.Rates[0].Rate != 7 || b.Rates[0].Tax != 0.06 {
+		t.Errorf("Expected 0.06 tax at 7%%, got %v", b.Rates[0])
+	}
+	if b.Rates[1].Rate != 19 || b.Rates[1].Tax != 0.64 {
+		t.Errorf("Expected 0.64 tax at 19%%, got %v", b.Rates[1])
+	}
+	if math.Abs(b.Gross-order.Total()) > 0.0001 {
+		t.Errorf("Expected gross %.2f, got %.2f", order.Total(), b.Gross)
+	}
+}
+
+func TestOrder_CreateOrderVariants(t *testing.T) {
+	ale, err := aggregate.NewProduct("Ale", "Dark and cloudy", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	small, err := ale.AddVariant("0.3l", 3.2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	large, err := ale.AddVariant("0.5l", 4.8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []uuid.UUID{small, large} {
+		if err := ale.AddVariantStock(v, 5); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lemon, err := ale.AddModifier("Lemon slice", 0.3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository([]aggregate.Product{ale}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cust, err := aggregate.NewCustomer("Donald")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customers.Add(cust); err != nil {
+		t.Fatal(err)
+	}
+
+	order, err := os.CreateOrder(OrderRequest{
+		CustomerID: cust.GetID(),
+		Lines: []valueobject.OrderLine{
+			{ProductID: ale.GetID(), VariantID: small, Quantity: 2},
+			{ProductID: ale.GetID(), VariantID: large, ModifierIDs: []uuid.UUID{lemon}, Quantity: 1},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items := order.GetItems()
+	if items[1].Variant != "0.5l" || len(items[1].Modifiers) != 1 || items[1].UnitPrice != 5.1 {
+		t.Errorf("Expected 0.5l with lemon at 5.10, got %v", items[1])
+	}
+	if math.Abs(order.Total()-(2*3.2+5.1)) > 0.0001 {
+		t.Errorf("Expected total %.2f, got %.2f", 2*3.2+5.1, order.Total())
+	}
+
+	stored, err := os.products.GetByID(ale.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for variant, expected := range map[uuid.UUID]int{small: 3, large: 4} {
+		if v, _ := stored.GetVariant(variant); v.Quantity != expected {
+			t.Errorf("Expected %d of %s in stock, got %d", expected, v.Name, v.Quantity)
+		}
+	}
+
+	_, err = os.CreateOrder(OrderRequest{
+		CustomerID: cust.GetID(),
+		Lines:      []valueobject.OrderLine{{ProductID: ale.GetID(), Quantity: 1}},
+	})
+	if !errors.Is(err, aggregate.ErrVariantRequired) {
+		t.Errorf("Expected error %v, got %v", aggregate.ErrVariantRequired, err)
+	}
+}
+
+func TestOrder_CreateOrderUnavailable(t *testing.T) {
+	products := init_products(t)
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cust, err := aggregate.NewCustomer("Donald")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customers.Add(cust); err != nil {
+		t.Fatal(err)
+	}
+
+	beer, wine := products[0].GetID(), products[2].GetID()
+	if err := os.SetProductAvailability(beer, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.products.Delete(wine); err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		name        string
+		id          uuid.UUID
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			name:        "86'd product",
+			id:          beer,
+			expectedErr: aggregate.ErrProductUnavailable,
+		},
+		{
+			name:        "Archived product",
+			id:          wine,
+			expectedErr: aggregate.ErrProductUnavailable,
+		},
+		{
+			name:        "Available product",
+			id:          products[1].GetID(),
+			expectedErr: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := os.CreateOrder(OrderRequest{
+				CustomerID: cust.GetID(),
+				Lines:      []valueobject.OrderLine{{ProductID: tc.id, Quantity: 1}},
+			})
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+
+	// Back on tap and back on the menu
+	if err := os.SetProductAvailability(beer, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.products.Restore(wine); err != nil {
+		t.Fatal(err)
+	}
+	_, err = os.CreateOrder(OrderRequest{
+		CustomerID: cust.GetID(),
+		Lines: []valueobject.OrderLine{
+			{ProductID: beer, Quantity: 1},
+			{ProductID: wine, Quantity: 1},
+		},
+	})
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
+
+func TestOrder_CreateOrderPriceAtOrderTime(t *testing.T) {
+	products := init_products(t)
+	beer := products[0].GetID()
+
+	now := time.Date(2026, 10, 19, 20, 0, 0, 0, time.UTC)
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+		WithClock(func() time.Time { return now }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cust, err := aggregate.NewCustomer("Donald")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customers.Add(cust); err != nil {
+		t.Fatal(err)
+	}
+
+	// beer gets more expensive tomorrow
+	tomorrow := now.Add(24 * time.Hour)
+	if err := os.ChangePrice(beer, 2.49, tomorrow); err != nil {
+		t.Fatal(err)
+	}
+
+	req := OrderRequest{
+		CustomerID: cust.GetID(),
+		Lines:      []valueobject.OrderLine{{ProductID: beer, Quantity: 1}},
+	}
+	today, err := os.CreateOrder(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now = tomorrow
+	later, err := os.CreateOrder(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := os.orders.Get(today.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Total() != 1.99 {
+		t.Errorf("Expected the first order to keep 1.99, got %.2f", stored.Total())
+	}
+	if later.Total() != 2.49 {
+		t.Errorf("Expected the second order at 2.49, got %.2f", later.Total())
+	}
+}
+
+func TestOrder_CreateOrderVariantPriceAtOrderTime(t *testing.T) {
+	ale, err := aggregate.NewProduct("Ale", "Brewed in the cellar", 3.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	small, err := ale.AddVariant("0.3l", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ale.AddVariantStock(small, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2026, 10, 19, 20, 0, 0, 0, time.UTC)
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository([]aggregate.Product{ale}),
+		WithClock(func() time.Time { return now }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cust, err := aggregate.NewCustomer("Donald")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customers.Add(cust); err != nil {
+		t.Fatal(err)
+	}
+
+	// the small ale gets more expensive tomorrow
+	tomorrow := now.Add(24 * time.Hour)
+	if err := os.ChangeVariantPrice(ale.GetID(), small, 3.2, tomorrow); err != nil {
+		t.Fatal(err)
+	}
+
+	req := OrderRequest{
+		CustomerID: cust.GetID(),
+		Lines:      []valueobject.OrderLine{{ProductID: ale.GetID(), VariantID: small, Quantity: 1}},
+	}
+	today, err := os.CreateOrder(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now = tomorrow
+	later, err := os.CreateOrder(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if today.Total() != 3 {
+		t.Errorf("Expected the first order at 3.00, got %.2f", today.Total())
+	}
+	if later.Total() != 3.2 {
+		t.Errorf("Expected the second order at 3.20, got %.2f", later.Total())
+	}
+}
+
+func TestOrder_CreateOrderAgeRestricted(t *testing.T) {
+	products := init_products(t)
+	// Wine is only served to adults
+	if err := products[2].SetMinimumAge(18); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, time.June, 15, 20, 0, 0, 0, time.UTC)
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+		WithClock(func() time.Time { return now }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newCustomer := func(birthDate time.Time) uuid.UUID {
+		cust, err := aggregate.NewCustomer("Huey")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !birthDate.IsZero() {
+			if err := cust.SetBirthDate(birthDate); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := os.customers.Add(cust); err != nil {
+			t.Fatal(err)
+		}
+		return cust.GetID()
+	}
+
+	type testCase struct {
+		name        string
+		customer    uuid.UUID
+		product     uuid.UUID
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			name:        "Unverified customer orders wine",
+			customer:    newCustomer(time.Time{}),
+			product:     products[2].GetID(),
+			expectedErr: aggregate.ErrAgeUnverified,
+		},
+		{
+			name:        "Unverified customer orders beer",
+			customer:    newCustomer(time.Time{}),
+			product:     products[0].GetID(),
+			expectedErr: nil,
+		},
+		{
+			name:        "Turns 18 tomorrow",
+			customer:    newCustomer(time.Date(2006, time.June, 16, 0, 0, 0, 0, time.UTC)),
+			product:     products[2].GetID(),
+			expectedErr: ErrUnderAge,
+		},
+		{
+			name:        "Turns 18 today",
+			customer:    newCustomer(time.Date(2006, time.June, 15, 0, 0, 0, 0, time.UTC)),
+			product:     products[2].GetID(),
+			expectedErr: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := os.CreateOrder(OrderRequest{
+				CustomerID: tc.customer,
+				Lines:      []valueobject.OrderLine{{ProductID: tc.product, Quantity: 1}},
+			})
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestOrder_CreateOrderAllergens(t *testing.T) {
+	products := init_products(t)
+	if err := products[1].SetAllergens(valueobject.AllergenPeanuts); err != nil {
+		t.Fatal(err)
+	}
+	if err := products[0].SetAllergens(valueobject.AllergenGluten); err != nil {
+		t.Fatal(err)
+	}
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cust, err := aggregate.NewCustomer("Dewey", aggregate.WithAllergies(valueobject.AllergenPeanuts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customers.Add(cust); err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		name        string
+		product     uuid.UUID
+		acknowledge bool
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			name:        "No allergy to the product",
+			product:     products[0].GetID(),
+			expectedErr: nil,
+		},
+		{
+			name:        "Allergic to the product",
+			product:     products[1].GetID(),
+			expectedErr: ErrAllergenConflict,
+		},
+		{
+			name:        "Acknowledged allergy",
+			product:     products[1].GetID(),
+			acknowledge: true,
+			expectedErr: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := os.CreateOrder(OrderRequest{
+				CustomerID:           cust.GetID(),
+				Lines:                []valueobject.OrderLine{{ProductID: tc.product, Quantity: 1}},
+				AcknowledgeAllergens: tc.acknowledge,
+			})
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+
+	// the error names the product and the allergens, so the staff can warn the customer
+	_, err = os.CreateOrder(OrderRequest{
+		CustomerID: cust.GetID(),
+		Lines:      []valueobject.OrderLine{{ProductID: products[1].GetID(), Quantity: 1}},
+	})
+	var conflict *AllergenConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("Expected an allergen conflict, got %v", err)
+	}
+	if len(conflict.Conflicts) != 1 || conflict.Conflicts[0].Name != "Peenuts" {
+		t.Errorf("Expected a conflict with Peenuts, got %v", conflict.Conflicts)
+	}
+	// nothing was taken out of stock for the refused order
+	p, err := os.products.GetByID(products[1].GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.GetQuantity() != 9 {
+		t.Errorf("Expected 9 Peenuts in stock, got %d", p.GetQuantity())
+	}
+}
+
+func TestOrder_CreateOrderConcurrentStock(t *testing.T) {
+	products := init_products(t)
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cust, err := aggregate.NewCustomer("Donald")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customers.Add(cust); err != nil {
+		t.Fatal(err)
+	}
+
+	// ten beers are in stock, so only ten of the orders may take one
+	const orders = 25
+	var wg sync.WaitGroup
+	placed := make(chan aggregate.Order, orders)
+	for i := 0; i < orders; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			order, err := os.CreateOrder(OrderRequest{
+				CustomerID: cust.GetID(),
+				Lines:      []valueobject.OrderLine{{ProductID: products[0].GetID(), Quantity: 1}},
+			})
+			if err == nil {
+				placed <- order
+			} else if !errors.Is(err, aggregate.ErrInsufficientStock) {
+				t.Errorf("Expected error %v, got %v", aggregate.ErrInsufficientStock, err)
+			}
+		}()
+	}
+	wg.Wait()
+	close(placed)
+
+	if len(placed) != 10 {
+		t.Errorf("Expected 10 orders, got %d", len(placed))
+	}
+	p, err := os.products.GetByID(products[0].GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.GetQuantity() != 0 {
+		t.Errorf("Expected no beer left in stock, got %d", p.GetQuantity())
+	}
+
+	// a transition and another change of the same order both survive
+	order := <-placed
+	table := uuid.New()
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		if err := os.PrepareOrder(order.GetID()); err != nil {
+			t.Error(err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		if err := os.advance(order.GetID(), func(o *aggregate.Order) error {
+			o.SetTableID(table)
+			return nil
+		}); err != nil {
+			t.Error(err)
+		}
+	}()
+	wg.Wait()
+	stored, err := os.orders.Get(order.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.GetStatus() != aggregate.OrderPreparing || stored.GetTableID() != table {
+		t.Errorf("Expected a preparing order at table %s, got %s at %s", table, stored.GetStatus(), stored.GetTableID())
+	}
+}
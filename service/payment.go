@@ -0,0 +1,191 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"taverne/domain/payment"
+	"taverne/valueobject"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrUnknownPaymentMethod is returned when a bill is paid by a method without a gateway
+	ErrUnknownPaymentMethod = errors.New("no payment gateway for the payment method")
+	// ErrPaymentNotFound is returned when a reference is refunded which was not paid through a gateway
+	ErrPaymentNotFound = errors.New("the reference was not paid through a payment gateway")
+	// ErrPaymentUnknown is returned when the gateway did not answer whether a payment was captured,
+	// billing the same customer and reference again settles it without charging twice
+	ErrPaymentUnknown = errors.New("the outcome of the payment is unknown")
+)
+
+// PaymentConfiguration is an alias for a function that will take in a pointer to a PaymentBilling and modify it
+type PaymentConfiguration func(pb *PaymentBilling) error
+
+// PaymentBilling is a BillingService which takes the money through payment gateways.
+// The reference and the customer of a bill make up the idempotency key, so retrying a bill
+// never charges twice while every payer of a split bill is charged under a key of their own
+type PaymentBilling struct {
+	gateways map[payment.Method]payment.Gateway
+	// method is used for bills which do not name a payment method
+	method payment.Method
+	// retries is how often a call which timed out is repeated
+	retries int
+	// payments remembers how every customer paid a reference, so it can be refunded
+	payments map[payer]*gatewayPayment
+	mu       sync.Mutex
+}
+
+// payer is a customer paying for a reference, several customers can pay for the same reference
+type payer struct {
+	customer  uuid.UUID
+	reference uuid.UUID
+}
+
+// key is the idempotency key of the payment of the payer
+func (p payer) key() string {
+	return p.reference.String() + "/" + p.customer.String()
+}
+
+// gatewayPayment is a captured authorization of a gateway
+type gatewayPayment struct {
+	method payment.Method
+	id     string
+}
+
+// NewPaymentBilling takes a variable amount of PaymentConfigurations and builds a PaymentBilling,
+// cash is taken through a payment.CashGateway unless another gateway is configured
+func NewPaymentBilling(cfgs ...PaymentConfiguration) (*PaymentBilling, error) {
+	pb := &PaymentBilling{
+		gateways: make(map[payment.Method]payment.Gateway),
+		method:   payment.MethodCash,
+		retries:  2,
+		payments: make(map[payer]*gatewayPayment),
+	}
+	for _, cfg := range cfgs {
+		if err := cfg(pb); err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := pb.gateways[payment.MethodCash]; !ok {
+		pb.gateways[payment.MethodCash] = payment.NewCashGateway()
+	}
+	return pb, nil
+}
+
+// WithPaymentGateway takes the payments of method through the gateway
+func WithPaymentGateway(method payment.Method, g payment.Gateway) PaymentConfiguration {
+	return func(pb *PaymentBilling) error {
+		pb.gateways[method] = g
+		return nil
+	}
+}
+
+// WithDefaultPaymentMethod applies the method of bills which do not name one
+func WithDefaultPaymentMethod(method payment.Method) PaymentConfiguration {
+	return func(pb *PaymentBilling) error {
+		pb.method = method
+		return nil
+	}
+}
+
+// WithPaymentRetries applies how often a call to a gateway which timed out is repeated
+func WithPaymentRetries(retries int) PaymentConfiguration {
+	return func(pb *PaymentBilling) error {
+		pb.retries = retries
+		return nil
+	}
+}
+
+// Bill takes the amount by the default payment method
+func (pb *PaymentBilling) Bill(customer uuid.UUID, reference uuid.UUID, amount float64) error {
+	return pb.BillWith(pb.method, customer, reference, amount)
+}
+
+// BillWith authorizes and captures the amount through the gateway of the payment method.
+// An authorization which can not be captured is voided. If it stays unknown whether the capture
+// went through, or the capture timed out and can be retried, the authorization is left alone
+// and ErrPaymentUnknown is returned
+func (pb *PaymentBilling) BillWith(method payment.Method, customer uuid.UUID, reference uuid.UUID, amount float64) error {
+	if toCents(amount) == 0 {
+		return nil
+	}
+	g, ok := pb.gateways[method]
+	if !ok {
+		return fmt.Errorf("%s: %w", method, ErrUnknownPaymentMethod)
+	}
+
+	pp := payer{customer: customer, reference: reference}
+	var auth payment.Authorization
+	err := pb.retry(func() (err error) {
+		auth, err = g.Authorize(pp.key(), customer, toCents(amount))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	err = pb.retry(func() error {
+		_, err := g.Capture(auth.ID)
+		return err
+	})
+	if errors.Is(err, payment.ErrTimeout) {
+		// the capture may have gone through, so the authorization of the key tells whether it did
+		var current payment.Authorization
+		lerr := pb.retry(func() (err error) {
+			current, err = g.Authorize(pp.key(), customer, toCents(amount))
+			return err
+		})
+		switch {
+		case lerr != nil:
+			return fmt.Errorf("%w: %w", ErrPaymentUnknown, errors.Join(err, lerr))
+		case current.Status == payment.StatusCaptured:
+			err = nil
+		case current.Status == payment.StatusAuthorized:
+			// the capture can still be retried, so the authorization is kept for billing the key again
+			return fmt.Errorf("%w: %w", ErrPaymentUnknown, err)
+		}
+	}
+	if err != nil {
+		if verr := pb.retry(func() error {
+			_, err := g.Void(auth.ID)
+			return err
+		}); verr != nil {
+			return errors.Join(err, verr)
+		}
+		return err
+	}
+
+	pb.mu.Lock()
+	pb.payments[pp] = &gatewayPayment{method: method, id: auth.ID}
+	pb.mu.Unlock()
+	return nil
+}
+
+// Refund pays amount back through the gateway the customer paid the reference with.
+// The refund ID is the idempotency key, so paying the same refund again never pays back twice
+func (pb *PaymentBilling) Refund(customer uuid.UUID, reference uuid.UUID, refundID uuid.UUID, amount float64) (valueobject.Transaction, error) {
+	pb.mu.Lock()
+	p, ok := pb.payments[payer{customer: customer, reference: reference}]
+	pb.mu.Unlock()
+	if !ok {
+		return valueobject.Transaction{}, ErrPaymentNotFound
+	}
+	err := pb.retry(func() error {
+		_, err := pb.gateways[p.method].Refund(p.id, refundID.String(), toCents(amount))
+		return err
+	})
+	if err != nil {
+		return valueobject.Transaction{}, err
+	}
+	return valueobject.NewTransaction(valueobject.TransactionRefund, -toCents(amount), customer, reference), nil
+}
+
+// retry calls f again as long as it times out, up to the configured number of retries
+func (pb *PaymentBilling) retry(f func() error) error {
+	err := f()
+	for i := 0; i < pb.retries && errors.Is(err, payment.ErrTimeout); i++ {
+		err = f()
+	}
+	return err
+}
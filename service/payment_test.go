@@ -0,0 +1,501 @@
+package service
+
+import (
+	"errors"
+	"taverne/aggregate"
+	"taverne/domain/payment"
+	"taverne/valueobject"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestPayment_Tavern(t *testing.T) {
+	products := init_products(t)
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the first answer of the card provider gets lost and cards are declined above 5.00
+	card, err := payment.NewFakeCardGateway(payment.WithTimeouts(1), payment.WithDeclineAbove(500))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tavern, err := NewTavern(
+		WithOrderService(os),
+		WithPaymentBilling(WithPaymentGateway(payment.MethodCard, card)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cust, err := aggregate.NewCustomer("Donald")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customers.Add(cust); err != nil {
+		t.Fatal(err)
+	}
+	beer := products[0].GetID()
+
+	type testCase struct {
+		name        string
+		method      payment.Method
+		quantity    int
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{name: "Card after a timeout", method: payment.MethodCard, quantity: 2},
+		{name: "Card declined", method: payment.MethodCard, quantity: 3, expectedErr: payment.ErrDeclined},
+		{name: "Cash", method: payment.MethodCash, quantity: 3},
+		{name: "Default method", quantity: 1},
+		{name: "Unknown method", method: "cheque", quantity: 1, expectedErr: ErrUnknownPaymentMethod},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			order, err := tavern.Order(OrderRequest{
+				CustomerID:    cust.GetID(),
+				Lines:         []valueobject.OrderLine{{ProductID: beer, Quantity: tc.quantity}},
+				PaymentMethod: tc.method,
+			})
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+			if err != nil {
+				return
+			}
+			// the retry used the order and the customer as idempotency key, so the card was authorized only once
+			if tc.method == payment.MethodCard {
+				auth, err := card.Authorize(payer{customer: cust.GetID(), reference: order.GetID()}.key(), cust.GetID(), 398)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if auth.Status != payment.StatusCaptured {
+					t.Errorf("Expected the authorization to be captured, got %s", auth.Status)
+				}
+			}
+		})
+	}
+
+	// the declined and the unknown orders were cancelled and their beers are back in stock
+	p, err := os.products.GetByID(beer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.GetQuantity() != 4 {
+		t.Errorf("Expected 4 beers in stock, got %d", p.GetQuantity())
+	}
+}
+
+func TestPayment_Refund(t *testing.T) {
+	products := init_products(t)
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	card, err := payment.NewFakeCardGateway()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tavern, err := NewTavern(
+		WithOrderService(os),
+		WithPaymentBilling(WithPaymentGateway(payment.MethodCard, card), WithDefaultPaymentMethod(payment.MethodCard)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cust, err := aggregate.NewCustomer("Donald")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customers.Add(cust); err != nil {
+		t.Fatal(err)
+	}
+
+	order, err := tavern.Order(OrderRequest{
+		CustomerID: cust.GetID(),
+		Lines:      []valueobject.OrderLine{{ProductID: products[0].GetID(), Quantity: 2}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := tavern.Refund(order.GetID(), RefundRequest{Returned: []valueobject.ReturnedItem{{Item: 0, Quantity: 1}}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	auth, err := card.Authorize(payer{customer: cust.GetID(), reference: order.GetID()}.key(), cust.GetID(), 398)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth.Refunded != 398 {
+		t.Errorf("Expected 398 cents refunded to the card, got %d", auth.Refunded)
+	}
+}
+
+func TestPayment_SplitTab(t *testing.T) {
+	products := init_products(t)
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	card, err := payment.NewFakeCardGateway()
+	if err != nil {
+		t.Fatal(err)
+	}
+	billing, err := NewPaymentBilling(WithPaymentGateway(payment.MethodCard, card), WithDefaultPaymentMethod(payment.MethodCard))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tavern, err := NewTavern(
+		WithOrderService(os),
+		WithBillingService(billing),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var customers []aggregate.Customer
+	for _, name := range []string{"Donald", "Daisy"} {
+		cust, err := aggregate.NewCustomer(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.customers.Add(cust); err != nil {
+			t.Fatal(err)
+		}
+		customers = append(customers, cust)
+	}
+	donald, daisy := customers[0].GetID(), customers[1].GetID()
+
+	// both payers of one reference are charged, each under a key of their own
+	reference := uuid.New()
+	if err := billing.Bill(donald, reference, 1.00); err != nil {
+		t.Fatal(err)
+	}
+	if err := billing.Bill(daisy, reference, 0.99); err != nil {
+		t.Errorf("Expected the second payer to be charged, got %v", err)
+	}
+
+	tabID, err := tavern.OpenTab(donald)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tavern.OrderOnTab(tabID, OrderRequest{
+		Lines: []valueobject.OrderLine{{ProductID: products[0].GetID(), Quantity: 1}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tavern.CloseTabSplit(tabID, SplitEvenly(donald, daisy)); err != nil {
+		t.Fatal(err)
+	}
+
+	for customer, cents := range map[uuid.UUID]int{donald: 100, daisy: 99} {
+		p := payer{customer: customer, reference: settlementReference(tabID, customer)}
+		auth, err := card.Authorize(p.key(), customer, cents)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if auth.Status != payment.StatusCaptured {
+			t.Errorf("Expected the share of %v to be captured, got %s", customer, auth.Status)
+		}
+	}
+}
+
+// lostAnswers processes every call through the wrapped gateway but loses the answers of the
+// first captures and of the first authorizations which look up an existing key
+type lostAnswers struct {
+	payment.Gateway
+	captures int
+	lookups  int
+	keys     map[string]bool
+}
+
+func (la *lostAnswers) Authorize(key string, customer uuid.UUID, cents int) (payment.Authorization, error) {
+	a, err := la.Gateway.Authorize(key, customer, cents)
+	if la.keys[key] && la.lookups > 0 {
+		la.lookups--
+		return payment.Authorization{}, payment.ErrTimeout
+	}
+	la.keys[key] = true
+	return a, err
+}
+
+func (la *lostAnswers) Capture(id string) (payment.Authorization, error) {
+	a, err := la.Gateway.Capture(id)
+	if la.captures > 0 {
+		la.captures--
+		return payment.Authorization{}, payment.ErrTimeout
+	}
+	return a, err
+}
+
+func TestPayment_CaptureTimeout(t *testing.T) {
+	type testCase struct {
+		name        string
+		lookups     int
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{name: "Capture found by the lookup", lookups: 0, expectedErr: nil},
+		{name: "Capture stays unknown", lookups: 1, expectedErr: ErrPaymentUnknown},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			card, err := payment.NewFakeCardGateway()
+			if err != nil {
+				t.Fatal(err)
+			}
+			gateway := &lostAnswers{Gateway: card, captures: 1, lookups: tc.lookups, keys: make(map[string]bool)}
+			billing, err := NewPaymentBilling(
+				WithPaymentGateway(payment.MethodCard, gateway),
+				WithDefaultPaymentMethod(payment.MethodCard),
+				WithPaymentRetries(0),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			customer, reference := uuid.New(), uuid.New()
+			if err := billing.Bill(customer, reference, 3.98); !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+			// the capture went through, so it must never be voided
+			key := payer{customer: customer, reference: reference}.key()
+			auth, err := card.Authorize(key, customer, 398)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if auth.Status != payment.StatusCaptured {
+				t.Errorf("Expected the authorization to be captured, got %s", auth.Status)
+			}
+
+			// billing again settles an unknown payment without charging twice
+			if err := billing.Bill(customer, reference, 3.98); err != nil {
+				t.Fatal(err)
+			}
+			refund := uuid.New()
+			for i := 0; i < 2; i++ {
+				if _, err := billing.Refund(customer, reference, refund, 1.99); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if auth, _ := card.Authorize(key, customer, 398); auth.Refunded != 199 {
+				t.Errorf("Expected the retried refund to pay back 199 cents once, got %d", auth.Refunded)
+			}
+		})
+	}
+}
+
+// unansweredCaptures times out the first captures before they reach the wrapped gateway
+type unansweredCaptures struct {
+	payment.Gateway
+	captures int
+}
+
+func (uc *unansweredCaptures) Capture(id string) (payment.Authorization, error) {
+	if uc.captures > 0 {
+		uc.captures--
+		return payment.Authorization{}, payment.ErrTimeout
+	}
+	return uc.Gateway.Capture(id)
+}
+
+func TestPayment_CaptureNotProcessed(t *testing.T) {
+	card, err := payment.NewFakeCardGateway()
+	if err != nil {
+		t.Fatal(err)
+	}
+	billing, err := NewPaymentBilling(
+		WithPaymentGateway(payment.MethodCard, &unansweredCaptures{Gateway: card, captures: 1}),
+		WithDefaultPaymentMethod(payment.MethodCard),
+		WithPaymentRetries(0),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	customer, reference := uuid.New(), uuid.New()
+	if err := billing.Bill(customer, reference, 3.98); !errors.Is(err, ErrPaymentUnknown) {
+		t.Fatalf("Expected error %v, got %v", ErrPaymentUnknown, err)
+	}
+	// the capture can be retried, so the authorization must not be voided
+	key := payer{customer: customer, reference: reference}.key()
+	auth, err := card.Authorize(key, customer, 398)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth.Status != payment.StatusAuthorized {
+		t.Errorf("Expected the authorization to be kept, got %s", auth.Status)
+	}
+
+	if err := billing.Bill(customer, reference, 3.98); err != nil {
+		t.Fatal(err)
+	}
+	if auth, _ := card.Authorize(key, customer, 398); auth.Status != payment.StatusCaptured {
+		t.Errorf("Expected the authorization to be captured, got %s", auth.Status)
+	}
+}
+
+func TestPayment_SettlePayment(t *testing.T) {
+	products := init_products(t)
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	card, err := payment.NewFakeCardGateway()
+	if err != nil {
+		t.Fatal(err)
+	}
+	billing, err := NewPaymentBilling(
+		WithPaymentGateway(payment.MethodCard, &unansweredCaptures{Gateway: card, captures: 1}),
+		WithPaymentRetries(0),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tavern, err := NewTavern(
+		WithOrderService(os),
+		WithBillingService(billing),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cust, err := aggregate.NewCustomer("Donald")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customers.Add(cust); err != nil {
+		t.Fatal(err)
+	}
+
+	order, err := tavern.Order(OrderRequest{
+		CustomerID:    cust.GetID(),
+		PaymentMethod: payment.MethodCard,
+		Lines:         []valueobject.OrderLine{{ProductID: products[0].GetID(), Quantity: 2}},
+	})
+	if !errors.Is(err, ErrPaymentUnknown) {
+		t.Fatalf("Expected error %v, got %v", ErrPaymentUnknown, err)
+	}
+	if order.GetStatus() != aggregate.OrderPlaced {
+		t.Errorf("Expected status %s, got %s", aggregate.OrderPlaced, order.GetStatus())
+	}
+
+	for i := 0; i < 2; i++ {
+		settled, err := tavern.SettlePayment(order.GetID(), payment.MethodCard)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if settled.GetStatus() != aggregate.OrderPaid || settled.GetInvoiceNumber() == "" {
+			t.Errorf("Expected a paid and numbered order, got %s %q", settled.GetStatus(), settled.GetInvoiceNumber())
+		}
+	}
+	key := payer{customer: cust.GetID(), reference: order.GetID()}.key()
+	if auth, _ := card.Authorize(key, cust.GetID(), 398); auth.Status != payment.StatusCaptured {
+		t.Errorf("Expected the authorization to be captured, got %s", auth.Status)
+	}
+}
+
+func TestPayment_IdempotentUnknownPayment(t *testing.T) {
+	products := init_products(t)
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	card, err := payment.NewFakeCardGateway()
+	if err != nil {
+		t.Fatal(err)
+	}
+	gateway := &unansweredCaptures{Gateway: card}
+	billing, err := NewPaymentBilling(
+		WithPaymentGateway(payment.MethodCard, gateway),
+		WithPaymentRetries(0),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tavern, err := NewTavern(
+		WithOrderService(os),
+		WithBillingService(billing),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cust, err := aggregate.NewCustomer("Donald")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customers.Add(cust); err != nil {
+		t.Fatal(err)
+	}
+	request := func(key string) OrderRequest {
+		return OrderRequest{
+			CustomerID:     cust.GetID(),
+			PaymentMethod:  payment.MethodCard,
+			Lines:          []valueobject.OrderLine{{ProductID: products[0].GetID(), Quantity: 1}},
+			IdempotencyKey: key,
+		}
+	}
+
+	t.Run("Settled", func(t *testing.T) {
+		gateway.captures = 1
+		order, err := tavern.Order(request("tablet-1"))
+		if !errors.Is(err, ErrPaymentUnknown) {
+			t.Fatalf("Expected error %v, got %v", ErrPaymentUnknown, err)
+		}
+		// a retry must not pass the unsettled order off as paid
+		replayed, err := tavern.Order(request("tablet-1"))
+		if !errors.Is(err, ErrPaymentUnknown) || replayed.GetID() != order.GetID() {
+			t.Fatalf("Expected order %v with error %v, got %v with %v", order.GetID(), ErrPaymentUnknown, replayed.GetID(), err)
+		}
+
+		if _, err := tavern.SettlePayment(order.GetID(), payment.MethodCard); err != nil {
+			t.Fatal(err)
+		}
+		replayed, err = tavern.Order(request("tablet-1"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if replayed.GetID() != order.GetID() || replayed.GetStatus() != aggregate.OrderPaid {
+			t.Errorf("Expected the paid order %v, got %v %s", order.GetID(), replayed.GetID(), replayed.GetStatus())
+		}
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		gateway.captures = 1
+		order, err := tavern.Order(request("tablet-2"))
+		if !errors.Is(err, ErrPaymentUnknown) {
+			t.Fatalf("Expected error %v, got %v", ErrPaymentUnknown, err)
+		}
+		if err := tavern.CancelOrder(order.GetID()); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tavern.Order(request("tablet-2")); err != ErrRequestCancelled {
+			t.Errorf("Expected error %v, got %v", ErrRequestCancelled, err)
+		}
+	})
+}
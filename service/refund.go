@@ -0,0 +1,220 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"taverne/aggregate"
+	"taverne/domain/invoice"
+	"taverne/domain/payment"
+	"taverne/domain/receipt"
+	"taverne/domain/tax"
+	"taverne/valueobject"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrRefundUnsupported is returned when the BillingService of the Tavern can not pay money back
+	ErrRefundUnsupported = errors.New("the billing service does not support refunds")
+)
+
+// RefundRequest is what a customer gets back of a billed order
+type RefundRequest struct {
+	// Returned are the items the customer hands back, they are refunded at what was paid for them
+	Returned []valueobject.ReturnedItem
+	// Amount is refunded on top of the returned items, such as for a complaint
+	Amount float64
+	// Full refunds everything which was not refunded yet and returns all items which are left
+	Full bool
+	// Restock puts the returned items back into stock, which is only right for unopened goods
+	Restock bool
+	Reason  string
+}
+
+// CreditNote is the outcome of a refund
+type CreditNote struct {
+	// Transaction is the refund to the customer, its amount is negative
+	Transaction valueobject.Transaction
+	// Receipt is the credit note document, numbered in the series of the invoices
+	Receipt receipt.Receipt
+}
+
+// Refund pays a customer back for a billed order, in full or in part.
+// The refund is stored on the order before the money is paid back, so it is never paid out twice.
+// A refund which could not be paid back is taken back again, unless the payment timed out and may
+// have gone through. An order on a tab is paid back to the payer who settled it, and the loyalty
+// points earned with the payment are taken back in proportion to the refund.
+// A refund which was paid back but whose credit note could not be numbered returns ErrNotNumbered,
+// the credit note can be numbered later with Tavern.CreditNote
+// will return aggregate.ErrRefundExceedsPayment if more would be refunded than was paid
+func (t *Tavern) Refund(orderID uuid.UUID, req RefundRequest) (CreditNote, error) {
+	refunder, ok := t.BillingService.(Refunder)
+	if !ok {
+		return CreditNote{}, ErrRefundUnsupported
+	}
+
+	// refunds are serialized, so two refunds can not both pay back the same money
+	t.refundMu.Lock()
+	defer t.refundMu.Unlock()
+
+	// the order is changed through advance, so the refund can not overwrite a concurrent change of the order
+	var ord, refunded aggregate.Order
+	var refund valueobject.Refund
+	err := t.OrderService.advance(orderID, func(o *aggregate.Order) error {
+		ord = *o
+		returned, extra := req.Returned, req.Amount
+		if req.Full {
+			returned, extra = nil, 0
+			for i, item := range ord.GetItems() {
+				if left := item.Quantity - ord.Returned(i); left > 0 {
+					returned = append(returned, valueobject.ReturnedItem{Item: i, Quantity: left})
+				}
+			}
+		}
+
+		r, err := o.IssueRefund(returned, extra, req.Reason)
+		if err != nil {
+			return err
+		}
+		refund, refunded = r, *o
+		return nil
+	})
+	if err != nil {
+		return CreditNote{}, err
+	}
+
+	target, err := t.refundTarget(ord)
+	if err != nil {
+		return CreditNote{}, t.revokeRefund(orderID, refund.ID, err)
+	}
+	transaction, err := refunder.Refund(target.payer, target.reference, refund.ID, refund.Amount)
+	if errors.Is(err, payment.ErrTimeout) {
+		// the money may have been paid back, so the refund stays on the order and is not paid again
+		return CreditNote{}, err
+	}
+	if err != nil {
+		return CreditNote{}, t.revokeRefund(orderID, refund.ID, err)
+	}
+
+	if req.Restock {
+		if err := t.OrderService.releaseStock(returnedItems(ord, refund)); err != nil {
+			log.Printf("restocking the items returned of order %s failed: %v", ord.GetID(), err)
+		}
+	}
+
+	if err := t.OrderService.reversePoints(target.payer, target.earnedFor, target.paid, refund.ID, refund.Amount); err != nil {
+		log.Printf("taking back loyalty points for refund %s failed: %v", refund.ID, err)
+	}
+
+	// the money is paid back already, so the credit note is returned even if numbering it fails
+	r, err := t.creditNote(refunded, refund)
+	return CreditNote{
+		Transaction: transaction,
+		Receipt:     r,
+	}, err
+}
+
+// CreditNote returns the credit note of a refund which was paid back, such as after ErrNotNumbered
+// was returned by Refund. The number is allocated to the refund, so the credit note keeps its number
+func (t *Tavern) CreditNote(orderID, refundID uuid.UUID) (receipt.Receipt, error) {
+	ord, err := t.OrderService.orders.Get(orderID)
+	if err != nil {
+		return receipt.Receipt{}, err
+	}
+	for _, refund := range ord.GetRefunds() {
+		if refund.ID == refundID {
+			return t.creditNote(ord, refund)
+		}
+	}
+	return receipt.Receipt{}, aggregate.ErrRefundNotFound
+}
+
+// creditNote numbers the credit note of a refund in the series of the invoices, a failure is retried.
+// A credit note which could not be numbered is returned without a number together with ErrNotNumbered
+func (t *Tavern) creditNote(ord aggregate.Order, refund valueobject.Refund) (receipt.Receipt, error) {
+	var number invoice.Number
+	var err error
+	for attempt := 0; attempt < invoiceAttempts; attempt++ {
+		if number, err = t.invoices.Allocate(refund.CreatedAt.Year(), refund.ID); err == nil {
+			break
+		}
+	}
+	var numbered string
+	if err == nil {
+		numbered = number.String()
+	}
+
+	items := ord.GetItems()
+	taxLines := make([]tax.Line, 0, len(items))
+	for i, item := range items {
+		taxLines = append(taxLines, tax.Line{Category: item.TaxCategory, Gross: refund.Amounts[i]})
+	}
+	r, rerr := receipt.NewCreditNote(ord, refund, numbered, t.OrderService.taxes.Calculate(taxLines, t.OrderService.rounding))
+	if rerr != nil {
+		return receipt.Receipt{}, rerr
+	}
+	if err != nil {
+		return r, fmt.Errorf("refund %s: %w: %w", refund.ID, ErrNotNumbered, err)
+	}
+	return r, nil
+}
+
+// revokeRefund takes back a refund which was not paid back, so it can be requested again,
+// and returns the error the refund failed with
+func (t *Tavern) revokeRefund(orderID, refundID uuid.UUID, err error) error {
+	if rerr := t.OrderService.advance(orderID, func(o *aggregate.Order) error {
+		return o.RevokeRefund(refundID)
+	}); rerr != nil {
+		return errors.Join(err, rerr)
+	}
+	return err
+}
+
+// payback is who a refund of an order is paid back to
+type payback struct {
+	payer uuid.UUID
+	// reference is what the payer was billed under
+	reference uuid.UUID
+	// earnedFor is what the payer earned loyalty points for, paid is what they paid for it
+	earnedFor uuid.UUID
+	paid      float64
+}
+
+// refundTarget returns who is paid back for an order and the reference the order was billed under.
+// An order on a tab was billed with the settlement of the tab, which the customer of the order
+// settled, or otherwise the payer who settled the largest share of the tab
+func (t *Tavern) refundTarget(ord aggregate.Order) (payback, error) {
+	if ord.GetTabID() == uuid.Nil {
+		return payback{payer: ord.GetCustomerID(), reference: ord.GetID(), earnedFor: ord.GetID(), paid: ord.Total()}, nil
+	}
+	tb, err := t.tabs.Get(ord.GetTabID())
+	if err != nil {
+		return payback{}, err
+	}
+	payer := ord.GetCustomerID()
+	settled, ok := tb.Settled(payer)
+	if !ok {
+		for _, s := range tb.GetSettlements() {
+			if s.GetAmount() > settled {
+				payer, settled = s.GetFrom(), s.GetAmount()
+			}
+		}
+	}
+	if settled == 0 {
+		return payback{}, fmt.Errorf("tab %s has no settlement: %w", tb.GetID(), aggregate.ErrOrderNotBilled)
+	}
+	return payback{payer: payer, reference: settlementReference(tb.GetID(), payer), earnedFor: tb.GetID(), paid: fromCents(settled)}, nil
+}
+
+// returnedItems are the items handed back with a refund, with the returned quantity
+func returnedItems(ord aggregate.Order, refund valueobject.Refund) []valueobject.OrderItem {
+	items := ord.GetItems()
+	returned := make([]valueobject.OrderItem, 0, len(refund.Returned))
+	for _, ri := range refund.Returned {
+		item := items[ri.Item]
+		item.Quantity = ri.Quantity
+		returned = append(returned, item)
+	}
+	return returned
+}
@@ -0,0 +1,408 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"taverne/aggregate"
+	invmemory "taverne/domain/invoice/memory"
+	"taverne/domain/loyalty"
+	"taverne/domain/payment"
+	"taverne/valueobject"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestRefund_Refund(t *testing.T) {
+	products := init_products(t)
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wallet := NewWalletBilling(os.customers)
+	tavern, err := NewTavern(
+		WithOrderService(os),
+		WithBillingService(wallet),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cust, err := aggregate.NewCustomer("Donald")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customers.Add(cust); err != nil {
+		t.Fatal(err)
+	}
+	if err := wallet.TopUp(cust.GetID(), 10); err != nil {
+		t.Fatal(err)
+	}
+
+	// 3 × 1.99 beer and 1 × 0.99 peenuts leave 3.04 in the wallet
+	order, err := tavern.Order(OrderRequest{
+		CustomerID: cust.GetID(),
+		Lines: []valueobject.OrderLine{
+			{ProductID: products[0].GetID(), Quantity: 3},
+			{ProductID: products[1].GetID(), Quantity: 1},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// an unopened beer goes back into stock
+	note, err := tavern.Refund(order.GetID(), RefundRequest{
+		Returned: []valueobject.ReturnedItem{{Item: 0, Quantity: 1}},
+		Restock:  true,
+		Reason:   "Unopened",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if note.Transaction.GetKind() != valueobject.TransactionRefund || note.Transaction.GetAmount() != -199 {
+		t.Errorf("Expected a refund of -199 cents, got %v", note.Transaction)
+	}
+	c, err := os.customers.Get(cust.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transactions := c.GetTransactions(); transactions[len(transactions)-1] != note.Transaction {
+		t.Errorf("Expected the transaction of the wallet, got %v", note.Transaction)
+	}
+	if !note.Receipt.CreditNote || note.Receipt.InvoiceNumber != order.GetInvoiceNumber() || note.Receipt.Total != -1.99 {
+		t.Errorf("Expected a credit note of -1.99 for invoice %s, got %v", order.GetInvoiceNumber(), note.Receipt)
+	}
+	if note.Receipt.Number == order.GetInvoiceNumber() || !strings.HasSuffix(note.Receipt.Number, "000002") {
+		t.Errorf("Expected the credit note to get the next number, got %s", note.Receipt.Number)
+	}
+	if balance, _ := wallet.Balance(cust.GetID()); balance != 5.03 {
+		t.Errorf("Expected balance 5.03, got %.2f", balance)
+	}
+	p, err := os.products.GetByID(products[0].GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.GetQuantity() != 8 {
+		t.Errorf("Expected 8 beers in stock, got %d", p.GetQuantity())
+	}
+
+	if _, err := tavern.Refund(order.GetID(), RefundRequest{Amount: 5}); !errors.Is(err, aggregate.ErrRefundExceedsPayment) {
+		t.Errorf("Expected error %v, got %v", aggregate.ErrRefundExceedsPayment, err)
+	}
+
+	// everything else is paid back, but opened beers are not restocked
+	note, err = tavern.Refund(order.GetID(), RefundRequest{Full: true, Reason: "Complaint"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if note.Receipt.Total != -4.97 || len(note.Receipt.Lines) != 2 {
+		t.Errorf("Expected a credit note of -4.97 with 2 lines, got %v", note.Receipt)
+	}
+	if balance, _ := wallet.Balance(cust.GetID()); balance != 10 {
+		t.Errorf("Expected balance 10.00, got %.2f", balance)
+	}
+	if _, err := tavern.Refund(order.GetID(), RefundRequest{Full: true}); !errors.Is(err, aggregate.ErrInvalidRefund) {
+		t.Errorf("Expected error %v, got %v", aggregate.ErrInvalidRefund, err)
+	}
+}
+
+// failingRefunder bills everything but fails to pay refunds back with err
+type failingRefunder struct {
+	logBilling
+	err error
+}
+
+func (fr *failingRefunder) Refund(customer uuid.UUID, reference uuid.UUID, refundID uuid.UUID, amount float64) (valueobject.Transaction, error) {
+	if fr.err != nil {
+		return valueobject.Transaction{}, fr.err
+	}
+	return fr.logBilling.Refund(customer, reference, refundID, amount)
+}
+
+func TestRefund_PaymentFailure(t *testing.T) {
+	products := init_products(t)
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	billing := &failingRefunder{}
+	tavern, err := NewTavern(
+		WithOrderService(os),
+		WithBillingService(billing),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cust, err := aggregate.NewCustomer("Donald")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customers.Add(cust); err != nil {
+		t.Fatal(err)
+	}
+	order, err := tavern.Order(OrderRequest{
+		CustomerID: cust.GetID(),
+		Lines:      []valueobject.OrderLine{{ProductID: products[0].GetID(), Quantity: 2}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	refunds := func() int {
+		o, err := os.orders.Get(order.GetID())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(o.GetRefunds())
+	}
+	req := RefundRequest{Returned: []valueobject.ReturnedItem{{Item: 0, Quantity: 1}}}
+
+	// a refund which was not paid back is taken back
+	billing.err = payment.ErrDeclined
+	if _, err := tavern.Refund(order.GetID(), req); err != payment.ErrDeclined {
+		t.Fatalf("Expected error %v, got %v", payment.ErrDeclined, err)
+	}
+	if refunds() != 0 {
+		t.Errorf("Expected no refund on the order, got %d", refunds())
+	}
+
+	// a refund which may have been paid back stays, so it is never paid out twice
+	billing.err = payment.ErrTimeout
+	if _, err := tavern.Refund(order.GetID(), req); err != payment.ErrTimeout {
+		t.Fatalf("Expected error %v, got %v", payment.ErrTimeout, err)
+	}
+	if refunds() != 1 {
+		t.Errorf("Expected the refund to stay on the order, got %d", refunds())
+	}
+
+	billing.err = nil
+	if _, err := tavern.Refund(order.GetID(), RefundRequest{Full: true}); err != nil {
+		t.Fatal(err)
+	}
+	if refunds() != 2 {
+		t.Errorf("Expected 2 refunds on the order, got %d", refunds())
+	}
+}
+
+func TestRefund_NotNumbered(t *testing.T) {
+	products := init_products(t)
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	numbers := &failingNumbers{NumberRepository: invmemory.New()}
+	tavern, err := NewTavern(
+		WithOrderService(os),
+		WithInvoiceNumberRepository(numbers),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cust, err := aggregate.NewCustomer("Donald")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customers.Add(cust); err != nil {
+		t.Fatal(err)
+	}
+	order, err := tavern.Order(OrderRequest{
+		CustomerID: cust.GetID(),
+		Lines:      []valueobject.OrderLine{{ProductID: products[0].GetID(), Quantity: 2}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the refund is paid back, so it is returned even though its credit note got no number
+	numbers.failures = invoiceAttempts
+	note, err := tavern.Refund(order.GetID(), RefundRequest{Returned: []valueobject.ReturnedItem{{Item: 0, Quantity: 1}}})
+	if !errors.Is(err, ErrNotNumbered) {
+		t.Fatalf("Expected error %v, got %v", ErrNotNumbered, err)
+	}
+	if note.Transaction.GetAmount() != -199 || note.Receipt.Number != "" {
+		t.Errorf("Expected a paid back credit note without number, got %v", note)
+	}
+
+	ord, err := os.orders.Get(order.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	refunds := ord.GetRefunds()
+	if len(refunds) != 1 {
+		t.Fatalf("Expected the refund to stay on the order, got %d", len(refunds))
+	}
+	r, err := tavern.CreditNote(order.GetID(), refunds[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(r.Number, "000002") || r.Total != -1.99 {
+		t.Errorf("Expected credit note 000002 of -1.99, got %v", r)
+	}
+	if _, err := tavern.CreditNote(order.GetID(), uuid.New()); err != aggregate.ErrRefundNotFound {
+		t.Errorf("Expected error %v, got %v", aggregate.ErrRefundNotFound, err)
+	}
+}
+
+func TestRefund_TabOrder(t *testing.T) {
+	products := init_products(t)
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	card, err := payment.NewFakeCardGateway()
+	if err != nil {
+		t.Fatal(err)
+	}
+	billing, err := NewPaymentBilling(WithPaymentGateway(payment.MethodCard, card), WithDefaultPaymentMethod(payment.MethodCard))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tavern, err := NewTavern(
+		WithOrderService(os),
+		WithBillingService(billing),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var payers []uuid.UUID
+	for _, name := range []string{"Donald", "Daisy"} {
+		cust, err := aggregate.NewCustomer(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.customers.Add(cust); err != nil {
+			t.Fatal(err)
+		}
+		payers = append(payers, cust.GetID())
+	}
+
+	type testCase struct {
+		test          string
+		split         Split
+		expectedPayer uuid.UUID
+	}
+
+	testCases := []testCase{
+		{
+			test:          "Settled by the customer",
+			split:         SplitEvenly(payers[0]),
+			expectedPayer: payers[0],
+		},
+		{
+			test:          "Settled by another payer",
+			split:         SplitEvenly(payers[1]),
+			expectedPayer: payers[1],
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			tabID, err := tavern.OpenTab(payers[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			order, err := tavern.OrderOnTab(tabID, OrderRequest{
+				Lines: []valueobject.OrderLine{{ProductID: products[0].GetID(), Quantity: 2}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tavern.CloseTabSplit(tabID, tc.split); err != nil {
+				t.Fatal(err)
+			}
+
+			note, err := tavern.Refund(order.GetID(), RefundRequest{Returned: []valueobject.ReturnedItem{{Item: 0, Quantity: 1}}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if note.Transaction.GetAmount() != -199 || note.Transaction.GetFrom() != tc.expectedPayer {
+				t.Errorf("Expected -199 cents paid back to %s, got %v", tc.expectedPayer, note.Transaction)
+			}
+		})
+	}
+}
+
+func TestRefund_LoyaltyPoints(t *testing.T) {
+	products := init_products(t)
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+		WithLoyaltyProgram(loyalty.Program{PointsPerUnit: 10, PointValue: 0.01}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tavern, err := NewTavern(WithOrderService(os))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cust, err := aggregate.NewCustomer("Donald")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customers.Add(cust); err != nil {
+		t.Fatal(err)
+	}
+	points := func() int {
+		c, err := os.customers.Get(cust.GetID())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c.GetPoints()
+	}
+
+	// 4 × 1.99 earns 79 points
+	order, err := tavern.Order(OrderRequest{
+		CustomerID: cust.GetID(),
+		Lines:      []valueobject.OrderLine{{ProductID: products[0].GetID(), Quantity: 4}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if points() != 79 {
+		t.Fatalf("Expected 79 points, got %d", points())
+	}
+
+	// a quarter of the order is refunded, so a quarter of the points is taken back
+	note, err := tavern.Refund(order.GetID(), RefundRequest{Returned: []valueobject.ReturnedItem{{Item: 0, Quantity: 1}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if points() != 60 {
+		t.Errorf("Expected 60 points, got %d", points())
+	}
+
+	c, err := os.customers.Get(cust.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	transactions := c.GetTransactions()
+	reversal := transactions[len(transactions)-1]
+	if reversal.GetKind() != valueobject.TransactionLoyaltyRedemption || reversal.GetAmount() != 19 {
+		t.Errorf("Expected 19 points taken back, got %v", reversal)
+	}
+	stored, err := os.orders.Get(order.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refund := stored.GetRefunds()[0]; reversal.GetTo() != refund.ID || note.Receipt.Total != -1.99 {
+		t.Errorf("Expected the reversal to reference refund %v, got %v", refund.ID, reversal.GetTo())
+	}
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"errors"
+	"taverne/aggregate"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrClosed is returned when a reservation is made for a time slot the tavern is not open for
+	ErrClosed = errors.New("the tavern is closed during the time slot")
+	// ErrSlotInPast is returned when a reservation is made for a time slot which started already
+	ErrSlotInPast = errors.New("the time slot started already")
+	// ErrNoTableAvailable is returned when every table large enough is taken during the time slot
+	ErrNoTableAvailable = errors.New("no table for the party is available during the time slot")
+	// ErrOverlappingReservation is returned when a customer already has a reservation during the time slot
+	ErrOverlappingReservation = errors.New("the customer already has a reservation during the time slot")
+)
+
+// ReservationRequest is a booking a customer asks for on the phone
+type ReservationRequest struct {
+	CustomerID uuid.UUID
+	PartySize  int
+	Start      time.Time
+	// Duration is how long the table is held, zero holds it for the default slot of the Tavern
+	Duration time.Duration
+}
+
+// Reserve books a table for a party, the smallest free table the party fits at is held for it
+// will return ErrNoTableAvailable if every table large enough is taken during the slot
+func (t *Tavern) Reserve(req ReservationRequest) (aggregate.Reservation, error) {
+	if _, err := t.OrderService.customers.Get(req.CustomerID); err != nil {
+		return aggregate.Reservation{}, err
+	}
+	start, end := t.slot(req)
+	r, err := aggregate.NewReservation(req.CustomerID, req.PartySize, start, end)
+	if err != nil {
+		return aggregate.Reservation{}, err
+	}
+
+	// bookings are serialized, so two parties can not get the same table
+	t.reservationMu.Lock()
+	defer t.reservationMu.Unlock()
+
+	if err := t.assignTable(&r); err != nil {
+		return aggregate.Reservation{}, err
+	}
+	if err := t.reservations.Add(r); err != nil {
+		return aggregate.Reservation{}, err
+	}
+	return r, nil
+}
+
+// ModifyReservation moves a reservation to another slot or party size, the customer stays the same.
+// The party keeps its table if it is still free and large enough
+func (t *Tavern) ModifyReservation(id uuid.UUID, req ReservationRequest) (aggregate.Reservation, error) {
+	t.reservationMu.Lock()
+	defer t.reservationMu.Unlock()
+
+	r, err := t.reservations.Get(id)
+	if err != nil {
+		return aggregate.Reservation{}, err
+	}
+	start, end := t.slot(req)
+	if err := r.Change(req.PartySize, start, end); err != nil {
+		return aggregate.Reservation{}, err
+	}
+	if err := t.assignTable(&r); err != nil {
+		return aggregate.Reservation{}, err
+	}
+	if err := t.reservations.Update(r); err != nil {
+		return aggregate.Reservation{}, err
+	}
+	return r, nil
+}
+
+// CancelReservation cancels a reservation, which frees its table for the slot
+func (t *Tavern) CancelReservation(id uuid.UUID) error {
+	return t.changeReservation(id, (*aggregate.Reservation).Cancel)
+}
+
+// MarkNoShow records that the party of a reservation did not turn up, once its slot started
+func (t *Tavern) MarkNoShow(id uuid.UUID) error {
+	return t.changeReservation(id, func(r *aggregate.Reservation) error {
+		return r.MarkNoShow(t.OrderService.now())
+	})
+}
+
+// SeatReservation seats the arriving party at the table of its reservation,
+// no guests seats only the customer who booked
+func (t *Tavern) SeatReservation(id uuid.UUID, guests ...uuid.UUID) error {
+	t.reservationMu.Lock()
+	defer t.reservationMu.Unlock()
+
+	r, err := t.reservations.Get(id)
+	if err != nil {
+		return err
+	}
+	if err := r.Seat(); err != nil {
+		return err
+	}
+	if len(guests) == 0 {
+		guests = []uuid.UUID{r.GetCustomerID()}
+	}
+	if err := t.seat(r.GetTableID(), r.GetID(), guests); err != nil {
+		return err
+	}
+	return t.reservations.Update(r)
+}
+
+// Reservations returns every reservation whose slot overlaps from to to, the earliest first
+func (t *Tavern) Reservations(from, to time.Time) ([]aggregate.Reservation, error) {
+	return t.reservations.GetBetween(from, to)
+}
+
+// changeReservation gets, changes and stores a reservation while bookings are serialized
+func (t *Tavern) changeReservation(id uuid.UUID, change func(*aggregate.Reservation) error) error {
+	t.reservationMu.Lock()
+	defer t.reservationMu.Unlock()
+
+	r, err := t.reservations.Get(id)
+	if err != nil {
+		return err
+	}
+	if err := change(&r); err != nil {
+		return err
+	}
+	return t.reservations.Update(r)
+}
+
+// slot returns when the slot of a request starts and ends
+func (t *Tavern) slot(req ReservationRequest) (time.Time, time.Time) {
+	d := req.Duration
+	if d == 0 {
+		d = t.reservationSlot
+	}
+	return req.Start, req.Start.Add(d)
+}
+
+// assignTable checks the slot of a reservation against the opening hours and the other reservations
+// and holds a table for it. It expects reservationMu to be held
+func (t *Tavern) assignTable(r *aggregate.Reservation) error {
+	start, end := r.GetStart(), r.GetEnd()
+	if start.Before(t.OrderService.now()) {
+		return ErrSlotInPast
+	}
+	if !t.openingHours.Contains(start, end) {
+		return ErrClosed
+	}
+
+	others, err := t.reservations.GetBetween(start, end)
+	if err != nil {
+		return err
+	}
+	taken := make(map[uuid.UUID]bool)
+	for _, o := range others {
+		if o.GetID() == r.GetID() || !o.HoldsTable() {
+			continue
+		}
+		if o.GetCustomerID() == r.GetCustomerID() {
+			return ErrOverlappingReservation
+		}
+		taken[o.GetTableID()] = true
+	}
+
+	tables, err := t.tables.GetAll()
+	if err != nil {
+		return err
+	}
+	var best *aggregate.Table
+	for i, tb := range tables {
+		if taken[tb.GetID()] || tb.GetCapacity() < r.GetPartySize() {
+			continue
+		}
+		if tb.GetID() == r.GetTableID() {
+			// a modified reservation keeps its table if it still fits
+			best = &tables[i]
+			break
+		}
+		if best == nil || tb.GetCapacity() < best.GetCapacity() {
+			best = &tables[i]
+		}
+	}
+	if best == nil {
+		return ErrNoTableAvailable
+	}
+	return r.AssignTable(best.GetID())
+}
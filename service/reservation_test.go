@@ -0,0 +1,199 @@
+package service
+
+import (
+	"errors"
+	"taverne/aggregate"
+	"taverne/valueobject"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func Test_TavernReservations(t *testing.T) {
+	// Friday the 7th of June 2024, the tavern opens from 17:00 until 1:00 at night
+	friday := time.Date(2024, time.June, 7, 0, 0, 0, 0, time.UTC)
+	now := friday.Add(12 * time.Hour)
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(init_products(t)),
+		WithClock(func() time.Time { return now }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	small, err := aggregate.NewTable(1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	large, err := aggregate.NewTable(2, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tavern, err := NewTavern(
+		WithOrderService(os),
+		WithMemoryTableRepository([]aggregate.Table{small, large}),
+		WithOpeningHours(valueobject.OpeningHours{
+			time.Friday: {{Open: 17 * time.Hour, Close: 25 * time.Hour}},
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	customers := make([]uuid.UUID, 4)
+	for i := range customers {
+		c, err := aggregate.NewCustomer("Guest")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.customers.Add(c); err != nil {
+			t.Fatal(err)
+		}
+		customers[i] = c.GetID()
+	}
+
+	type testCase struct {
+		test          string
+		req           ReservationRequest
+		expectedTable uuid.UUID
+		expectedErr   error
+	}
+
+	testCases := []testCase{
+		{
+			test:          "Smallest table the party fits at",
+			req:           ReservationRequest{CustomerID: customers[0], PartySize: 2, Start: friday.Add(19 * time.Hour)},
+			expectedTable: small.GetID(),
+		},
+		{
+			test:          "Next table while the small one is taken",
+			req:           ReservationRequest{CustomerID: customers[1], PartySize: 2, Start: friday.Add(19*time.Hour + 30*time.Minute)},
+			expectedTable: large.GetID(),
+		},
+		{
+			test:        "Fully booked",
+			req:         ReservationRequest{CustomerID: customers[2], PartySize: 2, Start: friday.Add(20 * time.Hour)},
+			expectedErr: ErrNoTableAvailable,
+		},
+		{
+			test:        "Overlapping reservation of the same customer",
+			req:         ReservationRequest{CustomerID: customers[0], PartySize: 2, Start: friday.Add(20 * time.Hour)},
+			expectedErr: ErrOverlappingReservation,
+		},
+		{
+			test:        "Party larger than any table",
+			req:         ReservationRequest{CustomerID: customers[3], PartySize: 5, Start: friday.Add(22 * time.Hour)},
+			expectedErr: ErrNoTableAvailable,
+		},
+		{
+			test:          "After midnight on a Friday night",
+			req:           ReservationRequest{CustomerID: customers[2], PartySize: 2, Start: friday.Add(24 * time.Hour), Duration: time.Hour},
+			expectedTable: small.GetID(),
+		},
+		{
+			test:        "Slot ending after closing time",
+			req:         ReservationRequest{CustomerID: customers[3], PartySize: 2, Start: friday.Add(24 * time.Hour)},
+			expectedErr: ErrClosed,
+		},
+		{
+			test:        "Before opening",
+			req:         ReservationRequest{CustomerID: customers[3], PartySize: 2, Start: friday.Add(16 * time.Hour)},
+			expectedErr: ErrClosed,
+		},
+		{
+			test:        "Slot in the past",
+			req:         ReservationRequest{CustomerID: customers[3], PartySize: 2, Start: friday.Add(-5 * time.Hour)},
+			expectedErr: ErrSlotInPast,
+		},
+		{
+			test:        "Closed day",
+			req:         ReservationRequest{CustomerID: customers[3], PartySize: 2, Start: friday.Add(43 * time.Hour)},
+			expectedErr: ErrClosed,
+		},
+	}
+
+	booked := make(map[string]aggregate.Reservation)
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			r, err := tavern.Reserve(tc.req)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+			if err == nil && r.GetTableID() != tc.expectedTable {
+				t.Errorf("Expected table %s, got %s", tc.expectedTable, r.GetTableID())
+			}
+			booked[tc.test] = r
+		})
+	}
+
+	// cancelling the first reservation frees the small table for the third customer
+	first := booked["Smallest table the party fits at"]
+	if err := tavern.CancelReservation(first.GetID()); err != nil {
+		t.Fatal(err)
+	}
+	third, err := tavern.Reserve(ReservationRequest{CustomerID: customers[2], PartySize: 2, Start: friday.Add(20 * time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// growing the second party keeps its large table, a party of five does not fit anywhere
+	second := booked["Next table while the small one is taken"]
+	if second, err = tavern.ModifyReservation(second.GetID(), ReservationRequest{PartySize: 4, Start: second.GetStart()}); err != nil {
+		t.Fatal(err)
+	}
+	if second.GetTableID() != large.GetID() || second.GetPartySize() != 4 || second.GetCustomerID() != customers[1] {
+		t.Errorf("Expected a party of 4 at the large table, got %v", second)
+	}
+	if _, err := tavern.ModifyReservation(second.GetID(), ReservationRequest{PartySize: 5, Start: second.GetStart()}); !errors.Is(err, ErrNoTableAvailable) {
+		t.Errorf("Expected error %v, got %v", ErrNoTableAvailable, err)
+	}
+
+	if err := tavern.MarkNoShow(third.GetID()); !errors.Is(err, aggregate.ErrReservationNotStarted) {
+		t.Errorf("Expected error %v, got %v", aggregate.ErrReservationNotStarted, err)
+	}
+	now = friday.Add(20*time.Hour + 15*time.Minute)
+	if err := tavern.MarkNoShow(third.GetID()); err != nil {
+		t.Fatal(err)
+	}
+	// a walk-in can not take the large table booked for the second party, the small one is free again
+	if err := tavern.SeatParty(large.GetID(), customers[2]); !errors.Is(err, ErrTableBooked) {
+		t.Errorf("Expected error %v, got %v", ErrTableBooked, err)
+	}
+	if err := tavern.SeatParty(small.GetID(), customers[2]); err != nil {
+		t.Fatal(err)
+	}
+	if err := tavern.MoveGuests(small.GetID(), large.GetID()); !errors.Is(err, ErrTableBooked) {
+		t.Errorf("Expected error %v, got %v", ErrTableBooked, err)
+	}
+	if err := tavern.SeatReservation(second.GetID(), customers[1], customers[3]); err != nil {
+		t.Fatal(err)
+	}
+
+	reservations, err := tavern.Reservations(friday.Add(17*time.Hour), friday.Add(25*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses := make(map[uuid.UUID]aggregate.ReservationStatus)
+	for _, r := range reservations {
+		statuses[r.GetID()] = r.GetStatus()
+	}
+	expected := map[uuid.UUID]aggregate.ReservationStatus{
+		first.GetID():  aggregate.ReservationCancelled,
+		second.GetID(): aggregate.ReservationSeated,
+		third.GetID():  aggregate.ReservationNoShow,
+	}
+	for id, status := range expected {
+		if statuses[id] != status {
+			t.Errorf("Expected reservation %s to be %s, got %s", id, status, statuses[id])
+		}
+	}
+	overview, err := tavern.Tables()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if overview[1].Status != aggregate.TableOccupied || len(overview[1].Guests) != 2 {
+		t.Errorf("Expected the party of the reservation at the large table, got %v", overview[1])
+	}
+}
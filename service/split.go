@@ -0,0 +1,203 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"taverne/valueobject"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrSplitWithoutPayers is returned when a split has no payer
+	ErrSplitWithoutPayers = errors.New("a split needs at least one payer")
+	// ErrSplitMismatch is returned when the parts of a split do not sum up to the total
+	ErrSplitMismatch = errors.New("the parts of the split do not sum up to the total")
+	// ErrSplitItems is returned when line items are not assigned to exactly one payer
+	ErrSplitItems = errors.New("every line item has to be assigned to exactly one payer")
+	// ErrSplitPayers is returned when a payer appears more than once in a split
+	ErrSplitPayers = errors.New("every payer can only appear once in a split")
+	// ErrSplitAmount is returned when an explicit share is not a positive amount
+	ErrSplitAmount = errors.New("every share of a split has to be a positive amount")
+	// ErrSplitNotBilled is returned when an order is split which was not billed or was cancelled
+	ErrSplitNotBilled = errors.New("only a billed order can be split")
+)
+
+// Split decides which payer pays which part of a bill
+type Split interface {
+	// shares returns the cents every payer has to pay for items which add up to total cents
+	shares(total int, items []valueobject.OrderItem) ([]share, error)
+}
+
+type share struct {
+	payer uuid.UUID
+	cents int
+}
+
+// Share is an explicit amount a payer pays
+type Share struct {
+	Payer  uuid.UUID
+	Amount float64
+}
+
+// ItemShare assigns line items, by their position in the bill, to a payer
+type ItemShare struct {
+	Payer uuid.UUID
+	Items []int
+}
+
+type evenSplit []uuid.UUID
+
+type amountSplit []Share
+
+type itemSplit []ItemShare
+
+// SplitEvenly divides the bill evenly between all payers.
+// The cents which can not be divided evenly are paid by the first payers
+func SplitEvenly(payers ...uuid.UUID) Split {
+	return evenSplit(payers)
+}
+
+// SplitByAmounts lets every payer pay an explicit amount
+func SplitByAmounts(shares ...Share) Split {
+	return amountSplit(shares)
+}
+
+// SplitByItems lets every payer pay the line items assigned to them
+func SplitByItems(shares ...ItemShare) Split {
+	return itemSplit(shares)
+}
+
+func (s evenSplit) shares(total int, _ []valueobject.OrderItem) ([]share, error) {
+	if len(s) == 0 {
+		return nil, ErrSplitWithoutPayers
+	}
+	if err := uniquePayers(s); err != nil {
+		return nil, err
+	}
+	shares := make([]share, len(s))
+	for i, payer := range s {
+		cents := total / len(s)
+		if i < total%len(s) {
+			cents++
+		}
+		shares[i] = share{payer: payer, cents: cents}
+	}
+	return shares, nil
+}
+
+func (s amountSplit) shares(total int, _ []valueobject.OrderItem) ([]share, error) {
+	if len(s) == 0 {
+		return nil, ErrSplitWithoutPayers
+	}
+	payers := make([]uuid.UUID, len(s))
+	for i, sh := range s {
+		payers[i] = sh.Payer
+	}
+	if err := uniquePayers(payers); err != nil {
+		return nil, err
+	}
+	shares := make([]share, len(s))
+	var sum int
+	for i, sh := range s {
+		shares[i] = share{payer: sh.Payer, cents: toCents(sh.Amount)}
+		if shares[i].cents <= 0 {
+			return nil, fmt.Errorf("%.2f for payer %s: %w", sh.Amount, sh.Payer, ErrSplitAmount)
+		}
+		sum += shares[i].cents
+	}
+	if sum != total {
+		return nil, fmt.Errorf("%.2f of %.2f: %w", fromCents(sum), fromCents(total), ErrSplitMismatch)
+	}
+	return shares, nil
+}
+
+// shares of an item split are weighted by the items assigned to each payer,
+// so discounts on the bill reduce every share proportionally
+func (s itemSplit) shares(total int, items []valueobject.OrderItem) ([]share, error) {
+	if len(s) == 0 {
+		return nil, ErrSplitWithoutPayers
+	}
+	payers := make([]uuid.UUID, len(s))
+	for i, sh := range s {
+		payers[i] = sh.Payer
+	}
+	if err := uniquePayers(payers); err != nil {
+		return nil, err
+	}
+	assigned := make([]bool, len(items))
+	weights := make([]int, len(s))
+	var subtotal int
+	for i, sh := range s {
+		for _, idx := range sh.Items {
+			if idx < 0 || idx >= len(items) || assigned[idx] {
+				return nil, fmt.Errorf("item %d: %w", idx, ErrSplitItems)
+			}
+			assigned[idx] = true
+			weights[i] += toCents(items[idx].Total())
+		}
+		subtotal += weights[i]
+	}
+	for idx, ok := range assigned {
+		if !ok {
+			return nil, fmt.Errorf("item %d is unassigned: %w", idx, ErrSplitItems)
+		}
+	}
+
+	if subtotal == 0 && total != 0 {
+		return nil, fmt.Errorf("%.2f of %.2f: %w", 0.0, fromCents(total), ErrSplitMismatch)
+	}
+
+	shares := make([]share, len(s))
+	var sum int
+	for i, sh := range s {
+		shares[i].payer = sh.Payer
+		if subtotal > 0 {
+			shares[i].cents = weights[i] * total / subtotal
+		}
+		sum += shares[i].cents
+	}
+	// hand the cents lost by rounding down to the first payers with items
+	for i := 0; sum < total; i = (i + 1) % len(shares) {
+		if weights[i] > 0 {
+			shares[i].cents++
+			sum++
+		}
+	}
+	return shares, nil
+}
+
+// uniquePayers makes sure no payer is listed twice, so every share is billed
+func uniquePayers(payers []uuid.UUID) error {
+	seen := make(map[uuid.UUID]bool, len(payers))
+	for _, payer := range payers {
+		if seen[payer] {
+			return fmt.Errorf("payer %s: %w", payer, ErrSplitPayers)
+		}
+		seen[payer] = true
+	}
+	return nil
+}
+
+// splitTransactions divides total between the payers of split and returns one
+// transaction per payer towards the reference, such as an order or a tab
+func splitTransactions(split Split, reference uuid.UUID, total float64, items []valueobject.OrderItem) ([]valueobject.Transaction, error) {
+	shares, err := split.shares(toCents(total), items)
+	if err != nil {
+		return nil, err
+	}
+	transactions := make([]valueobject.Transaction, 0, len(shares))
+	for _, sh := range shares {
+		transactions = append(transactions, valueobject.NewTransaction(valueobject.TransactionPayment, sh.cents, sh.payer, reference))
+	}
+	return transactions, nil
+}
+
+func toCents(amount float64) int {
+	return int(math.Round(amount * 100))
+}
+
+func fromCents(cents int) float64 {
+	return float64(cents) / 100
+}
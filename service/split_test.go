@@ -0,0 +1,223 @@
+package service
+
+import (
+	"errors"
+	"taverne/aggregate"
+	"taverne/valueobject"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestSplit_SplitOrder(t *testing.T) {
+	products := init_products(t)
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var payers []uuid.UUID
+	for _, name := range []string{"Donald", "Daisy", "Gustav", "Dagobert"} {
+		cust, err := aggregate.NewCustomer(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.customers.Add(cust); err != nil {
+			t.Fatal(err)
+		}
+		payers = append(payers, cust.GetID())
+	}
+
+	// 2 × 1.99 + 0.99 + 0.99 = 5.96
+	order, err := os.CreateOrder(OrderRequest{
+		CustomerID: payers[0],
+		Lines: []valueobject.OrderLine{
+			{ProductID: products[0].GetID(), Quantity: 2},
+			{ProductID: products[1].GetID(), Quantity: 1},
+			{ProductID: products[2].GetID(), Quantity: 1},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.PayOrder(order.GetID()); err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		name        string
+		split       Split
+		expected    []int
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			name:     "Evenly between four",
+			split:    SplitEvenly(payers...),
+			expected: []int{149, 149, 149, 149},
+		},
+		{
+			name:     "Evenly with remaining cents",
+			split:    SplitEvenly(payers[:3]...),
+			expected: []int{199, 199, 198},
+		},
+		{
+			name: "By explicit amounts",
+			split: SplitByAmounts(
+				Share{Payer: payers[0], Amount: 5},
+				Share{Payer: payers[1], Amount: 0.96},
+			),
+			expected: []int{500, 96},
+		},
+		{
+			name: "Amounts not matching the total",
+			split: SplitByAmounts(
+				Share{Payer: payers[0], Amount: 5},
+				Share{Payer: payers[1], Amount: 0.95},
+			),
+			expectedErr: ErrSplitMismatch,
+		},
+		{
+			name: "By line items",
+			split: SplitByItems(
+				ItemShare{Payer: payers[0], Items: []int{0}},
+				ItemShare{Payer: payers[1], Items: []int{1, 2}},
+			),
+			expected: []int{398, 198},
+		},
+		{
+			name: "Unassigned line item",
+			split: SplitByItems(
+				ItemShare{Payer: payers[0], Items: []int{0, 1}},
+			),
+			expectedErr: ErrSplitItems,
+		},
+		{
+			name: "Line item assigned twice",
+			split: SplitByItems(
+				ItemShare{Payer: payers[0], Items: []int{0, 1}},
+				ItemShare{Payer: payers[1], Items: []int{1, 2}},
+			),
+			expectedErr: ErrSplitItems,
+		},
+		{
+			name:        "Same payer twice evenly",
+			split:       SplitEvenly(payers[0], payers[0]),
+			expectedErr: ErrSplitPayers,
+		},
+		{
+			name: "Same payer twice by amounts",
+			split: SplitByAmounts(
+				Share{Payer: payers[0], Amount: 2.96},
+				Share{Payer: payers[0], Amount: 3},
+			),
+			expectedErr: ErrSplitPayers,
+		},
+		{
+			name: "Same payer twice by line items",
+			split: SplitByItems(
+				ItemShare{Payer: payers[0], Items: []int{0}},
+				ItemShare{Payer: payers[0], Items: []int{1, 2}},
+			),
+			expectedErr: ErrSplitPayers,
+		},
+		{
+			name: "Zero amount",
+			split: SplitByAmounts(
+				Share{Payer: payers[0], Amount: 5.96},
+				Share{Payer: payers[1], Amount: 0},
+			),
+			expectedErr: ErrSplitAmount,
+		},
+		{
+			name: "Negative amount",
+			split: SplitByAmounts(
+				Share{Payer: payers[0], Amount: 6.96},
+				Share{Payer: payers[1], Amount: -1},
+			),
+			expectedErr: ErrSplitAmount,
+		},
+		{
+			name:        "No payers",
+			split:       SplitEvenly(),
+			expectedErr: ErrSplitWithoutPayers,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			transactions, err := os.SplitOrder(order.GetID(), tc.split)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+			if len(transactions) != len(tc.expected) {
+				t.Fatalf("Expected %d transactions, got %d", len(tc.expected), len(transactions))
+			}
+			for i, tr := range transactions {
+				if tr.GetAmount() != tc.expected[i] {
+					t.Errorf("Expected payer %d to pay %d, got %d", i, tc.expected[i], tr.GetAmount())
+				}
+				if tr.GetTo() != order.GetID() {
+					t.Errorf("Expected transaction to order %v, got %v", order.GetID(), tr.GetTo())
+				}
+			}
+		})
+	}
+}
+
+func TestSplit_SplitOrderNotBilled(t *testing.T) {
+	products := init_products(t)
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cust, err := aggregate.NewCustomer("Donald")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customers.Add(cust); err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		name   string
+		cancel bool
+	}
+
+	testCases := []testCase{
+		{name: "Placed order"},
+		{name: "Cancelled order", cancel: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			order, err := os.CreateOrder(OrderRequest{
+				CustomerID: cust.GetID(),
+				Lines: []valueobject.OrderLine{
+					{ProductID: products[0].GetID(), Quantity: 1},
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.cancel {
+				if err := os.CancelOrder(order.GetID()); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, err := os.SplitOrder(order.GetID(), SplitEvenly(cust.GetID())); !errors.Is(err, ErrSplitNotBilled) {
+				t.Errorf("Expected error %v, got %v", ErrSplitNotBilled, err)
+			}
+		})
+	}
+}
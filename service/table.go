@@ -0,0 +1,259 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"taverne/aggregate"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrNotSeated is returned when a customer orders for a table the customer is not seated at
+	ErrNotSeated = errors.New("the customer is not seated at the table")
+	// ErrTableBooked is returned when a walk-in party is seated at a table booked for a reservation
+	ErrTableBooked = errors.New("the table is booked for a reservation")
+	// ErrSeatedElsewhere is returned when a guest is seated while sitting at another table
+	ErrSeatedElsewhere = errors.New("the guest is seated at another table")
+)
+
+// TableOverview is the state of one table as the staff sees it on the floor plan
+type TableOverview struct {
+	ID       uuid.UUID
+	Number   int
+	Capacity int
+	Status   aggregate.TableStatus
+	Guests   []uuid.UUID
+	SeatedAt time.Time
+	// Orders are the orders placed since the guests were seated, Total is their sum
+	Orders []uuid.UUID
+	Total  float64
+}
+
+// SeatParty seats the customers at a free or reserved table. A walk-in party stays for a reservation
+// slot, so a table booked for a reservation within the slot from now on is not given away
+// will return aggregate.ErrTableCapacity if the party does not fit and ErrTableBooked if the table is booked
+func (t *Tavern) SeatParty(tableID uuid.UUID, guests ...uuid.UUID) error {
+	t.reservationMu.Lock()
+	defer t.reservationMu.Unlock()
+
+	return t.seat(tableID, uuid.Nil, guests)
+}
+
+// seat seats the guests at the table unless a reservation other than the one
+// being seated, uuid.Nil for walk-ins, is booked for the table or a guest sits at another table.
+// reservationMu has to be held
+func (t *Tavern) seat(tableID uuid.UUID, reservationID uuid.UUID, guests []uuid.UUID) error {
+	for _, g := range guests {
+		if _, err := t.OrderService.customers.Get(g); err != nil {
+			return err
+		}
+	}
+
+	if err := t.checkBookings(tableID, reservationID); err != nil {
+		return err
+	}
+
+	t.tableMu.Lock()
+	defer t.tableMu.Unlock()
+
+	tables, err := t.tables.GetAll()
+	if err != nil {
+		return err
+	}
+	for _, other := range tables {
+		for _, g := range guests {
+			if other.GetID() != tableID && other.IsSeated(g) {
+				return fmt.Errorf("guest %s at table %d: %w", g, other.GetNumber(), ErrSeatedElsewhere)
+			}
+		}
+	}
+
+	tb, err := t.tables.Get(tableID)
+	if err != nil {
+		return err
+	}
+	if err := tb.Seat(guests, t.OrderService.now()); err != nil {
+		return err
+	}
+	return t.tables.Update(tb)
+}
+
+// checkBookings makes sure no reservation other than the one given is booked for the table
+// within the slot from now on. reservationMu has to be held
+func (t *Tavern) checkBookings(tableID uuid.UUID, reservationID uuid.UUID) error {
+	now := t.OrderService.now()
+	bookings, err := t.reservations.GetBetween(now, now.Add(t.reservationSlot))
+	if err != nil {
+		return err
+	}
+	for _, r := range bookings {
+		if r.GetID() != reservationID && r.GetTableID() == tableID && r.GetStatus() == aggregate.ReservationBooked {
+			return ErrTableBooked
+		}
+	}
+	return nil
+}
+
+// ReserveTable holds a free table for a party which is about to arrive
+func (t *Tavern) ReserveTable(tableID uuid.UUID) error {
+	return t.changeTable(tableID, (*aggregate.Table).Reserve)
+}
+
+// ClearTable frees a table once the guests left or a reservation is released
+func (t *Tavern) ClearTable(tableID uuid.UUID) error {
+	return t.changeTable(tableID, (*aggregate.Table).Clear)
+}
+
+// changeTable gets, changes and stores a table while seating is serialized
+func (t *Tavern) changeTable(tableID uuid.UUID, change func(*aggregate.Table) error) error {
+	t.tableMu.Lock()
+	defer t.tableMu.Unlock()
+
+	tb, err := t.tables.Get(tableID)
+	if err != nil {
+		return err
+	}
+	if err := change(&tb); err != nil {
+		return err
+	}
+	return t.tables.Update(tb)
+}
+
+// MoveGuests moves guests from one table to another, no guests moves the whole party.
+// The guests join the party at an occupied table or are seated at a free one, and the orders
+// they placed follow them. A free table booked for a reservation is not given away
+func (t *Tavern) MoveGuests(from, to uuid.UUID, guests ...uuid.UUID) error {
+	t.reservationMu.Lock()
+	defer t.reservationMu.Unlock()
+	t.tableMu.Lock()
+	defer t.tableMu.Unlock()
+
+	source, err := t.tables.Get(from)
+	if err != nil {
+		return err
+	}
+	target, err := t.tables.Get(to)
+	if err != nil {
+		return err
+	}
+	if from == to {
+		return nil
+	}
+	if len(guests) == 0 {
+		guests = source.GetGuests()
+	}
+
+	// the orders have to be collected before the source table is cleared by the last guest leaving
+	var moved []uuid.UUID
+	for _, id := range source.GetOrders() {
+		ord, err := t.OrderService.orders.Get(id)
+		if err != nil {
+			return err
+		}
+		for _, g := range guests {
+			if ord.GetCustomerID() == g {
+				moved = append(moved, id)
+				source.RemoveOrder(id)
+				break
+			}
+		}
+	}
+	if err := source.Leave(guests); err != nil {
+		return err
+	}
+	if target.GetStatus() == aggregate.TableOccupied {
+		err = target.Join(guests)
+	} else if err = t.checkBookings(to, uuid.Nil); err == nil {
+		err = target.Seat(guests, t.OrderService.now())
+	}
+	if err != nil {
+		return err
+	}
+	for _, id := range moved {
+		if err := target.AddOrder(id); err != nil {
+			return err
+		}
+	}
+
+	if err := t.tables.Update(source); err != nil {
+		return err
+	}
+	if err := t.tables.Update(target); err != nil {
+		return err
+	}
+	for _, id := range moved {
+		if err := t.OrderService.advance(id, func(o *aggregate.Order) error {
+			o.SetTableID(to)
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Tables returns an overview of all tables ordered by number
+func (t *Tavern) Tables() ([]TableOverview, error) {
+	tables, err := t.tables.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	overview := make([]TableOverview, 0, len(tables))
+	for _, tb := range tables {
+		o := TableOverview{
+			ID:       tb.GetID(),
+			Number:   tb.GetNumber(),
+			Capacity: tb.GetCapacity(),
+			Status:   tb.GetStatus(),
+			Guests:   tb.GetGuests(),
+			SeatedAt: tb.GetSeatedAt(),
+			Orders:   tb.GetOrders(),
+		}
+		for _, id := range o.Orders {
+			ord, err := t.OrderService.orders.Get(id)
+			if err != nil {
+				return nil, err
+			}
+			if ord.GetStatus() != aggregate.OrderCancelled {
+				o.Total += ord.Total()
+			}
+		}
+		overview = append(overview, o)
+	}
+	return overview, nil
+}
+
+// createOrder creates the order of a request, an order for a table is served there.
+// Seating is serialized while the order is created, so moving the guests can not miss it
+func (t *Tavern) createOrder(req OrderRequest) (aggregate.Order, error) {
+	if req.TableID == uuid.Nil {
+		return t.OrderService.CreateOrder(req)
+	}
+
+	t.tableMu.Lock()
+	defer t.tableMu.Unlock()
+
+	tb, err := t.tables.Get(req.TableID)
+	if err != nil {
+		return aggregate.Order{}, err
+	}
+	if !tb.IsSeated(req.CustomerID) {
+		return aggregate.Order{}, ErrNotSeated
+	}
+	order, err := t.OrderService.CreateOrder(req)
+	if err != nil {
+		return aggregate.Order{}, err
+	}
+	if err := tb.AddOrder(order.GetID()); err != nil {
+		return aggregate.Order{}, err
+	}
+	if err := t.tables.Update(tb); err != nil {
+		if cerr := t.OrderService.CancelOrder(order.GetID()); cerr != nil {
+			return aggregate.Order{}, errors.Join(err, cerr)
+		}
+		return aggregate.Order{}, err
+	}
+	return order, nil
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"errors"
+	"taverne/aggregate"
+	"taverne/valueobject"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func Test_TavernTables(t *testing.T) {
+	products := init_products(t)
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	small, err := aggregate.NewTable(1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	large, err := aggregate.NewTable(2, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tavern, err := NewTavern(
+		WithOrderService(os),
+		WithMemoryTableRepository([]aggregate.Table{large, small}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	guests := make([]uuid.UUID, 3)
+	for i := range guests {
+		c, err := aggregate.NewCustomer("Guest")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.customers.Add(c); err != nil {
+			t.Fatal(err)
+		}
+		guests[i] = c.GetID()
+	}
+
+	if err := tavern.SeatParty(small.GetID(), guests...); !errors.Is(err, aggregate.ErrTableCapacity) {
+		t.Errorf("Expected error %v, got %v", aggregate.ErrTableCapacity, err)
+	}
+	if err := tavern.SeatParty(small.GetID(), guests[:2]...); err != nil {
+		t.Fatal(err)
+	}
+	if err := tavern.SeatParty(large.GetID(), guests[1:]...); !errors.Is(err, ErrSeatedElsewhere) {
+		t.Errorf("Expected error %v, got %v", ErrSeatedElsewhere, err)
+	}
+
+	beer := []valueobject.OrderLine{{ProductID: products[0].GetID(), Quantity: 1}}
+	if _, err := tavern.Order(OrderRequest{CustomerID: guests[2], TableID: small.GetID(), Lines: beer}); !errors.Is(err, ErrNotSeated) {
+		t.Errorf("Expected error %v, got %v", ErrNotSeated, err)
+	}
+	first, err := tavern.Order(OrderRequest{CustomerID: guests[0], TableID: small.GetID(), Lines: beer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.GetTableID() != small.GetID() {
+		t.Errorf("Expected the order to be served at table %s, got %s", small.GetID(), first.GetTableID())
+	}
+	if _, err := tavern.Order(OrderRequest{CustomerID: guests[1], TableID: small.GetID(), Lines: beer}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the first guest moves to the large table and takes the beer along
+	if err := tavern.MoveGuests(small.GetID(), large.GetID(), guests[0]); err != nil {
+		t.Fatal(err)
+	}
+	moved, err := os.orders.Get(first.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved.GetTableID() != large.GetID() {
+		t.Errorf("Expected the order to move to table %s, got %s", large.GetID(), moved.GetTableID())
+	}
+
+	overview, err := tavern.Tables()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(overview) != 2 || overview[0].Number != 1 || overview[1].Number != 2 {
+		t.Fatalf("Expected both tables ordered by number, got %v", overview)
+	}
+	for _, o := range overview {
+		if o.Status != aggregate.TableOccupied || len(o.Guests) != 1 || len(o.Orders) != 1 || o.Total != 1.99 {
+			t.Errorf("Expected table %d to be occupied by one guest with one beer, got %v", o.Number, o)
+		}
+	}
+
+	// the rest of the party joins, the small table is free again
+	if err := tavern.MoveGuests(small.GetID(), large.GetID()); err != nil {
+		t.Fatal(err)
+	}
+	if err := tavern.SeatParty(large.GetID(), guests[2]); !errors.Is(err, aggregate.ErrTableOccupied) {
+		t.Errorf("Expected error %v, got %v", aggregate.ErrTableOccupied, err)
+	}
+	overview, err = tavern.Tables()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if overview[0].Status != aggregate.TableFree || len(overview[1].Guests) != 2 || overview[1].Total != 3.98 {
+		t.Errorf("Expected the party at table 2 with both beers, got %v", overview)
+	}
+
+	if err := tavern.ClearTable(large.GetID()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tavern.Order(OrderRequest{CustomerID: guests[0], TableID: large.GetID(), Lines: beer}); !errors.Is(err, ErrNotSeated) {
+		t.Errorf("Expected error %v, got %v", ErrNotSeated, err)
+	}
+}
@@ -1,17 +1,89 @@
 package service
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"sync"
+	"taverne/aggregate"
+	"taverne/domain/category"
+	catmemory "taverne/domain/category/memory"
+	"taverne/domain/idempotency"
+	idemmemory "taverne/domain/idempotency/memory"
+	idemsqlite "taverne/domain/idempotency/sqlite"
+	"taverne/domain/invoice"
+	invmemory "taverne/domain/invoice/memory"
+	invsqlite "taverne/domain/invoice/sqlite"
+	"taverne/domain/payment"
+	"taverne/domain/receipt"
+	"taverne/domain/reservation"
+	resmemory "taverne/domain/reservation/memory"
+	ressqlite "taverne/domain/reservation/sqlite"
+	"taverne/domain/tab"
+	tabmemory "taverne/domain/tab/memory"
+	"taverne/domain/table"
+	tablememory "taverne/domain/table/memory"
+	"taverne/valueobject"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+var (
+	// ErrIdempotencyKeyReused is returned when an idempotency key is sent again with a different request
+	ErrIdempotencyKeyReused = errors.New("the idempotency key was used for a different request")
+	// ErrRequestInProgress is returned when a request is retried while the first one is still being placed
+	ErrRequestInProgress = errors.New("a request with the idempotency key is still in progress")
+	// ErrInvalidRetention is returned when idempotency keys would be remembered for no time at all
+	ErrInvalidRetention = errors.New("the retention of idempotency keys has to be positive")
+	// ErrNotNumbered is returned together with a billed order which did not get its invoice number,
+	// the number can be assigned later with Tavern.Invoice
+	ErrNotNumbered = errors.New("the order is billed but has no invoice number")
+	// ErrRequestCancelled is returned when a request is retried whose order was cancelled,
+	// such as when its payment was unknown and turned out to fail
+	ErrRequestCancelled = errors.New("the order of the idempotency key was cancelled")
+	// ErrOrderOnTab is returned when an order on a tab is billed on its own, it is billed when the tab is closed
+	ErrOrderOnTab = errors.New("the order is billed with its tab")
+)
+
+const (
+	// invoiceAttempts is how often numbering a billed order is tried before the failure is returned
+	invoiceAttempts = 3
+	// recordAttempts is how often the result of a request is recorded under its idempotency key
+	// before the key is given up
+	recordAttempts = 3
+)
+
 // TavernConfiguration is an alias that takes a pointer and modifies the Tavern
 type TavernConfiguration func(os *Tavern) error
 
 type Tavern struct {
 	OrderService   *OrderService
-	BillingService interface{}
+	BillingService BillingService
+
+	tabs       tab.TabRepository
+	categories category.CategoryRepository
+	// tabMu serializes changes to tabs, so concurrent rounds are not lost
+	tabMu sync.Mutex
+	// invoices hands out the gap-free invoice numbers of billed orders and credit notes
+	invoices invoice.NumberRepository
+	// refundMu serializes refunds
+	refundMu sync.Mutex
+	// requests remembers the orders placed by idempotency key for the retention window
+	requests  idempotency.Repository
+	retention time.Duration
+	// tables are where parties are seated, tableMu serializes seating so no order misses a move
+	tables  table.TableRepository
+	tableMu sync.Mutex
+	// reservations hold tables for a time slot within the opening hours, reservationMu serializes bookings
+	reservations    reservation.ReservationRepository
+	openingHours    valueobject.OpeningHours
+	reservationSlot time.Duration
+	reservationMu   sync.Mutex
 }
 
 // NewTavern takes a variable amount of TavernConfigurations and builds a Tavern
@@ -25,6 +97,33 @@ func NewTavern(cfgs ...TavernConfiguration) (*Tavern, error) {
 			return nil, err
 		}
 	}
+	if t.BillingService == nil {
+		t.BillingService = logBilling{}
+	}
+	if t.tabs == nil {
+		t.tabs = tabmemory.New()
+	}
+	if t.categories == nil {
+		t.categories = catmemory.New()
+	}
+	if t.invoices == nil {
+		t.invoices = invmemory.New()
+	}
+	if t.requests == nil {
+		t.requests = idemmemory.New()
+	}
+	if t.retention == 0 {
+		t.retention = 24 * time.Hour
+	}
+	if t.tables == nil {
+		t.tables = tablememory.New()
+	}
+	if t.reservations == nil {
+		t.reservations = resmemory.New()
+	}
+	if t.reservationSlot == 0 {
+		t.reservationSlot = 2 * time.Hour
+	}
 	return t, nil
 }
 
@@ -37,15 +136,625 @@ func WithOrderService(os *OrderService) TavernConfiguration {
 	}
 }
 
-// Order performs an order for a customer
-func (t *Tavern) Order(customer uuid.UUID, products []uuid.UUID) error {
-	price, err := t.OrderService.CreateOrder(customer, products)
+// WithBillingService applies a given BillingService to the Tavern
+func WithBillingService(bs BillingService) TavernConfiguration {
+	return func(t *Tavern) error {
+		t.BillingService = bs
+		return nil
+	}
+}
+
+// WithPaymentBilling takes the money of every bill through payment gateways
+func WithPaymentBilling(cfgs ...PaymentConfiguration) TavernConfiguration {
+	return func(t *Tavern) error {
+		pb, err := NewPaymentBilling(cfgs...)
+		if err != nil {
+			return err
+		}
+		t.BillingService = pb
+		return nil
+	}
+}
+
+// WithIdempotencyRepository applies a given idempotency repository to the Tavern
+func WithIdempotencyRepository(ir idempotency.Repository) TavernConfiguration {
+	return func(t *Tavern) error {
+		t.requests = ir
+		return nil
+	}
+}
+
+// WithSQLiteIdempotencyRepository remembers idempotency keys in the sqlite database at connectionString
+func WithSQLiteIdempotencyRepository(connectionString string) TavernConfiguration {
+	return func(t *Tavern) error {
+		ir, err := idemsqlite.New(context.Background(), connectionString)
+		if err != nil {
+			return err
+		}
+		t.requests = ir
+		return nil
+	}
+}
+
+// WithIdempotencyRetention applies how long an idempotency key is remembered, one day by default
+func WithIdempotencyRetention(d time.Duration) TavernConfiguration {
+	return func(t *Tavern) error {
+		if d <= 0 {
+			return ErrInvalidRetention
+		}
+		t.retention = d
+		return nil
+	}
+}
+
+// WithTabRepository applies a given tab repository to the Tavern
+func WithTabRepository(tr tab.TabRepository) TavernConfiguration {
+	return func(t *Tavern) error {
+		t.tabs = tr
+		return nil
+	}
+}
+
+// WithMemoryTabRepository applies a memory tab repository to the Tavern
+func WithMemoryTabRepository() TavernConfiguration {
+	return WithTabRepository(tabmemory.New())
+}
+
+// WithTableRepository applies a given table repository to the Tavern
+func WithTableRepository(tr table.TableRepository) TavernConfiguration {
+	return func(t *Tavern) error {
+		t.tables = tr
+		return nil
+	}
+}
+
+// WithMemoryTableRepository applies a memory table repository with the given tables to the Tavern
+func WithMemoryTableRepository(tables []aggregate.Table) TavernConfiguration {
+	return func(t *Tavern) error {
+		tr := tablememory.New()
+		for _, tb := range tables {
+			if err := tr.Add(tb); err != nil {
+				return err
+			}
+		}
+		t.tables = tr
+		return nil
+	}
+}
+
+// WithReservationRepository applies a given reservation repository to the Tavern
+func WithReservationRepository(rr reservation.ReservationRepository) TavernConfiguration {
+	return func(t *Tavern) error {
+		t.reservations = rr
+		return nil
+	}
+}
+
+// WithSQLiteReservationRepository persists the reservations in the sqlite database at connectionString
+func WithSQLiteReservationRepository(connectionString string) TavernConfiguration {
+	return func(t *Tavern) error {
+		rr, err := ressqlite.New(context.Background(), connectionString)
+		if err != nil {
+			return err
+		}
+		t.reservations = rr
+		return nil
+	}
+}
+
+// WithOpeningHours applies the opening hours reservations have to fall into, without them the Tavern never closes
+func WithOpeningHours(hours valueobject.OpeningHours) TavernConfiguration {
+	return func(t *Tavern) error {
+		if err := hours.Validate(); err != nil {
+			return err
+		}
+		t.openingHours = hours
+		return nil
+	}
+}
+
+// WithReservationSlot applies how long a table is held for a reservation without a duration, two hours by default
+func WithReservationSlot(d time.Duration) TavernConfiguration {
+	return func(t *Tavern) error {
+		if d <= 0 {
+			return aggregate.ErrInvalidSlot
+		}
+		t.reservationSlot = d
+		return nil
+	}
+}
+
+// WithCategoryRepository applies a given category repository to the Tavern
+func WithCategoryRepository(cr category.CategoryRepository) TavernConfiguration {
+	return func(t *Tavern) error {
+		t.categories = cr
+		return nil
+	}
+}
+
+// WithMemoryCategoryRepository applies a memory category repository with the given categories to the Tavern
+func WithMemoryCategoryRepository(categories []aggregate.Category) TavernConfiguration {
+	return func(t *Tavern) error {
+		cr := catmemory.New()
+		for _, c := range categories {
+			if err := cr.Add(c); err != nil {
+				return err
+			}
+		}
+		t.categories = cr
+		return nil
+	}
+}
+
+// WithInvoiceNumberRepository applies a given invoice number repository to the Tavern
+func WithInvoiceNumberRepository(nr invoice.NumberRepository) TavernConfiguration {
+	return func(t *Tavern) error {
+		t.invoices = nr
+		return nil
+	}
+}
+
+// WithSQLiteInvoiceNumberRepository persists the invoice numbers in the sqlite database at connectionString
+func WithSQLiteInvoiceNumberRepository(connectionString string) TavernConfiguration {
+	return func(t *Tavern) error {
+		nr, err := invsqlite.New(context.Background(), connectionString)
+		if err != nil {
+			return err
+		}
+		t.invoices = nr
+		return nil
+	}
+}
+
+// Order performs an order for a customer and bills it right away.
+// A request with an idempotency key which was placed before returns the order placed back then,
+// with ErrPaymentUnknown as long as its payment is not settled by SettlePayment
+// will return ErrIdempotencyKeyReused if the key was used for a different request
+// A billed order which could not be numbered is returned together with ErrNotNumbered,
+// an order whose payment is unknown stays placed and is returned together with ErrPaymentUnknown.
+// If the order can not be recorded under its key, the key is given up and the order is returned with the error
+func (t *Tavern) Order(req OrderRequest) (aggregate.Order, error) {
+	if req.IdempotencyKey == "" {
+		return t.order(req)
+	}
+
+	now := t.OrderService.now()
+	if err := t.requests.DeleteBefore(now.Add(-t.retention)); err != nil {
+		log.Printf("forgetting expired idempotency keys failed: %v", err)
+	}
+	fp, err := fingerprint(req)
+	if err != nil {
+		return aggregate.Order{}, err
+	}
+
+	// reserving the key first makes sure concurrent retries do not both place the order
+	rec := idempotency.Record{Key: req.IdempotencyKey, Fingerprint: fp, CreatedAt: now}
+	err = t.requests.Add(rec)
+	if errors.Is(err, idempotency.ErrRecordAlreadyExist) {
+		return t.replay(rec)
+	}
+	if err != nil {
+		return aggregate.Order{}, err
+	}
+
+	order, err := t.order(req)
+	if err != nil && order.GetID() == uuid.Nil {
+		// nothing was placed, so the request may be retried under the same key
+		if derr := t.requests.Delete(rec.Key); derr != nil {
+			return aggregate.Order{}, errors.Join(err, derr)
+		}
+		return aggregate.Order{}, err
+	}
+	// the order is recorded even if it was not numbered or its payment is unknown,
+	// so a retry returns it instead of placing and billing it again
+	rec.Result = order.GetID()
+	if rerr := t.record(rec); rerr != nil {
+		// a key which stays in progress would block every retry, so it is given up
+		// and the placed order is returned together with the error
+		if derr := t.requests.Delete(rec.Key); derr != nil {
+			rerr = errors.Join(rerr, derr)
+		}
+		return order, errors.Join(err, fmt.Errorf("recording idempotency key of order %s: %w", order.GetID(), rerr))
+	}
+	return order, err
+}
+
+// record stores the result of a request under its idempotency key, a failure is retried
+func (t *Tavern) record(rec idempotency.Record) error {
+	var err error
+	for attempt := 0; attempt < recordAttempts; attempt++ {
+		if err = t.requests.Update(rec); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// replay returns the order placed by an earlier request under the same key
+// together with ErrPaymentUnknown or ErrNotNumbered as long as the order is not settled or numbered
+func (t *Tavern) replay(rec idempotency.Record) (aggregate.Order, error) {
+	earlier, err := t.requests.Get(rec.Key)
+	if err != nil {
+		return aggregate.Order{}, err
+	}
+	if earlier.Fingerprint != rec.Fingerprint {
+		return aggregate.Order{}, ErrIdempotencyKeyReused
+	}
+	if !earlier.Done() {
+		return aggregate.Order{}, ErrRequestInProgress
+	}
+	order, err := t.OrderService.orders.Get(earlier.Result)
+	if err != nil {
+		return aggregate.Order{}, err
+	}
+	// the order is replayed with the error its request would return now, so an order
+	// whose payment was never settled is not taken for a paid one
+	switch {
+	case order.GetStatus() == aggregate.OrderPlaced:
+		return order, fmt.Errorf("order %s: %w", order.GetID(), ErrPaymentUnknown)
+	case order.GetStatus() == aggregate.OrderCancelled:
+		return order, ErrRequestCancelled
+	case order.GetInvoiceNumber() == "":
+		return order, fmt.Errorf("order %s: %w", order.GetID(), ErrNotNumbered)
+	}
+	return order, nil
+}
+
+// fingerprint hashes the payload of a request, everything but its idempotency key
+func fingerprint(req OrderRequest) (string, error) {
+	req.IdempotencyKey = ""
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// order places and bills an order
+func (t *Tavern) order(req OrderRequest) (aggregate.Order, error) {
+	order, err := t.createOrder(req)
+	if err != nil {
+		return aggregate.Order{}, err
+	}
+	return t.checkout(req.PaymentMethod, order)
+}
+
+// checkout bills a placed order, marks it paid and numbers it.
+// An order which can not be billed is cancelled to release its stock
+func (t *Tavern) checkout(method payment.Method, order aggregate.Order) (aggregate.Order, error) {
+	err := t.bill(method, order)
+	if errors.Is(err, ErrPaymentUnknown) {
+		// the order may be paid, so it stays placed until SettlePayment settles the payment
+		return order, err
+	}
+	if err != nil {
+		if cerr := t.OrderService.CancelOrder(order.GetID()); cerr != nil {
+			return aggregate.Order{}, errors.Join(err, cerr)
+		}
+		return aggregate.Order{}, err
+	}
+
+	// the order is billed already, so it is returned even if marking or numbering it fails
+	if order, err = t.pay(order); err != nil {
+		return order, err
+	}
+	t.OrderService.dispatch(order)
+	order, err = t.invoice(order)
+	if err := t.OrderService.accruePoints(order.GetCustomerID(), order.GetID(), order.Total()); err != nil {
+		log.Printf("accruing loyalty points for order %s failed: %v", order.GetID(), err)
+	}
+	return order, err
+}
+
+// SettlePayment bills an order again whose payment is unknown, such as after Order returned ErrPaymentUnknown.
+// Billing is idempotent, so a payment which went through is not charged twice, and the settled order is paid
+// and numbered. An order which can not be billed is cancelled, while ErrPaymentUnknown leaves it placed for
+// another attempt. An order which was paid already is only numbered if it has no invoice number yet
+func (t *Tavern) SettlePayment(orderID uuid.UUID, method payment.Method) (aggregate.Order, error) {
+	order, err := t.OrderService.orders.Get(orderID)
+	if err != nil {
+		return aggregate.Order{}, err
+	}
+	if order.GetTabID() != uuid.Nil {
+		return order, ErrOrderOnTab
+	}
+	switch order.GetStatus() {
+	case aggregate.OrderPlaced:
+		return t.checkout(method, order)
+	case aggregate.OrderPaid, aggregate.OrderRefunded:
+		return t.invoice(order)
+	default:
+		return order, &aggregate.TransitionError{From: order.GetStatus(), To: aggregate.OrderPaid}
+	}
+}
+
+// bill charges the order by the payment method, an empty method bills by the default of the BillingService
+func (t *Tavern) bill(method payment.Method, order aggregate.Order) error {
+	if method == "" {
+		return t.BillingService.Bill(order.GetCustomerID(), order.GetID(), order.Total())
+	}
+	mb, ok := t.BillingService.(MethodBiller)
+	if !ok {
+		return fmt.Errorf("%s: %w", method, ErrUnknownPaymentMethod)
+	}
+	return mb.BillWith(method, order.GetCustomerID(), order.GetID(), order.Total())
+}
+
+// pay marks a billed order as paid and returns it with its new status
+func (t *Tavern) pay(order aggregate.Order) (aggregate.Order, error) {
+	if err := t.OrderService.PayOrder(order.GetID()); err != nil {
+		return order, err
+	}
+	return t.OrderService.orders.Get(order.GetID())
+}
+
+// Invoice assigns the invoice number to a billed order which did not get one, such as after
+// ErrNotNumbered was returned. An order which is numbered already is returned as it is
+func (t *Tavern) Invoice(orderID uuid.UUID) (aggregate.Order, error) {
+	order, err := t.OrderService.orders.Get(orderID)
+	if err != nil {
+		return aggregate.Order{}, err
+	}
+	return t.invoice(order)
+}
+
+// invoice assigns the next invoice number of the year to a billed order, a failure is retried
+// The number is allocated to the order, so retrying a failed update gets the same number and leaves no gap
+func (t *Tavern) invoice(order aggregate.Order) (aggregate.Order, error) {
+	var err error
+	for attempt := 0; attempt < invoiceAttempts; attempt++ {
+		var invoiced aggregate.Order
+		if invoiced, err = t.number(order); err == nil {
+			return invoiced, nil
+		}
+	}
+	return order, fmt.Errorf("order %s: %w: %w", order.GetID(), ErrNotNumbered, err)
+}
+
+// number allocates the invoice number of the order and stores it on the order
+// The number belongs to the year the order is billed in, which is not the year it was placed in
+// for an order placed on New Year's Eve and billed after midnight
+func (t *Tavern) number(order aggregate.Order) (aggregate.Order, error) {
+	if order.GetInvoiceNumber() != "" {
+		return order, nil
+	}
+	n, err := t.invoices.Allocate(t.OrderService.now().Year(), order.GetID())
+	if err != nil {
+		return order, err
+	}
+	invoiced := order
+	if err := invoiced.SetInvoiceNumber(n.String()); err != nil {
+		return order, err
+	}
+	if err := t.OrderService.advance(order.GetID(), func(o *aggregate.Order) error {
+		// an earlier attempt may have stored the number before failing
+		if o.GetInvoiceNumber() == n.String() {
+			return nil
+		}
+		return o.SetInvoiceNumber(n.String())
+	}); err != nil {
+		return order, err
+	}
+	return invoiced, nil
+}
+
+// Receipt returns the receipt of a billed order, which can be rendered by a receipt.Renderer
+func (t *Tavern) Receipt(orderID uuid.UUID) (receipt.Receipt, error) {
+	order, err := t.OrderService.orders.Get(orderID)
+	if err != nil {
+		return receipt.Receipt{}, err
+	}
+	return receipt.New(order)
+}
+
+// OpenTab opens a running bill for a customer, a customer can only have one open tab
+func (t *Tavern) OpenTab(customer uuid.UUID) (uuid.UUID, error) {
+	if _, err := t.OrderService.customers.Get(customer); err != nil {
+		return uuid.Nil, err
+	}
+
+	t.tabMu.Lock()
+	defer t.tabMu.Unlock()
+
+	tb := aggregate.NewTab(customer)
+	if err := t.tabs.Add(tb); err != nil {
+		return uuid.Nil, err
+	}
+	return tb.GetID(), nil
+}
+
+// OrderOnTab places an order for the tabs customer without billing it
+// The customer of the request is always the customer of the tab
+func (t *Tavern) OrderOnTab(tabID uuid.UUID, req OrderRequest) (aggregate.Order, error) {
+	t.tabMu.Lock()
+	defer t.tabMu.Unlock()
+
+	tb, err := t.tabs.Get(tabID)
+	if err != nil {
+		return aggregate.Order{}, err
+	}
+	if !tb.IsOpen() {
+		return aggregate.Order{}, aggregate.ErrTabClosed
+	}
+
+	req.CustomerID = tb.GetCustomerID()
+	order, err := t.createOrder(req)
+	if err != nil {
+		return aggregate.Order{}, err
+	}
+	if err := tb.AddOrder(order); err != nil {
+		return aggregate.Order{}, err
+	}
+	// the order remembers its tab, so a refund is paid back to whoever settled the tab
+	order.SetTabID(tabID)
+	if err := t.OrderService.advance(order.GetID(), func(o *aggregate.Order) error {
+		o.SetTabID(tabID)
+		return nil
+	}); err != nil {
+		return aggregate.Order{}, err
+	}
+	if err := t.tabs.Update(tb); err != nil {
+		return aggregate.Order{}, err
+	}
+	return order, nil
+}
+
+// CancelOrder cancels an order like OrderService.CancelOrder and takes an order
+// on an open tab off the tab, so its amount is no longer billed when the tab is closed
+func (t *Tavern) CancelOrder(orderID uuid.UUID) error {
+	t.tabMu.Lock()
+	defer t.tabMu.Unlock()
+
+	order, err := t.OrderService.orders.Get(orderID)
+	if err != nil {
+		return err
+	}
+	tb, err := t.tabs.GetOpen(order.GetCustomerID())
+	if errors.Is(err, tab.ErrTabNotFound) || (err == nil && !tb.HasOrder(orderID)) {
+		return t.OrderService.CancelOrder(orderID)
+	}
+	if err != nil {
+		return err
+	}
+
+	// take the order off the tab first, so a tab which payers started to settle refuses the cancel
+	if err := tb.RemoveOrder(order); err != nil {
+		return err
+	}
+	if err := t.OrderService.CancelOrder(orderID); err != nil {
+		return err
+	}
+	return t.tabs.Update(tb)
+}
+
+// SplitTab divides the total of a tab between several customers
+// and returns one transaction per payer. Line items are addressed by their
+// position across all orders of the tab, in the order they were placed
+func (t *Tavern) SplitTab(tabID uuid.UUID, split Split) ([]valueobject.Transaction, error) {
+	tb, err := t.tabs.Get(tabID)
+	if err != nil {
+		return nil, err
+	}
+	return t.splitTab(tb, split)
+}
+
+// CloseTab closes a tab and bills its total to the tabs customer
+func (t *Tavern) CloseTab(tabID uuid.UUID) error {
+	return t.CloseTabSplit(tabID, nil)
+}
+
+// CloseTabSplit closes a tab and bills every payer of the split through the billing service.
+// A nil split lets the tabs customer settle the whole tab. Every payer is billed under their own
+// reference and their settlement is stored on the tab right away. If billing fails the tab stays
+// open and a retry with the same split only bills the payers who did not settle yet.
+// The orders of the closed tab are numbered, an order which could not be numbered returns ErrNotNumbered.
+func (t *Tavern) CloseTabSplit(tabID uuid.UUID, split Split) error {
+	t.tabMu.Lock()
+	defer t.tabMu.Unlock()
+
+	tb, err := t.tabs.Get(tabID)
+	if err != nil {
+		return err
+	}
+	if !tb.IsOpen() {
+		return aggregate.ErrTabClosed
+	}
+	if split == nil {
+		split = SplitEvenly(tb.GetCustomerID())
+	}
+	transactions, err := t.splitTab(tb, split)
 	if err != nil {
 		return err
 	}
-	log.Printf("Bill the Customer: %0.0f", price)
+	// payers who settled before keep their share, so a retry has to split the tab the same way
+	var settled int
+	for _, tr := range transactions {
+		cents, ok := tb.Settled(tr.GetFrom())
+		if !ok {
+			continue
+		}
+		if cents != tr.GetAmount() {
+			return fmt.Errorf("payer %s settled %.2f already: %w", tr.GetFrom(), fromCents(cents), ErrSplitMismatch)
+		}
+		settled++
+	}
+	if settled != len(tb.GetSettlements()) {
+		return fmt.Errorf("payers who settled already are missing: %w", ErrSplitMismatch)
+	}
 
-	// Bill the customer
-	// err = t.BillingService.Bill(customer, price)
-	return nil
+	for _, tr := range transactions {
+		if _, ok := tb.Settled(tr.GetFrom()); ok || tr.GetAmount() == 0 {
+			continue
+		}
+		reference := settlementReference(tb.GetID(), tr.GetFrom())
+		if err := t.BillingService.Bill(tr.GetFrom(), reference, fromCents(tr.GetAmount())); err != nil {
+			return err
+		}
+		if err := tb.Settle(tr); err != nil {
+			return err
+		}
+		if err := t.tabs.Update(tb); err != nil {
+			return err
+		}
+		if err := t.OrderService.accruePoints(tr.GetFrom(), tb.GetID(), fromCents(tr.GetAmount())); err != nil {
+			log.Printf("accruing loyalty points for tab %s failed: %v", tb.GetID(), err)
+		}
+	}
+
+	if err := tb.Close(); err != nil {
+		return err
+	}
+	if err := t.tabs.Update(tb); err != nil {
+		return err
+	}
+
+	// every order on the settled tab is billed now, so each is paid, sent to the kitchen and gets its invoice number
+	var errs []error
+	for _, id := range tb.GetOrders() {
+		order, err := t.OrderService.orders.Get(id)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if order.GetStatus() == aggregate.OrderCancelled {
+			continue
+		}
+		if order.GetStatus() != aggregate.OrderPaid {
+			if order, err = t.pay(order); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			t.OrderService.dispatch(order)
+		}
+		if _, err := t.invoice(order); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// settlementReference is the reference a payer of a tab is billed under,
+// it is the same on every retry and differs between the payers of a tab
+func settlementReference(tabID, payer uuid.UUID) uuid.UUID {
+	return uuid.NewSHA1(tabID, payer[:])
+}
+
+// splitTab collects the items of all orders on the tab and splits their total,
+// an order which was cancelled without being taken off the tab is left out
+func (t *Tavern) splitTab(tb aggregate.Tab, split Split) ([]valueobject.Transaction, error) {
+	var items []valueobject.OrderItem
+	var total int
+	for _, id := range tb.GetOrders() {
+		order, err := t.OrderService.orders.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		if order.GetStatus() == aggregate.OrderCancelled {
+			continue
+		}
+		items = append(items, order.GetItems()...)
+		total += toCents(order.Total())
+	}
+	return t.OrderService.splitBill(split, tb.GetID(), fromCents(total), items)
 }
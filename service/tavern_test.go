@@ -1,8 +1,21 @@
 package service
 
 import (
+	"errors"
+	"math"
+	"path/filepath"
+	"sync"
 	"taverne/aggregate"
+	"taverne/domain/idempotency"
+	idemmemory "taverne/domain/idempotency/memory"
+	"taverne/domain/invoice"
+	invmemory "taverne/domain/invoice/memory"
+	"taverne/domain/loyalty"
+	"taverne/domain/receipt"
+	"taverne/domain/tab"
+	"taverne/valueobject"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -33,12 +46,15 @@ func Test_Tavern(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
-	order := []uuid.UUID{
-		products[0].GetID(),
+	order := OrderRequest{
+		CustomerID: cust.GetID(),
+		Lines: []valueobject.OrderLine{
+			{ProductID: products[0].GetID(), Quantity: 1},
+		},
 	}
 
 	// Execute Order
-	err = tavern.Order(cust.GetID(), order)
+	_, err = tavern.Order(order)
 	if err != nil {
 		t.Error(err)
 	}
@@ -73,13 +89,806 @@ func Test_SQLiteTavern(t *testing.T) {
 		t.Error(err)
 	}
 
-	order := []uuid.UUID{
-		products[0].GetID(),
+	order := OrderRequest{
+		CustomerID: cust.GetID(),
+		Lines: []valueobject.OrderLine{
+			{ProductID: products[0].GetID(), Quantity: 1},
+		},
 	}
 
 	// Execute order
-	err = tavern.Order(cust.GetID(), order)
+	_, err = tavern.Order(order)
 	if err != nil {
 		t.Error(err)
 	}
 }
+
+func Test_TavernOrderPaid(t *testing.T) {
+	products := init_products(t)
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tavern, err := NewTavern(WithOrderService(os))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cust, err := aggregate.NewCustomer("Donald")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customers.Add(cust); err != nil {
+		t.Fatal(err)
+	}
+
+	order, err := tavern.Order(OrderRequest{
+		CustomerID: cust.GetID(),
+		Lines: []valueobject.OrderLine{
+			{ProductID: products[0].GetID(), Quantity: 1},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.GetStatus() != aggregate.OrderPaid {
+		t.Errorf("Expected status %s, got %s", aggregate.OrderPaid, order.GetStatus())
+	}
+	if err := os.CancelOrder(order.GetID()); err != aggregate.ErrOrderInvoiced {
+		t.Errorf("Expected error %v, got %v", aggregate.ErrOrderInvoiced, err)
+	}
+	stored, err := os.orders.Get(order.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.GetStatus() != aggregate.OrderPaid {
+		t.Errorf("Expected status %s, got %s", aggregate.OrderPaid, stored.GetStatus())
+	}
+}
+
+// recordingBilling remembers every bill instead of charging anyone, bills of declined customers fail
+type recordingBilling struct {
+	bills    map[uuid.UUID]float64
+	declined map[uuid.UUID]bool
+}
+
+func (rb *recordingBilling) Bill(customer uuid.UUID, reference uuid.UUID, amount float64) error {
+	if rb.declined[customer] {
+		return aggregate.ErrInsufficientFunds
+	}
+	if rb.bills == nil {
+		rb.bills = make(map[uuid.UUID]float64)
+	}
+	rb.bills[customer] += amount
+	return nil
+}
+
+func Test_TavernTab(t *testing.T) {
+	products := init_products(t)
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	billing := &recordingBilling{}
+	tavern, err := NewTavern(
+		WithOrderService(os),
+		WithBillingService(billing),
+		WithMemoryTabRepository(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var customers []aggregate.Customer
+	for _, name := range []string{"Donald", "Daisy"} {
+		cust, err := aggregate.NewCustomer(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.customers.Add(cust); err != nil {
+			t.Fatal(err)
+		}
+		customers = append(customers, cust)
+	}
+	donald, daisy := customers[0].GetID(), customers[1].GetID()
+
+	round := OrderRequest{
+		Lines: []valueobject.OrderLine{
+			{ProductID: products[0].GetID(), Quantity: 1},
+		},
+	}
+
+	t.Run("Single settlement", func(t *testing.T) {
+		tabID, err := tavern.OpenTab(donald)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tavern.OpenTab(donald); err != tab.ErrTabAlreadyOpen {
+			t.Errorf("Expected error %v, got %v", tab.ErrTabAlreadyOpen, err)
+		}
+
+		var orders []uuid.UUID
+		for i := 0; i < 2; i++ {
+			order, err := tavern.OrderOnTab(tabID, round)
+			if err != nil {
+				t.Fatal(err)
+			}
+			orders = append(orders, order.GetID())
+		}
+		if len(billing.bills) != 0 {
+			t.Fatalf("Expected nothing billed before closing, got %v", billing.bills)
+		}
+
+		if err := tavern.CloseTab(tabID); err != nil {
+			t.Fatal(err)
+		}
+		if got := billing.bills[donald]; math.Abs(got-2*1.99) > 0.0001 {
+			t.Errorf("Expected %.2f billed, got %.2f", 2*1.99, got)
+		}
+		for _, id := range orders {
+			order, err := os.orders.Get(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if order.GetStatus() != aggregate.OrderPaid {
+				t.Errorf("Expected status %s, got %s", aggregate.OrderPaid, order.GetStatus())
+			}
+		}
+
+		if _, err := tavern.OrderOnTab(tabID, round); err != aggregate.ErrTabClosed {
+			t.Errorf("Expected error %v, got %v", aggregate.ErrTabClosed, err)
+		}
+		if err := tavern.CloseTab(tabID); err != aggregate.ErrTabClosed {
+			t.Errorf("Expected error %v, got %v", aggregate.ErrTabClosed, err)
+		}
+	})
+
+	t.Run("Cancelled orders", func(t *testing.T) {
+		billing.bills = nil
+		tabID, err := tavern.OpenTab(donald)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var orders []uuid.UUID
+		for i := 0; i < 3; i++ {
+			order, err := tavern.OrderOnTab(tabID, round)
+			if err != nil {
+				t.Fatal(err)
+			}
+			orders = append(orders, order.GetID())
+		}
+
+		if err := tavern.CancelOrder(orders[0]); err != nil {
+			t.Fatal(err)
+		}
+		tb, err := tavern.tabs.Get(tabID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tb.HasOrder(orders[0]) || tb.Total() != 2*1.99 {
+			t.Errorf("Expected the order taken off a tab of %.2f, got %v and %.2f", 2*1.99, tb.GetOrders(), tb.Total())
+		}
+		// an order cancelled past the tavern stays on the tab but is not billed
+		if err := os.CancelOrder(orders[1]); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := tavern.CloseTab(tabID); err != nil {
+			t.Fatal(err)
+		}
+		if got := billing.bills[donald]; got != 1.99 {
+			t.Errorf("Expected 1.99 billed, got %.2f", got)
+		}
+	})
+
+	t.Run("Split settlement", func(t *testing.T) {
+		billing.bills = nil
+		tabID, err := tavern.OpenTab(donald)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tavern.OrderOnTab(tabID, round); err != nil {
+			t.Fatal(err)
+		}
+		if err := tavern.CloseTabSplit(tabID, SplitEvenly(donald, daisy)); err != nil {
+			t.Fatal(err)
+		}
+		if billing.bills[donald] != 1.00 || billing.bills[daisy] != 0.99 {
+			t.Errorf("Expected 1.00 and 0.99, got %v", billing.bills)
+		}
+	})
+
+	t.Run("Split settlement retried", func(t *testing.T) {
+		billing.bills = nil
+		billing.declined = map[uuid.UUID]bool{daisy: true}
+		tabID, err := tavern.OpenTab(donald)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tavern.OrderOnTab(tabID, round); err != nil {
+			t.Fatal(err)
+		}
+		if err := tavern.CloseTabSplit(tabID, SplitEvenly(donald, daisy)); err != aggregate.ErrInsufficientFunds {
+			t.Fatalf("Expected error %v, got %v", aggregate.ErrInsufficientFunds, err)
+		}
+		tb, err := tavern.tabs.Get(tabID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !tb.IsOpen() {
+			t.Error("Expected the tab to stay open")
+		}
+		if cents, ok := tb.Settled(donald); !ok || cents != 100 {
+			t.Errorf("Expected 100 cents settled by Donald, got %d", cents)
+		}
+		if _, err := tavern.OrderOnTab(tabID, round); err != aggregate.ErrTabSettling {
+			t.Errorf("Expected error %v, got %v", aggregate.ErrTabSettling, err)
+		}
+		if err := tavern.CloseTabSplit(tabID, SplitEvenly(daisy)); !errors.Is(err, ErrSplitMismatch) {
+			t.Errorf("Expected error %v, got %v", ErrSplitMismatch, err)
+		}
+
+		billing.declined = nil
+		if err := tavern.CloseTabSplit(tabID, SplitEvenly(donald, daisy)); err != nil {
+			t.Fatal(err)
+		}
+		if billing.bills[donald] != 1.00 || billing.bills[daisy] != 0.99 {
+			t.Errorf("Expected 1.00 and 0.99, got %v", billing.bills)
+		}
+		if err := tavern.CloseTabSplit(tabID, SplitEvenly(donald, daisy)); err != aggregate.ErrTabClosed {
+			t.Errorf("Expected error %v, got %v", aggregate.ErrTabClosed, err)
+		}
+	})
+}
+
+func Test_TavernLoyalty(t *testing.T) {
+	products := init_products(t)
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+		WithLoyaltyProgram(loyalty.Program{PointsPerUnit: 10, PointValue: 0.01}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tavern, err := NewTavern(WithOrderService(os))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cust, err := aggregate.NewCustomer("Donald")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customers.Add(cust); err != nil {
+		t.Fatal(err)
+	}
+
+	points := func() aggregate.Customer {
+		c, err := os.customers.Get(cust.GetID())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	// 5 × 1.99 = 9.95 earns 99 points
+	_, err = tavern.Order(OrderRequest{
+		CustomerID: cust.GetID(),
+		Lines:      []valueobject.OrderLine{{ProductID: products[0].GetID(), Quantity: 5}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := points(); c.GetPoints() != 99 {
+		t.Fatalf("Expected 99 points, got %d", c.GetPoints())
+	}
+
+	// a declined order gives the redeemed points back
+	tavern.BillingService = &recordingBilling{declined: map[uuid.UUID]bool{cust.GetID(): true}}
+	_, err = tavern.Order(OrderRequest{
+		CustomerID:   cust.GetID(),
+		Lines:        []valueobject.OrderLine{{ProductID: products[0].GetID(), Quantity: 1}},
+		RedeemPoints: 50,
+	})
+	if err != aggregate.ErrInsufficientFunds {
+		t.Fatalf("Expected error %v, got %v", aggregate.ErrInsufficientFunds, err)
+	}
+	if c := points(); c.GetPoints() != 99 {
+		t.Fatalf("Expected 99 points after the declined order, got %d", c.GetPoints())
+	}
+	tavern.BillingService = &recordingBilling{}
+
+	// 0.99 only needs 99 points, the order is free and earns nothing
+	order, err := tavern.Order(OrderRequest{
+		CustomerID:   cust.GetID(),
+		Lines:        []valueobject.OrderLine{{ProductID: products[1].GetID(), Quantity: 1}},
+		RedeemPoints: 99,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Total() != 0 {
+		t.Errorf("Expected the order to be paid with points, got %.2f", order.Total())
+	}
+
+	c := points()
+	if c.GetPoints() != 0 {
+		t.Errorf("Expected 0 points left, got %d", c.GetPoints())
+	}
+	kinds := []valueobject.TransactionKind{
+		valueobject.TransactionLoyaltyAccrual,
+		valueobject.TransactionLoyaltyRedemption,
+		valueobject.TransactionLoyaltyAccrual,
+		valueobject.TransactionLoyaltyRedemption,
+	}
+	transactions := c.GetTransactions()
+	if len(transactions) != len(kinds) {
+		t.Fatalf("Expected %d transactions, got %d", len(kinds), len(transactions))
+	}
+	for i, kind := range kinds {
+		if transactions[i].GetKind() != kind {
+			t.Errorf("Expected transaction %d to be %s, got %s", i, kind, transactions[i].GetKind())
+		}
+	}
+
+	_, err = tavern.Order(OrderRequest{
+		CustomerID:   cust.GetID(),
+		Lines:        []valueobject.OrderLine{{ProductID: products[1].GetID(), Quantity: 1}},
+		RedeemPoints: 1,
+	})
+	if err != aggregate.ErrInsufficientPoints {
+		t.Errorf("Expected error %v, got %v", aggregate.ErrInsufficientPoints, err)
+	}
+}
+
+func Test_TavernReceipt(t *testing.T) {
+	products := init_products(t)
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tavern, err := NewTavern(WithOrderService(os))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cust, err := aggregate.NewCustomer("Donald")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customers.Add(cust); err != nil {
+		t.Fatal(err)
+	}
+
+	// every billed order gets the next number
+	for i, expected := range []int{1, 2} {
+		order, err := tavern.Order(OrderRequest{
+			CustomerID: cust.GetID(),
+			Lines:      []valueobject.OrderLine{{ProductID: products[0].GetID(), Quantity: i + 1}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := tavern.Receipt(order.GetID())
+		if err != nil {
+			t.Fatal(err)
+		}
+		number := invoice.Number{Year: order.GetPlacedAt().Year(), Sequence: expected}.String()
+		if r.Number != number || order.GetInvoiceNumber() != number {
+			t.Errorf("Expected invoice number %s, got %s", number, r.Number)
+		}
+		if len(r.Lines) != 1 || r.Lines[0].Quantity != i+1 || r.Total != order.Total() {
+			t.Errorf("Expected %d beers for %.2f, got %v", i+1, order.Total(), r)
+		}
+	}
+
+	// an order on a tab is not billed yet, so it has no receipt
+	tabID, err := tavern.OpenTab(cust.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	order, err := tavern.OrderOnTab(tabID, OrderRequest{
+		CustomerID: cust.GetID(),
+		Lines:      []valueobject.OrderLine{{ProductID: products[1].GetID(), Quantity: 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tavern.Receipt(order.GetID()); err != receipt.ErrNotInvoiced {
+		t.Errorf("Expected error %v, got %v", receipt.ErrNotInvoiced, err)
+	}
+
+	// closing the tab bills and numbers its orders
+	if err := tavern.CloseTab(tabID); err != nil {
+		t.Fatal(err)
+	}
+	r, err := tavern.Receipt(order.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if number := (invoice.Number{Year: order.GetPlacedAt().Year(), Sequence: 3}).String(); r.Number != number {
+		t.Errorf("Expected invoice number %s, got %s", number, r.Number)
+	}
+}
+
+// failingNumbers fails the next allocations before handing out numbers of the wrapped repository
+type failingNumbers struct {
+	invoice.NumberRepository
+	failures int
+}
+
+func (fn *failingNumbers) Allocate(year int, reference uuid.UUID) (invoice.Number, error) {
+	if fn.failures > 0 {
+		fn.failures--
+		return invoice.Number{}, errors.New("database is locked")
+	}
+	return fn.NumberRepository.Allocate(year, reference)
+}
+
+func Test_TavernInvoiceFailure(t *testing.T) {
+	products := init_products(t)
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	numbers := &failingNumbers{NumberRepository: invmemory.New()}
+	tavern, err := NewTavern(
+		WithOrderService(os),
+		WithInvoiceNumberRepository(numbers),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cust, err := aggregate.NewCustomer("Donald")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customers.Add(cust); err != nil {
+		t.Fatal(err)
+	}
+	req := OrderRequest{
+		CustomerID: cust.GetID(),
+		Lines:      []valueobject.OrderLine{{ProductID: products[0].GetID(), Quantity: 1}},
+	}
+
+	// a failure is retried
+	numbers.failures = invoiceAttempts - 1
+	order, err := tavern.Order(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.GetInvoiceNumber() == "" {
+		t.Error("Expected the order to be numbered")
+	}
+
+	// a lasting failure returns the billed order, which is numbered later
+	numbers.failures = invoiceAttempts
+	order, err = tavern.Order(req)
+	if !errors.Is(err, ErrNotNumbered) {
+		t.Fatalf("Expected error %v, got %v", ErrNotNumbered, err)
+	}
+	if order.GetID() == uuid.Nil || order.GetInvoiceNumber() != "" {
+		t.Fatalf("Expected the billed order without number, got %v", order)
+	}
+	invoiced, err := tavern.Invoice(order.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := tavern.Invoice(order.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if invoiced.GetInvoiceNumber() == "" || again.GetInvoiceNumber() != invoiced.GetInvoiceNumber() {
+		t.Errorf("Expected one invoice number, got %s and %s", invoiced.GetInvoiceNumber(), again.GetInvoiceNumber())
+	}
+}
+
+func Test_TavernInvoiceYear(t *testing.T) {
+	products := init_products(t)
+	now := time.Date(2024, time.December, 31, 23, 30, 0, 0, time.UTC)
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+		WithClock(func() time.Time { return now }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tavern, err := NewTavern(WithOrderService(os))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cust, err := aggregate.NewCustomer("Donald")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customers.Add(cust); err != nil {
+		t.Fatal(err)
+	}
+
+	// the order is placed on New Year's Eve and billed after midnight
+	tabID, err := tavern.OpenTab(cust.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	order, err := tavern.OrderOnTab(tabID, OrderRequest{
+		CustomerID: cust.GetID(),
+		Lines:      []valueobject.OrderLine{{ProductID: products[0].GetID(), Quantity: 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(time.Hour)
+	if err := tavern.CloseTab(tabID); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := tavern.Receipt(order.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if number := (invoice.Number{Year: 2025, Sequence: 1}).String(); r.Number != number {
+		t.Errorf("Expected invoice number %s, got %s", number, r.Number)
+	}
+}
+
+func Test_TavernConcurrentInvoices(t *testing.T) {
+	products := init_products(t)
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tavern, err := NewTavern(
+		WithOrderService(os),
+		WithSQLiteInvoiceNumberRepository(filepath.Join(t.TempDir(), "invoice.db")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cust, err := aggregate.NewCustomer("Donald")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customers.Add(cust); err != nil {
+		t.Fatal(err)
+	}
+
+	// ten beers are in stock, so ten of the orders are billed and numbered
+	const orders = 15
+	var wg sync.WaitGroup
+	numbers := make(chan string, orders)
+	for i := 0; i < orders; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			order, err := tavern.Order(OrderRequest{
+				CustomerID: cust.GetID(),
+				Lines:      []valueobject.OrderLine{{ProductID: products[0].GetID(), Quantity: 1}},
+			})
+			if err == nil {
+				numbers <- order.GetInvoiceNumber()
+			}
+		}()
+	}
+	wg.Wait()
+	close(numbers)
+
+	seen := make(map[string]bool)
+	for n := range numbers {
+		seen[n] = true
+	}
+	year := os.now().Year()
+	for i := 1; i <= 10; i++ {
+		n := invoice.Number{Year: year, Sequence: i}.String()
+		if !seen[n] {
+			t.Errorf("Expected invoice number %s, got %v", n, seen)
+		}
+	}
+	if len(seen) != 10 {
+		t.Errorf("Expected 10 invoice numbers, got %d", len(seen))
+	}
+}
+
+func Test_TavernIdempotentOrder(t *testing.T) {
+	products := init_products(t)
+	now := time.Date(2024, time.June, 1, 20, 0, 0, 0, time.UTC)
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+		WithClock(func() time.Time { return now }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tavern, err := NewTavern(
+		WithOrderService(os),
+		WithSQLiteIdempotencyRepository(filepath.Join(t.TempDir(), "idempotency.db")),
+		WithIdempotencyRetention(time.Hour),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cust, err := aggregate.NewCustomer("Donald")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customers.Add(cust); err != nil {
+		t.Fatal(err)
+	}
+	beer := products[0].GetID()
+	request := func(key string, quantity int) OrderRequest {
+		return OrderRequest{
+			CustomerID:     cust.GetID(),
+			Lines:          []valueobject.OrderLine{{ProductID: beer, Quantity: quantity}},
+			IdempotencyKey: key,
+		}
+	}
+	stock := func() int {
+		p, err := os.products.GetByID(beer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p.GetQuantity()
+	}
+
+	// the tablet retries the same request concurrently, the order is placed once
+	var wg sync.WaitGroup
+	placed := make(chan uuid.UUID, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			order, err := tavern.Order(request("tablet-1", 2))
+			if err == nil {
+				placed <- order.GetID()
+			} else if err != ErrRequestInProgress {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	close(placed)
+	var first uuid.UUID
+	for id := range placed {
+		if first == uuid.Nil {
+			first = id
+		}
+		if id != first {
+			t.Errorf("Expected order %v for every retry, got %v", first, id)
+		}
+	}
+	order, err := tavern.Order(request("tablet-1", 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.GetID() != first || order.GetInvoiceNumber() == "" {
+		t.Errorf("Expected the billed order %v, got %v", first, order.GetID())
+	}
+	if stock() != 8 {
+		t.Errorf("Expected 8 beers in stock, got %d", stock())
+	}
+
+	if _, err := tavern.Order(request("tablet-1", 3)); err != ErrIdempotencyKeyReused {
+		t.Errorf("Expected error %v, got %v", ErrIdempotencyKeyReused, err)
+	}
+
+	// a failed request is not remembered, so it can be retried once the problem is solved
+	if _, err := tavern.Order(request("tablet-2", 20)); !errors.Is(err, aggregate.ErrInsufficientStock) {
+		t.Errorf("Expected error %v, got %v", aggregate.ErrInsufficientStock, err)
+	}
+	if _, err := tavern.Order(request("tablet-2", 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	// after the retention window the key is forgotten
+	now = now.Add(2 * time.Hour)
+	order, err = tavern.Order(request("tablet-1", 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.GetID() == first {
+		t.Error("Expected a new order once the key expired")
+	}
+	if stock() != 5 {
+		t.Errorf("Expected 5 beers in stock, got %d", stock())
+	}
+}
+
+// failingRequests fails the next updates before recording in the wrapped repository
+type failingRequests struct {
+	idempotency.Repository
+	failures int
+}
+
+func (fr *failingRequests) Update(r idempotency.Record) error {
+	if fr.failures > 0 {
+		fr.failures--
+		return errors.New("database is locked")
+	}
+	return fr.Repository.Update(r)
+}
+
+func Test_TavernIdempotentOrderNotRecorded(t *testing.T) {
+	products := init_products(t)
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests := &failingRequests{Repository: idemmemory.New()}
+	tavern, err := NewTavern(
+		WithOrderService(os),
+		WithIdempotencyRepository(requests),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cust, err := aggregate.NewCustomer("Donald")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customers.Add(cust); err != nil {
+		t.Fatal(err)
+	}
+	request := func(key string) OrderRequest {
+		return OrderRequest{
+			CustomerID:     cust.GetID(),
+			Lines:          []valueobject.OrderLine{{ProductID: products[0].GetID(), Quantity: 1}},
+			IdempotencyKey: key,
+		}
+	}
+
+	// a failed update is retried
+	requests.failures = recordAttempts - 1
+	order, err := tavern.Order(request("tablet-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec, err := requests.Get("tablet-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Result != order.GetID() {
+		t.Errorf("Expected order %v recorded, got %v", order.GetID(), rec.Result)
+	}
+
+	// a lasting failure gives the key up and returns the placed order with the error
+	requests.failures = recordAttempts
+	order, err = tavern.Order(request("tablet-2"))
+	if err == nil || order.GetID() == uuid.Nil {
+		t.Fatalf("Expected the placed order with an error, got %v and %v", order.GetID(), err)
+	}
+	if _, err := requests.Get("tablet-2"); err != idempotency.ErrRecordNotFound {
+		t.Errorf("Expected error %v, got %v", idempotency.ErrRecordNotFound, err)
+	}
+	if _, err := tavern.Order(request("tablet-2")); err != nil {
+		t.Errorf("Expected the key not to be stuck, got %v", err)
+	}
+}
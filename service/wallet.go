@@ -0,0 +1,103 @@
+package service
+
+import (
+	"errors"
+	"taverne/aggregate"
+	"taverne/domain/customer"
+	"taverne/valueobject"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrWalletReferenceReused is returned when a reference is billed or a refund is paid again with another amount
+	ErrWalletReferenceReused = errors.New("the reference was processed by the wallet with another amount")
+)
+
+// WalletBilling is a BillingService which draws every bill from the prepaid wallet of the customer
+// Wallet changes go through CustomerRepository.Change, so two bills can not both spend the same balance.
+// The transactions of the wallet record the references it billed and the refunds it paid back,
+// so a retried bill or refund is not processed twice
+type WalletBilling struct {
+	customers customer.CustomerRepository
+}
+
+// NewWalletBilling creates a WalletBilling on top of the customer repository
+func NewWalletBilling(cr customer.CustomerRepository) *WalletBilling {
+	return &WalletBilling{
+		customers: cr,
+	}
+}
+
+// Bill charges the wallet of the customer, billing the same reference again charges nothing
+// will return aggregate.ErrInsufficientFunds if the balance and the credit limit do not cover the bill
+func (wb *WalletBilling) Bill(customerID uuid.UUID, reference uuid.UUID, amount float64) error {
+	if toCents(amount) == 0 {
+		return nil
+	}
+
+	_, err := wb.customers.Change(customerID, func(c *aggregate.Customer) error {
+		if t, ok := processed(*c, valueobject.TransactionWalletCharge, reference); ok {
+			if t.GetAmount() != toCents(amount) {
+				return ErrWalletReferenceReused
+			}
+			return nil
+		}
+		_, err := c.Charge(toCents(amount), reference)
+		return err
+	})
+	return err
+}
+
+// Refund pays amount back into the wallet of the customer, the refund is recorded towards
+// the refund ID, so paying the same refund again returns the transaction of the first time
+func (wb *WalletBilling) Refund(customerID uuid.UUID, reference uuid.UUID, refundID uuid.UUID, amount float64) (valueobject.Transaction, error) {
+	var t valueobject.Transaction
+	_, err := wb.customers.Change(customerID, func(c *aggregate.Customer) (err error) {
+		if earlier, ok := processed(*c, valueobject.TransactionRefund, refundID); ok {
+			if earlier.GetAmount() != -toCents(amount) {
+				return ErrWalletReferenceReused
+			}
+			t = earlier
+			return nil
+		}
+		t, err = c.Refund(toCents(amount), refundID)
+		return err
+	})
+	return t, err
+}
+
+// processed returns the transaction of the kind the wallet recorded towards the reference before
+func processed(c aggregate.Customer, kind valueobject.TransactionKind, reference uuid.UUID) (valueobject.Transaction, bool) {
+	for _, t := range c.GetTransactions() {
+		if t.GetKind() == kind && t.GetTo() == reference {
+			return t, true
+		}
+	}
+	return valueobject.Transaction{}, false
+}
+
+// TopUp pays amount into the wallet of the customer
+func (wb *WalletBilling) TopUp(customerID uuid.UUID, amount float64) error {
+	_, err := wb.customers.Change(customerID, func(c *aggregate.Customer) error {
+		return c.TopUp(valueobject.NewTransaction(valueobject.TransactionTopUp, toCents(amount), customerID, customerID))
+	})
+	return err
+}
+
+// Balance returns the wallet balance of the customer, negative while the customer is on credit
+func (wb *WalletBilling) Balance(customerID uuid.UUID) (float64, error) {
+	c, err := wb.customers.Get(customerID)
+	if err != nil {
+		return 0, err
+	}
+	return fromCents(c.GetBalance()), nil
+}
+
+// SetCreditLimit allows the wallet of the customer to be overdrawn by limit
+func (wb *WalletBilling) SetCreditLimit(customerID uuid.UUID, limit float64) error {
+	_, err := wb.customers.Change(customerID, func(c *aggregate.Customer) error {
+		return c.SetCreditLimit(toCents(limit))
+	})
+	return err
+}
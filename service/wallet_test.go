@@ -0,0 +1,215 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"taverne/aggregate"
+	"taverne/domain/loyalty"
+	"taverne/valueobject"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestWallet_Bill(t *testing.T) {
+	products := init_products(t)
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wallet := NewWalletBilling(os.customers)
+	tavern, err := NewTavern(
+		WithOrderService(os),
+		WithBillingService(wallet),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cust, err := aggregate.NewCustomer("Donald")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customers.Add(cust); err != nil {
+		t.Fatal(err)
+	}
+	if err := wallet.TopUp(cust.GetID(), 5); err != nil {
+		t.Fatal(err)
+	}
+
+	beer := products[0].GetID()
+	order := func(quantity int) error {
+		_, err := tavern.Order(OrderRequest{
+			CustomerID: cust.GetID(),
+			Lines:      []valueobject.OrderLine{{ProductID: beer, Quantity: quantity}},
+		})
+		return err
+	}
+	balance := func() float64 {
+		b, err := wallet.Balance(cust.GetID())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	// 2 × 1.99 from 5.00
+	if err := order(2); err != nil {
+		t.Fatal(err)
+	}
+	if got := balance(); got != 1.02 {
+		t.Errorf("Expected balance 1.02, got %.2f", got)
+	}
+
+	// 1.99 is more than is left, the order is cancelled and the beer goes back into stock
+	if err := order(1); !errors.Is(err, aggregate.ErrInsufficientFunds) {
+		t.Fatalf("Expected error %v, got %v", aggregate.ErrInsufficientFunds, err)
+	}
+	p, err := os.products.GetByID(beer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.GetQuantity() != 8 {
+		t.Errorf("Expected 8 beers in stock, got %d", p.GetQuantity())
+	}
+
+	// on credit the order goes through
+	if err := wallet.SetCreditLimit(cust.GetID(), 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := order(1); err != nil {
+		t.Fatal(err)
+	}
+	if got := balance(); got != -0.97 {
+		t.Errorf("Expected balance -0.97, got %.2f", got)
+	}
+}
+
+func TestWallet_Retries(t *testing.T) {
+	os, err := NewOrderService(WithMemoryCustomerRepository())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wallet := NewWalletBilling(os.customers)
+
+	cust, err := aggregate.NewCustomer("Donald")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customers.Add(cust); err != nil {
+		t.Fatal(err)
+	}
+	if err := wallet.TopUp(cust.GetID(), 5); err != nil {
+		t.Fatal(err)
+	}
+	balance := func() float64 {
+		b, err := wallet.Balance(cust.GetID())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	// a retried bill is charged once
+	reference := uuid.New()
+	for i := 0; i < 2; i++ {
+		if err := wallet.Bill(cust.GetID(), reference, 1.99); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := balance(); got != 3.01 {
+		t.Errorf("Expected balance 3.01, got %.2f", got)
+	}
+	if err := wallet.Bill(cust.GetID(), reference, 2.99); err != ErrWalletReferenceReused {
+		t.Errorf("Expected error %v, got %v", ErrWalletReferenceReused, err)
+	}
+
+	// a retried refund is paid back once, another refund of the same reference is paid as well
+	refund := uuid.New()
+	first, err := wallet.Refund(cust.GetID(), reference, refund, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := wallet.Refund(cust.GetID(), reference, refund, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != first {
+		t.Errorf("Expected the transaction of the first refund, got %v", again)
+	}
+	if _, err := wallet.Refund(cust.GetID(), reference, uuid.New(), 0.99); err != nil {
+		t.Fatal(err)
+	}
+	if got := balance(); got != 5 {
+		t.Errorf("Expected balance 5.00, got %.2f", got)
+	}
+	if _, err := wallet.Refund(cust.GetID(), reference, refund, 0.5); err != ErrWalletReferenceReused {
+		t.Errorf("Expected error %v, got %v", ErrWalletReferenceReused, err)
+	}
+}
+
+func TestWallet_ConcurrentCustomerChanges(t *testing.T) {
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithLoyaltyProgram(loyalty.Program{PointsPerUnit: 1, PointValue: 0.01}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wallet := NewWalletBilling(os.customers)
+	customers := NewCustomerService(os.customers)
+
+	cust, err := aggregate.NewCustomer("Donald")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customers.Add(cust); err != nil {
+		t.Fatal(err)
+	}
+
+	// wallet, profile and loyalty changes of one customer must not overwrite each other
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			if err := wallet.TopUp(cust.GetID(), 1); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := customers.SetMarketingConsent(cust.GetID(), true); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if err := os.accruePoints(cust.GetID(), uuid.New(), 1); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	c, err := os.customers.Get(cust.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.GetBalance() != 1000 {
+		t.Errorf("Expected a balance of 1000 cents, got %d", c.GetBalance())
+	}
+	if c.GetPoints() != 10 {
+		t.Errorf("Expected 10 points, got %d", c.GetPoints())
+	}
+	if len(c.GetTransactions()) != 20 {
+		t.Errorf("Expected 20 transactions, got %d", len(c.GetTransactions()))
+	}
+	if !c.HasMarketingConsent() {
+		t.Error("Expected the marketing consent to be stored")
+	}
+}
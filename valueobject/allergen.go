@@ -0,0 +1,60 @@
+package valueobject
+
+// Allergen is one of the 14 allergens which have to be declared in the EU
+type Allergen string
+
+const (
+	AllergenGluten      Allergen = "gluten"
+	AllergenCrustaceans Allergen = "crustaceans"
+	AllergenEggs        Allergen = "eggs"
+	AllergenFish        Allergen = "fish"
+	AllergenPeanuts     Allergen = "peanuts"
+	AllergenSoybeans    Allergen = "soybeans"
+	AllergenMilk        Allergen = "milk"
+	AllergenNuts        Allergen = "nuts"
+	AllergenCelery      Allergen = "celery"
+	AllergenMustard     Allergen = "mustard"
+	AllergenSesame      Allergen = "sesame"
+	AllergenSulphites   Allergen = "sulphites"
+	AllergenLupin       Allergen = "lupin"
+	AllergenMolluscs    Allergen = "molluscs"
+)
+
+// Allergens returns all allergens in the order of the EU regulation
+func Allergens() []Allergen {
+	return []Allergen{
+		AllergenGluten, AllergenCrustaceans, AllergenEggs, AllergenFish, AllergenPeanuts,
+		AllergenSoybeans, AllergenMilk, AllergenNuts, AllergenCelery, AllergenMustard,
+		AllergenSesame, AllergenSulphites, AllergenLupin, AllergenMolluscs,
+	}
+}
+
+// Valid reports if the allergen is one of the 14 declared allergens
+func (a Allergen) Valid() bool {
+	for _, known := range Allergens() {
+		if a == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Diet is a dietary preference of a customer
+type Diet string
+
+const (
+	DietVegetarian  Diet = "vegetarian"
+	DietVegan       Diet = "vegan"
+	DietPescatarian Diet = "pescatarian"
+	DietHalal       Diet = "halal"
+	DietKosher      Diet = "kosher"
+)
+
+// Valid reports if the diet is one of the known diets
+func (d Diet) Valid() bool {
+	switch d {
+	case DietVegetarian, DietVegan, DietPescatarian, DietHalal, DietKosher:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,13 @@
+package valueobject
+
+import "github.com/google/uuid"
+
+// Discount is a reduction applied to an order by a pricing rule
+type Discount struct {
+	// Description tells the customer why the discount was given, such as "Happy hour"
+	Description string
+	// ProductID is the discounted product, uuid.Nil for discounts on the whole order
+	ProductID uuid.UUID
+	// Amount is the reduction of the order total
+	Amount float64
+}
@@ -0,0 +1,51 @@
+package valueobject
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrInvalidOpeningHours is returned when a period closes before it opens or opens outside of its day
+	ErrInvalidOpeningHours = errors.New("opening hours have to open within the day and close after they open")
+)
+
+// Period is the time the tavern is open on one day, as offsets from midnight.
+// Close may be later than 24h for a night which ends after midnight
+type Period struct {
+	Open  time.Duration
+	Close time.Duration
+}
+
+// OpeningHours are the periods the tavern is open on each weekday, a day without periods is a closed day
+type OpeningHours map[time.Weekday][]Period
+
+// Validate checks that every period opens within its day and closes after it opens
+func (h OpeningHours) Validate() error {
+	for _, periods := range h {
+		for _, p := range periods {
+			if p.Open < 0 || p.Open >= 24*time.Hour || p.Close <= p.Open {
+				return ErrInvalidOpeningHours
+			}
+		}
+	}
+	return nil
+}
+
+// Contains reports if the tavern is open for the whole slot from start to end.
+// Nil opening hours are always open
+func (h OpeningHours) Contains(start, end time.Time) bool {
+	if h == nil {
+		return true
+	}
+	// a slot right after midnight may still belong to the night before
+	midnight := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+	for _, day := range []time.Time{midnight, midnight.AddDate(0, 0, -1)} {
+		for _, p := range h[day.Weekday()] {
+			if !start.Before(day.Add(p.Open)) && !end.After(day.Add(p.Close)) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,26 @@
+package valueobject
+
+import "github.com/google/uuid"
+
+// OrderItem is a priced position of a placed order
+// It snapshots the product name and unit price at the time the order was placed
+type OrderItem struct {
+	ProductID uuid.UUID
+	Name      string
+	// VariantID and Variant are the chosen variant, uuid.Nil and empty for products without variants
+	VariantID uuid.UUID
+	Variant   string
+	// Modifiers are the names of the chosen extras
+	Modifiers []string
+	Quantity  int
+	Notes     string
+	// UnitPrice is the price of the variant including the surcharges of all modifiers
+	UnitPrice float64
+	// TaxCategory is the tax category of the product when the order was placed
+	TaxCategory TaxCategory
+}
+
+// Total returns unit price × quantity
+func (i OrderItem) Total() float64 {
+	return i.UnitPrice * float64(i.Quantity)
+}
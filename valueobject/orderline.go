@@ -0,0 +1,16 @@
+package valueobject
+
+import "github.com/google/uuid"
+
+// OrderLine is a single position of an order request
+// It references a product with the wanted quantity and optional notes for the kitchen
+type OrderLine struct {
+	ProductID uuid.UUID
+	// VariantID is the chosen variant, uuid.Nil for products without variants
+	VariantID uuid.UUID
+	// ModifierIDs are the chosen extras
+	ModifierIDs []uuid.UUID
+	Quantity    int
+	// Notes are free text wishes such as "no onions"
+	Notes string
+}
@@ -0,0 +1,9 @@
+package valueobject
+
+import "time"
+
+// PriceChange is a price which is in effect from a point in time on
+type PriceChange struct {
+	Price         float64
+	EffectiveFrom time.Time
+}
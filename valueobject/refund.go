@@ -0,0 +1,27 @@
+package valueobject
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReturnedItem is a quantity of an ordered item which the customer hands back
+type ReturnedItem struct {
+	// Item is the index of the item in the order
+	Item     int
+	Quantity int
+}
+
+// Refund is money paid back to the customer for an order
+type Refund struct {
+	ID uuid.UUID
+	// Amount is the refunded total
+	Amount float64
+	// Amounts is the refunded part of every item of the order,
+	// the value of returned items and a share of any amount refunded on top
+	Amounts   []float64
+	Returned  []ReturnedItem
+	Reason    string
+	CreatedAt time.Time
+}
@@ -0,0 +1,28 @@
+package valueobject
+
+// TaxCategory groups products which are taxed at the same rate, such as food or beverages
+type TaxCategory string
+
+const (
+	// TaxStandard is the category of every product without a reduced rate
+	TaxStandard TaxCategory = ""
+	TaxFood     TaxCategory = "food"
+	TaxBeverage TaxCategory = "beverage"
+)
+
+// TaxRate is the part of a bill taxed at one rate
+type TaxRate struct {
+	// Rate is the tax rate in percent
+	Rate  float64
+	Net   float64
+	Tax   float64
+	Gross float64
+}
+
+// TaxBreakdown splits a gross total into net and the tax of every rate
+type TaxBreakdown struct {
+	Net   float64
+	Tax   float64
+	Gross float64
+	Rates []TaxRate
+}
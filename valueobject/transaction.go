@@ -6,9 +6,72 @@ import (
 	"github.com/google/uuid"
 )
 
+// TransactionKind tells what a transaction moves and therefore the unit of its amount
+type TransactionKind string
+
+const (
+	// TransactionPayment is a payment in cents
+	TransactionPayment TransactionKind = "payment"
+	// TransactionTopUp is money in cents paid into the prepaid wallet of a customer
+	TransactionTopUp TransactionKind = "top_up"
+	// TransactionWalletCharge is money in cents drawn from the prepaid wallet of a customer
+	TransactionWalletCharge TransactionKind = "wallet_charge"
+	// TransactionRefund is money in cents paid back to a customer, its amount is negative
+	// as it reverses a payment or wallet charge from the customer
+	TransactionRefund TransactionKind = "refund"
+	// TransactionLoyaltyAccrual are loyalty points earned by a customer
+	TransactionLoyaltyAccrual TransactionKind = "loyalty_accrual"
+	// TransactionLoyaltyRedemption are loyalty points spent by a customer
+	TransactionLoyaltyRedemption TransactionKind = "loyalty_redemption"
+)
+
+// Transaction moves an amount from one party to another
 type Transaction struct {
+	kind      TransactionKind
 	amount    int
 	from      uuid.UUID
 	to        uuid.UUID
 	createdAt time.Time
 }
+
+// NewTransaction creates a transaction of the given kind created right now
+func NewTransaction(kind TransactionKind, amount int, from, to uuid.UUID) Transaction {
+	return RestoreTransaction(kind, amount, from, to, time.Now())
+}
+
+// RestoreTransaction creates a transaction which was created at an earlier time,
+// such as a transaction read back from a database
+func RestoreTransaction(kind TransactionKind, amount int, from, to uuid.UUID, createdAt time.Time) Transaction {
+	return Transaction{
+		kind:      kind,
+		amount:    amount,
+		from:      from,
+		to:        to,
+		createdAt: createdAt,
+	}
+}
+
+// GetKind returns what the transaction moves
+func (t Transaction) GetKind() TransactionKind {
+	return t.kind
+}
+
+// GetAmount returns the amount, in points for loyalty transactions and in cents for all others
+func (t Transaction) GetAmount() int {
+	return t.amount
+}
+
+// GetFrom returns who pays
+func (t Transaction) GetFrom() uuid.UUID {
+	return t.from
+}
+
+// GetTo returns who or what is paid
+func (t Transaction) GetTo() uuid.UUID {
+	return t.to
+}
+
+// GetCreatedAt returns when the transaction was created
+func (t Transaction) GetCreatedAt() time.Time {
+	return t.createdAt
+}